@@ -0,0 +1,560 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joho/godotenv"
+	"github.com/regiellis/wp-manager-cli/internal/compose"
+)
+
+// wpodRegistryFileName is wpod's instance registry in <user config dir>/wpod.
+const wpodRegistryFileName = ".wpod-instances.json"
+
+// addonPort is a host port allocated for an add-on and stored in .env. The ranges sit
+// above the ones wpod assigns at create time (8000-8999, 10000-19999) and don't overlap
+// each other, so add-on ports never take a port a new instance may be given.
+type addonPort struct {
+	Env       string
+	Container int
+	From, To  int // host port range searched for a free port
+}
+
+// addonDefinition is one entry of the add-on catalogue. Service is the compose service
+// body; the service joins the same network as wordpress when it is added.
+type addonDefinition struct {
+	Name        string
+	Description string
+	Service     string
+	Volumes     []string // top-level named volumes the service uses
+	Ports       []addonPort
+	Env         map[string]string // extra .env keys written on add
+	Setup       func(ctx context.Context) error
+	Teardown    func(ctx context.Context) error
+	URL         func(env map[string]string) string // where to reach it, if anywhere
+}
+
+var addonCatalogue = []addonDefinition{
+	{
+		Name:        "redis",
+		Description: "Redis object cache (Redis Object Cache plugin)",
+		Service: `
+image: redis:7-alpine
+container_name: wordpress_redis_${WORDPRESS_CONTAINER_NAME}
+restart: unless-stopped
+command: ["redis-server", "--save", "", "--maxmemory", "256mb", "--maxmemory-policy", "allkeys-lru"]
+ports:
+  - "${REDIS_PORT}:6379"
+healthcheck:
+  test: ["CMD", "redis-cli", "ping"]
+  interval: 10s
+  timeout: 5s
+  retries: 5
+`,
+		Ports: []addonPort{{Env: "REDIS_PORT", Container: 6379, From: 26379, To: 26999}},
+		Setup: func(ctx context.Context) error {
+			return runWPSteps(ctx,
+				[]string{"config", "set", "WP_REDIS_HOST", "redis"},
+				[]string{"config", "set", "WP_REDIS_PORT", "6379", "--raw"},
+				[]string{"plugin", "install", "redis-cache", "--activate"},
+				[]string{"redis", "enable", "--force"},
+			)
+		},
+		Teardown: func(ctx context.Context) error {
+			return runWPSteps(ctx,
+				[]string{"redis", "disable"},
+				[]string{"plugin", "deactivate", "redis-cache", "--uninstall"},
+				[]string{"config", "delete", "WP_REDIS_HOST"},
+				[]string{"config", "delete", "WP_REDIS_PORT"},
+			)
+		},
+	},
+	{
+		Name:        "memcached",
+		Description: "Memcached object cache (Memcached Object Cache drop-in)",
+		Service: `
+image: memcached:1.6-alpine
+container_name: wordpress_memcached_${WORDPRESS_CONTAINER_NAME}
+restart: unless-stopped
+command: ["memcached", "-m", "256"]
+ports:
+  - "${MEMCACHED_PORT}:11211"
+`,
+		Ports: []addonPort{{Env: "MEMCACHED_PORT", Container: 11211, From: 21211, To: 21999}},
+		Setup: func(ctx context.Context) error {
+			// The drop-in talks to memcached through the PECL memcache extension.
+			modules, _ := runCommandGetOutput(ctx, "docker", "compose", "exec", "-T", "wordpress", "php", "-m")
+			if !regexp.MustCompile(`(?m)^memcache$`).MatchString(modules) {
+				return errors.New("the PHP memcache extension is missing from the wordpress image; add 'RUN pecl install memcache && docker-php-ext-enable memcache' to the Dockerfile, rebuild, then run './manage addon add memcached' again")
+			}
+			if err := runWPSteps(ctx,
+				[]string{"plugin", "install", "memcached"},
+				[]string{"config", "set", "memcached_servers", "array('default' => array('memcached:11211'))", "--raw", "--type=variable"},
+			); err != nil {
+				return err
+			}
+			return runCommand(ctx, "docker", "compose", "exec", "-T", "--user", "www-data", "wordpress",
+				"cp", containerWPContent+"/plugins/memcached/object-cache.php", containerWPContent+"/object-cache.php")
+		},
+		Teardown: func(ctx context.Context) error {
+			errDropIn := runCommand(ctx, "docker", "compose", "exec", "-T", "wordpress", "rm", "-f", containerWPContent+"/object-cache.php")
+			return errors.Join(errDropIn, runWPSteps(ctx,
+				[]string{"plugin", "delete", "memcached"},
+				[]string{"config", "delete", "memcached_servers", "--type=variable"},
+			))
+		},
+	},
+	{
+		Name:        "phpmyadmin",
+		Description: "phpMyAdmin web UI for the instance database",
+		Service: `
+image: phpmyadmin:5
+container_name: wordpress_phpmyadmin_${WORDPRESS_CONTAINER_NAME}
+restart: unless-stopped
+environment:
+  PMA_HOST: db
+  UPLOAD_LIMIT: 256M
+ports:
+  - "${PHPMYADMIN_PORT}:80"
+depends_on:
+  - db
+`,
+		Ports: []addonPort{{Env: "PHPMYADMIN_PORT", Container: 80, From: 22080, To: 22999}},
+		URL: func(env map[string]string) string {
+			return "http://localhost:" + env["PHPMYADMIN_PORT"]
+		},
+	},
+	{
+		Name:        "elasticsearch",
+		Description: "Elasticsearch search backend (ElasticPress plugin)",
+		Service: `
+image: elasticsearch:${ELASTICSEARCH_VERSION:-8.15.0}
+container_name: wordpress_elasticsearch_${WORDPRESS_CONTAINER_NAME}
+restart: unless-stopped
+environment:
+  discovery.type: single-node
+  xpack.security.enabled: "false"
+  ES_JAVA_OPTS: "-Xms512m -Xmx512m"
+volumes:
+  - es_data:/usr/share/elasticsearch/data
+ports:
+  - "${ELASTICSEARCH_PORT}:9200"
+healthcheck:
+  test: ["CMD-SHELL", "curl -fs http://localhost:9200/_cluster/health || exit 1"]
+  interval: 15s
+  timeout: 5s
+  retries: 10
+`,
+		Volumes: []string{"es_data"},
+		Ports:   []addonPort{{Env: "ELASTICSEARCH_PORT", Container: 9200, From: 29200, To: 29999}},
+		Env:     map[string]string{"ELASTICSEARCH_VERSION": "8.15.0"},
+		Setup: func(ctx context.Context) error {
+			return runWPSteps(ctx,
+				[]string{"config", "set", "EP_HOST", "http://elasticsearch:9200"},
+				[]string{"plugin", "install", "elasticpress", "--activate"},
+				[]string{"elasticpress", "sync", "--setup", "--yes"},
+			)
+		},
+		Teardown: func(ctx context.Context) error {
+			return runWPSteps(ctx,
+				[]string{"plugin", "deactivate", "elasticpress", "--uninstall"},
+				[]string{"config", "delete", "EP_HOST"},
+			)
+		},
+	},
+}
+
+func findAddon(name string) (addonDefinition, bool) {
+	for _, a := range addonCatalogue {
+		if a.Name == strings.ToLower(name) {
+			return a, true
+		}
+	}
+	return addonDefinition{}, false
+}
+
+func addonNames() []string {
+	names := make([]string, 0, len(addonCatalogue))
+	for _, a := range addonCatalogue {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+// runWPSteps runs WP-CLI commands in order and stops at the first failure.
+func runWPSteps(ctx context.Context, steps ...[]string) error {
+	for _, step := range steps {
+		if err := wpCLI(ctx, step...); err != nil {
+			return fmt.Errorf("wp %s: %w", strings.Join(step, " "), err)
+		}
+	}
+	return nil
+}
+
+// cmdAddon handles './manage addon <list|add|remove> [name]'.
+func cmdAddon(ctx context.Context, args []string) error {
+	if len(args) == 0 || strings.ToLower(args[0]) == "list" || strings.ToLower(args[0]) == "ls" {
+		return addonList()
+	}
+	if len(args) < 2 {
+		printError("Add-on Name Required", "Usage: ./manage addon <add|remove> <"+strings.Join(addonNames(), "|")+">")
+		return errors.New("missing add-on name")
+	}
+	addon, ok := findAddon(args[1])
+	if !ok {
+		printError("Unknown Add-on", fmt.Sprintf("'%s' is not in the catalogue.", args[1]), "Available: "+strings.Join(addonNames(), ", "))
+		return errors.New("unknown add-on")
+	}
+	switch strings.ToLower(args[0]) {
+	case "add", "install":
+		return addonAdd(ctx, addon)
+	case "remove", "rm", "uninstall":
+		return addonRemove(ctx, addon)
+	default:
+		printError("Unknown Add-on Subcommand", fmt.Sprintf("'%s' is not recognized.", args[0]), "Valid subcommands are: list, add, remove")
+		return errors.New("unknown add-on subcommand")
+	}
+}
+
+func addonList() error {
+	printSectionHeader("Service Add-ons")
+	file, err := compose.Load(composeFileName)
+	if err != nil {
+		printError("Could not read "+composeFileName, err.Error())
+		return err
+	}
+	nameStyle := lipgloss.NewStyle().Foreground(colorSecondary).Width(16)
+	for _, a := range addonCatalogue {
+		state := subtleStyle.Render("available")
+		if file.HasService(a.Name) {
+			state = successMsgStyle.Render("installed")
+		}
+		fmt.Printf("  %s %-58s %s\n", nameStyle.Render(a.Name), a.Description, state)
+	}
+	fmt.Println()
+	printInfo("Add one with " + commandStyle.Render("./manage addon add <name>") + ", remove it with " + commandStyle.Render("./manage addon remove <name>") + ".")
+	return nil
+}
+
+// addonAdd merges the add-on into docker-compose.yml and .env, starts the service and runs
+// its WordPress setup. Running it again for an installed add-on repeats the setup only.
+func addonAdd(ctx context.Context, addon addonDefinition) error {
+	printSectionHeader("Add Service: " + addon.Name)
+
+	file, err := compose.Load(composeFileName)
+	if err != nil {
+		printError("Could not read "+composeFileName, err.Error())
+		return err
+	}
+	if file.HasService(addon.Name) {
+		printInfo(fmt.Sprintf("'%s' is already in %s; re-running its setup.", addon.Name, composeFileName))
+	} else {
+		env, _ := godotenv.Read(envFileName)
+		assigned, err := allocateAddonPorts(addon, env)
+		if err != nil {
+			printError("No Free Port", err.Error())
+			return err
+		}
+		for key, value := range addon.Env {
+			if env[key] == "" {
+				assigned[key] = value
+			}
+		}
+		svc, err := file.AddServiceYAML(addon.Name, addon.Service)
+		if err != nil {
+			printError("Invalid Add-on Definition", err.Error())
+			return err
+		}
+		if wordpress := file.Service("wordpress"); wordpress != nil {
+			if networks := wordpress.Networks(); len(networks) > 0 {
+				svc.SetList("networks", networks[:1], false)
+			}
+		}
+		for _, volume := range addon.Volumes {
+			file.AddVolume(volume)
+		}
+		if err := file.Save(composeFileName); err != nil {
+			printError("Could not write "+composeFileName, err.Error())
+			return err
+		}
+		if err := updateEnvFile(assigned, nil); err != nil {
+			printError("Could not update .env", err.Error())
+			return err
+		}
+		details := []string{"Service '" + addon.Name + "' added to " + composeFileName}
+		for _, key := range sortedKeys(assigned) {
+			details = append(details, fmt.Sprintf("%s=%s", key, assigned[key]))
+			_ = os.Setenv(key, assigned[key])
+		}
+		printSuccess("Compose and .env Updated", details...)
+	}
+
+	// --wait returns once the service is healthy (or running, without a healthcheck), so
+	// the setup below doesn't talk to a service that is still starting.
+	printInfo("Starting " + addon.Name + " and waiting until it is ready...")
+	if err := runCommand(ctx, "docker", "compose", "up", "-d", "--wait", addon.Name); err != nil {
+		printError("Failed to Start "+addon.Name, err.Error())
+		return err
+	}
+
+	if addon.Setup != nil {
+		if _, err := wpCLIGetOutput(ctx, "core", "is-installed"); err != nil {
+			printWarning("WordPress Is Not Installed Yet", "The WordPress-side setup was skipped. After "+commandStyle.Render("./manage install")+", run "+commandStyle.Render("./manage addon add "+addon.Name)+" again.")
+			return nil
+		}
+		printInfo("Configuring WordPress for " + addon.Name + "...")
+		if err := addon.Setup(ctx); err != nil {
+			printError("WordPress Setup Failed", err.Error(), "The service is running; fix the problem and run "+commandStyle.Render("./manage addon add "+addon.Name)+" again.")
+			return err
+		}
+	}
+
+	details := []string{addon.Description}
+	if addon.URL != nil {
+		env, _ := godotenv.Read(envFileName)
+		details = append(details, "Open: "+commandStyle.Render(addon.URL(env)))
+	}
+	printSuccess("Add-on '"+addon.Name+"' Ready", details...)
+	return nil
+}
+
+// addonRemove undoes addonAdd: WordPress setup, container, named volumes, compose service
+// and .env keys.
+func addonRemove(ctx context.Context, addon addonDefinition) error {
+	printSectionHeader("Remove Service: " + addon.Name)
+
+	file, err := compose.Load(composeFileName)
+	if err != nil {
+		printError("Could not read "+composeFileName, err.Error())
+		return err
+	}
+	if !file.HasService(addon.Name) {
+		printInfo(fmt.Sprintf("'%s' is not part of this instance; nothing to remove.", addon.Name))
+		return nil
+	}
+
+	var problems []string
+	if addon.Teardown != nil {
+		if _, err := wpCLIGetOutput(ctx, "core", "is-installed"); err == nil {
+			printInfo("Removing the WordPress-side setup...")
+			if err := addon.Teardown(ctx); err != nil {
+				problems = append(problems, "WordPress cleanup: "+err.Error())
+			}
+		} else {
+			problems = append(problems, "WordPress is not reachable, so its "+addon.Name+" settings were left in place")
+		}
+	}
+
+	printInfo("Stopping and removing the " + addon.Name + " container...")
+	if err := runCommand(ctx, "docker", "compose", "rm", "--stop", "--force", addon.Name); err != nil {
+		problems = append(problems, "container: "+err.Error())
+	}
+	for _, volume := range addon.Volumes {
+		if err := removeDockerVolume(ctx, volume); err != nil {
+			problems = append(problems, "volume "+volume+": "+err.Error())
+		}
+		file.RemoveVolume(volume)
+	}
+
+	file.RemoveService(addon.Name)
+	if err := file.Save(composeFileName); err != nil {
+		printError("Could not write "+composeFileName, err.Error())
+		return err
+	}
+	var keys []string
+	for _, p := range addon.Ports {
+		keys = append(keys, p.Env)
+	}
+	for key := range addon.Env {
+		keys = append(keys, key)
+	}
+	if err := updateEnvFile(nil, keys); err != nil {
+		problems = append(problems, ".env: "+err.Error())
+	}
+
+	if len(problems) > 0 {
+		printWarning("Add-on '"+addon.Name+"' Removed with Problems", problems...)
+		return nil
+	}
+	printSuccess("Add-on '"+addon.Name+"' Removed", "Service, volumes and .env keys ("+strings.Join(keys, ", ")+") are gone.")
+	return nil
+}
+
+// removeDockerVolume deletes the named volume compose created for this project, if any.
+func removeDockerVolume(ctx context.Context, volume string) error {
+	out, err := runCommandGetOutput(ctx, "docker", "volume", "ls", "-q",
+		"--filter", "label=com.docker.compose.project="+composeProjectName(),
+		"--filter", "label=com.docker.compose.volume="+volume)
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Fields(out) {
+		if err := runCommand(ctx, "docker", "volume", "rm", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// composeProjectName mirrors docker compose's default: COMPOSE_PROJECT_NAME, otherwise the
+// directory name lowercased with unsupported characters dropped.
+func composeProjectName() string {
+	if name := os.Getenv("COMPOSE_PROJECT_NAME"); name != "" {
+		return name
+	}
+	dir, _ := os.Getwd()
+	name := strings.ToLower(filepath.Base(dir))
+	return regexp.MustCompile(`[^a-z0-9_-]`).ReplaceAllString(name, "")
+}
+
+// --- Ports and .env ---
+
+// allocateAddonPorts picks a free host port for each of the add-on's ports, skipping
+// ports already assigned in .env or in any other registered instance's .env (stopped
+// instances hold no host port but get it back on start), and ports in use on the host.
+func allocateAddonPorts(addon addonDefinition, env map[string]string) (map[string]string, error) {
+	used := registeredInstancePorts()
+	for _, value := range env {
+		if p, err := strconv.Atoi(value); err == nil {
+			used[p] = true
+		}
+	}
+	assigned := make(map[string]string)
+	for _, port := range addon.Ports {
+		if existing := env[port.Env]; existing != "" {
+			assigned[port.Env] = existing
+			continue
+		}
+		found := 0
+		for p := port.From; p <= port.To; p++ {
+			if !used[p] && hostPortFree(p) {
+				found = p
+				break
+			}
+		}
+		if found == 0 {
+			return nil, fmt.Errorf("no free port for %s in %d-%d", port.Env, port.From, port.To)
+		}
+		used[found] = true
+		assigned[port.Env] = strconv.Itoa(found)
+	}
+	return assigned, nil
+}
+
+// registeredInstancePorts collects the *_PORT values from the .env of every instance in
+// wpod's registry. A missing or unreadable registry yields an empty set.
+func registeredInstancePorts() map[int]bool {
+	used := make(map[int]bool)
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return used
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "wpod", wpodRegistryFileName))
+	if err != nil {
+		return used
+	}
+	var registry map[string]struct {
+		Directory string `json:"directory"`
+	}
+	if err := json.Unmarshal(data, &registry); err != nil {
+		printVerbose("Could not parse the wpod registry:", err.Error())
+		return used
+	}
+	for _, instance := range registry {
+		if instance.Directory == "" {
+			continue
+		}
+		env, err := godotenv.Read(filepath.Join(instance.Directory, envFileName))
+		if err != nil {
+			continue
+		}
+		for key, value := range env {
+			if !strings.Contains(key, "_PORT") {
+				continue
+			}
+			if p, err := strconv.Atoi(value); err == nil && p > 0 {
+				used[p] = true
+			}
+		}
+	}
+	return used
+}
+
+func hostPortFree(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	_ = l.Close()
+	return true
+}
+
+// updateEnvFile sets and removes keys in .env, keeping every other line as it is.
+func updateEnvFile(set map[string]string, remove []string) error {
+	data, err := os.ReadFile(envFileName)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	drop := make(map[string]bool, len(remove))
+	for _, key := range remove {
+		drop[key] = true
+	}
+	done := make(map[string]bool, len(set))
+	out := lines[:0]
+	for _, line := range lines {
+		key, _, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		switch {
+		case ok && drop[key]:
+			continue
+		case ok && set[key] != "":
+			line = key + "=" + set[key]
+			done[key] = true
+		}
+		out = append(out, line)
+	}
+	for _, key := range sortedKeys(set) {
+		if !done[key] {
+			out = append(out, key+"="+set[key])
+		}
+	}
+	return os.WriteFile(envFileName, []byte(strings.Join(out, "\n")+"\n"), 0644)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,264 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	blueprintFileName   = "blueprint.json"
+	containerWPContent  = "/var/www/html/wp-content"
+	containerUploadTemp = "/tmp"
+)
+
+// BlueprintPackage mirrors the plugin/theme entries of wpod's blueprint.json. An entry is
+// either a string ("query-monitor", "query-monitor@3.16.0", "./plugins/acme.zip") or an
+// object with these fields.
+type BlueprintPackage struct {
+	Slug     string `json:"slug,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Activate *bool  `json:"activate,omitempty"`
+}
+
+func (p *BlueprintPackage) UnmarshalJSON(data []byte) error {
+	var spec string
+	if err := json.Unmarshal(data, &spec); err == nil {
+		spec = strings.TrimSpace(spec)
+		switch {
+		case strings.ContainsAny(spec, `/\`) || strings.HasSuffix(strings.ToLower(spec), ".zip"):
+			*p = BlueprintPackage{Source: spec}
+		case strings.Contains(spec, "@"):
+			slug, version, _ := strings.Cut(spec, "@")
+			*p = BlueprintPackage{Slug: slug, Version: version}
+		default:
+			*p = BlueprintPackage{Slug: spec}
+		}
+		return nil
+	}
+	type plain BlueprintPackage
+	var obj plain
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("plugin/theme entries must be a string or an object: %w", err)
+	}
+	*p = BlueprintPackage(obj)
+	return nil
+}
+
+func (p BlueprintPackage) label() string {
+	switch {
+	case p.Source != "" && p.Slug != "":
+		return fmt.Sprintf("%s (%s)", p.Slug, p.Source)
+	case p.Source != "":
+		return p.Source
+	case p.Version != "":
+		return p.Slug + "@" + p.Version
+	default:
+		return p.Slug
+	}
+}
+
+func (p BlueprintPackage) activate() bool {
+	return p.Activate == nil || *p.Activate
+}
+
+// packageResult is the outcome of one blueprint plugin or theme.
+type packageResult struct {
+	Kind    string // plugin or theme
+	Label   string
+	Version string
+	Outcome string // installed, updated, already installed, failed
+	Active  bool
+	Err     error
+}
+
+// cmdApplyBlueprint installs and activates the plugins and themes listed in the
+// instance's blueprint.json. Each item is attempted even if an earlier one fails.
+func cmdApplyBlueprint(ctx context.Context) error {
+	printSectionHeader("Apply Blueprint Plugins & Themes")
+
+	data, err := os.ReadFile(blueprintFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		printInfo("No blueprint.json in this instance; nothing to install.")
+		return nil
+	} else if err != nil {
+		printError("Could not read blueprint.json", err.Error())
+		return err
+	}
+	var bp struct {
+		Plugins []BlueprintPackage `json:"plugins"`
+		Themes  []BlueprintPackage `json:"themes"`
+	}
+	if err := json.Unmarshal(data, &bp); err != nil {
+		printError("Invalid blueprint.json", err.Error())
+		return err
+	}
+	if len(bp.Plugins)+len(bp.Themes) == 0 {
+		printInfo("The blueprint lists no plugins or themes.")
+		return nil
+	}
+
+	if _, err := wpCLIGetOutput(ctx, "core", "is-installed"); err != nil {
+		printWarning("WordPress Is Not Installed Yet", "Run "+commandStyle.Render("./manage install")+" first; it applies the blueprint when it finishes.")
+		return err
+	}
+
+	var results []packageResult
+	for _, p := range bp.Plugins {
+		results = append(results, applyBlueprintPackage(ctx, "plugin", p))
+	}
+	for _, p := range bp.Themes {
+		results = append(results, applyBlueprintPackage(ctx, "theme", p))
+	}
+	return reportBlueprintResults(results)
+}
+
+func applyBlueprintPackage(ctx context.Context, kind string, p BlueprintPackage) packageResult {
+	result := packageResult{Kind: kind, Label: p.label()}
+	printInfo(fmt.Sprintf("Applying %s:", kind), p.label())
+
+	slug := p.Slug
+	switch {
+	case p.Source == "" && p.Slug == "":
+		result.Err = errors.New("entry needs a slug or a source")
+	case p.Source == "":
+		result.Outcome, result.Err = installFromWordPressOrg(ctx, kind, p)
+	case strings.Contains(p.Source, "://"):
+		// Remote zip: WP-CLI downloads it itself.
+		result.Outcome, result.Err = "installed", wpCLI(ctx, zipInstallArgs(kind, p.Source, p)...)
+	default:
+		slug, result.Outcome, result.Err = installFromLocalSource(ctx, kind, p)
+	}
+	if result.Err != nil {
+		result.Outcome = "failed"
+		return result
+	}
+
+	if slug == "" {
+		// Zip installs without a slug were activated by 'install --activate'; WP-CLI does not
+		// return the installed name, so the version cannot be looked up.
+		result.Active = p.activate()
+		return result
+	}
+	if p.activate() {
+		if err := wpCLI(ctx, kind, "activate", slug); err != nil {
+			result.Outcome, result.Err = "failed", fmt.Errorf("installed but activation failed: %w", err)
+			return result
+		}
+		result.Active = true
+	}
+	result.Version, _ = wpCLIGetOutput(ctx, kind, "get", slug, "--field=version")
+	return result
+}
+
+// installFromWordPressOrg installs a wp.org slug, honouring a pinned version.
+func installFromWordPressOrg(ctx context.Context, kind string, p BlueprintPackage) (string, error) {
+	installed, err := wpCLIGetOutput(ctx, kind, "get", p.Slug, "--field=version")
+	if err == nil && (p.Version == "" || installed == p.Version) {
+		return "already installed", nil
+	}
+	args := []string{kind, "install", p.Slug}
+	outcome := "installed"
+	if p.Version != "" {
+		args = append(args, "--version="+p.Version)
+	}
+	if err == nil {
+		args = append(args, "--force")
+		outcome = "updated"
+	}
+	return outcome, wpCLI(ctx, args...)
+}
+
+// installFromLocalSource copies a zip or directory (relative to the instance) into the
+// wordpress container. It returns the slug to activate, when known.
+func installFromLocalSource(ctx context.Context, kind string, p BlueprintPackage) (string, string, error) {
+	info, err := os.Stat(p.Source)
+	if err != nil {
+		return "", "", fmt.Errorf("source %s: %w", p.Source, err)
+	}
+
+	if !info.IsDir() {
+		target := containerUploadTemp + "/wpod-" + filepath.Base(p.Source)
+		if err := runCommand(ctx, "docker", "compose", "cp", p.Source, "wordpress:"+target); err != nil {
+			return "", "", err
+		}
+		defer func() {
+			_ = runCommand(ctx, "docker", "compose", "exec", "-T", "wordpress", "rm", "-f", target)
+		}()
+		return p.Slug, "installed", wpCLI(ctx, zipInstallArgs(kind, target, p)...)
+	}
+
+	slug := p.Slug
+	if slug == "" {
+		slug = filepath.Base(filepath.Clean(p.Source))
+	}
+	target := fmt.Sprintf("%s/%ss/%s", containerWPContent, kind, slug)
+	// 'docker compose cp' nests into an existing directory, so replace it instead.
+	if err := runCommand(ctx, "docker", "compose", "exec", "-T", "wordpress", "rm", "-rf", target); err != nil {
+		return "", "", err
+	}
+	if err := runCommand(ctx, "docker", "compose", "cp", filepath.Clean(p.Source), "wordpress:"+target); err != nil {
+		return "", "", err
+	}
+	if err := runCommand(ctx, "docker", "compose", "exec", "-T", "wordpress", "chown", "-R", "www-data:www-data", target); err != nil {
+		printWarning("Could not hand the copied files to www-data", err.Error())
+	}
+	return slug, "installed", nil
+}
+
+func zipInstallArgs(kind, zip string, p BlueprintPackage) []string {
+	args := []string{kind, "install", zip, "--force"}
+	if p.activate() && p.Slug == "" {
+		args = append(args, "--activate")
+	}
+	return args
+}
+
+func reportBlueprintResults(results []packageResult) error {
+	var ok, failed []string
+	for _, r := range results {
+		line := fmt.Sprintf("%s %s: %s", r.Kind, r.Label, r.Outcome)
+		if r.Version != "" {
+			line += " (" + r.Version + ")"
+		}
+		if r.Active {
+			line += ", active"
+		}
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s: %v", r.Kind, r.Label, r.Err))
+			continue
+		}
+		ok = append(ok, line)
+	}
+	if len(ok) > 0 {
+		printSuccess(fmt.Sprintf("%d of %d blueprint item(s) applied", len(ok), len(results)), ok...)
+	}
+	if len(failed) > 0 {
+		printError(fmt.Sprintf("%d blueprint item(s) failed", len(failed)), failed...)
+		return fmt.Errorf("%d blueprint item(s) failed", len(failed))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	dbEngineMySQL   = "mysql"
+	dbEngineMariaDB = "mariadb"
+)
+
+// dbServerVersionRe matches "mysqld  Ver 8.0.39 for Linux ..." and
+// "mariadbd  Ver 11.4.2-MariaDB-ubu2404 for debian-linux-gnu ...".
+var dbServerVersionRe = regexp.MustCompile(`Ver\s+(\d+\.\d+\.\d+)(-MariaDB)?`)
+
+// instanceDBEngine returns DB_ENGINE from .env; instances that predate it run MySQL.
+func instanceDBEngine() string {
+	if strings.EqualFold(os.Getenv("DB_ENGINE"), dbEngineMariaDB) {
+		return dbEngineMariaDB
+	}
+	return dbEngineMySQL
+}
+
+// dbClientCommand returns the client ("mysql") or dump ("mysqldump") binary for the db
+// container. MariaDB 11 images only ship the mariadb-prefixed names.
+func dbClientCommand(tool string) string {
+	if instanceDBEngine() != dbEngineMariaDB {
+		return tool
+	}
+	switch tool {
+	case "mysql":
+		return "mariadb"
+	case "mysqldump":
+		return "mariadb-dump"
+	}
+	return tool
+}
+
+// parseDBServerVersion extracts the engine and version from the server's --version output.
+func parseDBServerVersion(output string) (engine, version string, err error) {
+	match := dbServerVersionRe.FindStringSubmatch(output)
+	if match == nil {
+		return "", "", fmt.Errorf("unrecognised server version output: %q", strings.TrimSpace(output))
+	}
+	engine = dbEngineMySQL
+	if match[2] != "" || strings.Contains(strings.ToLower(output), "mariadb") {
+		engine = dbEngineMariaDB
+	}
+	return engine, match[1], nil
+}
+
+// detectDBServer asks the running db container for its server version.
+func detectDBServer(ctx context.Context) (engine, version string, err error) {
+	out, err := runCommandGetOutput(ctx, "docker", "compose", "exec", "-T", "db", "sh", "-c", "mariadbd --version 2>/dev/null || mysqld --version")
+	if err != nil {
+		return "", "", err
+	}
+	return parseDBServerVersion(out)
+}
+
+// recordDBServerVersion stores the detected database engine and version in the local meta
+// file; 'wpod list' and 'wpod status' pick it up from there.
+func recordDBServerVersion(ctx context.Context, meta *LocalInstanceMeta) {
+	engine, version, err := detectDBServer(ctx)
+	if err != nil {
+		printVerbose("Could not detect the database server version:", err.Error())
+		return
+	}
+	meta.DBEngine, meta.DBVersion = engine, version
+}
+
+// refreshDBServerVersion records the database server version after the services start.
+func refreshDBServerVersion(ctx context.Context) {
+	meta, err := readLocalMeta()
+	if err != nil {
+		return
+	}
+	engine, version := meta.DBEngine, meta.DBVersion
+	recordDBServerVersion(ctx, meta)
+	if meta.DBEngine != engine || meta.DBVersion != version {
+		if err := writeLocalMeta(meta); err != nil {
+			printWarning("Meta File Write Error", err.Error())
+		}
+	}
+}
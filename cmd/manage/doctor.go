@@ -0,0 +1,561 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// doctorTimeout bounds each docker call of 'manage doctor', so a hung daemon can't stall it.
+const doctorTimeout = 20 * time.Second
+
+// doctorDebugLogTail is how much of the end of wp-content/debug.log is scanned for errors.
+const doctorDebugLogTail = 256 << 10
+
+// composeVarRe matches ${KEY}, ${KEY:-default} and $KEY in a compose file.
+var composeVarRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:?[-?+][^}]*)?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// debugLogErrorRe picks the lines of debug.log worth showing.
+var debugLogErrorRe = regexp.MustCompile(`PHP (Fatal error|Parse error|Warning|Recoverable fatal error)|WordPress database error`)
+
+// doctorCounts tallies the findings of 'manage doctor'.
+type doctorCounts struct{ issues, warnings int }
+
+func (c *doctorCounts) issue(title string, details ...string) {
+	c.issues++
+	printError(title, details...)
+}
+
+func (c *doctorCounts) warn(title string, details ...string) {
+	c.warnings++
+	printWarning(title, details...)
+}
+
+// cmdDoctor checks this instance: its .env, database credentials, containers, WP-CLI,
+// file ownership and permissions, and recent PHP errors. It changes nothing.
+func cmdDoctor(ctx context.Context) error {
+	printSectionHeader("Instance Doctor")
+	c := &doctorCounts{}
+
+	printSectionHeader(".env")
+	doctorEnv(c)
+
+	printSectionHeader("Containers")
+	running := doctorContainers(ctx, c)
+
+	printSectionHeader("Database Credentials")
+	doctorDBCredentials(ctx, c)
+
+	printSectionHeader("WP-CLI")
+	if running["wordpress"] {
+		doctorWPCLI(ctx, c)
+	} else {
+		printInfo("Skipped:", "The wordpress container is not running; start it with "+commandStyle.Render("./manage start")+".")
+	}
+
+	printSectionHeader("File Ownership")
+	if running["wordpress"] {
+		doctorOwnership(ctx, c)
+	} else {
+		printInfo("Skipped:", "The wordpress container is not running.")
+	}
+
+	printSectionHeader("wp-content Permissions")
+	doctorPermissions(c)
+
+	printSectionHeader("debug.log")
+	doctorDebugLog(c)
+
+	printSectionHeader("Instance Doctor Summary")
+	if c.issues > 0 {
+		printError(fmt.Sprintf("%d issue(s) found.", c.issues), "Please review the errors above.")
+	} else {
+		printSuccess("No issues found.")
+	}
+	if c.warnings > 0 {
+		printWarning(fmt.Sprintf("%d warning(s).", c.warnings))
+	}
+	if c.issues > 0 {
+		return errors.New("instance doctor found issues")
+	}
+	return nil
+}
+
+// doctorOutput runs a command with doctorTimeout and returns its trimmed combined output.
+func doctorOutput(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = time.Second // docker compose plugins can outlive the kill
+	out, err := cmd.CombinedOutput()
+	printVerbose("Ran:", name+" "+strings.Join(args, " "))
+	return strings.TrimSpace(string(out)), err
+}
+
+// lastOutputLine is the last non-empty line of command output, for short error details.
+func lastOutputLine(out string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// doctorEnv compares .env with the template's env-template. A key the compose file uses
+// without a default must have a value; other empty keys are fine.
+func doctorEnv(c *doctorCounts) {
+	templateData, err := os.ReadFile("env-template")
+	if err != nil {
+		templateData, err = os.ReadFile(".env-template")
+	}
+	if err != nil {
+		c.warn("No env-template in this directory.", "Cannot compare .env with the template's keys.")
+		return
+	}
+	templateEnv, err := godotenv.Unmarshal(string(templateData))
+	if err != nil {
+		c.warn("env-template could not be parsed.", err.Error())
+		return
+	}
+	env, err := godotenv.Read(envFileName)
+	if err != nil {
+		c.issue(".env could not be parsed.", err.Error())
+		return
+	}
+
+	required := make(map[string]bool)
+	if composeData, err := os.ReadFile(composeFileName); err == nil {
+		for _, line := range strings.Split(strings.ReplaceAll(string(composeData), "$$", ""), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			for _, m := range composeVarRe.FindAllStringSubmatch(line, -1) {
+				if m[1] != "" && m[2] == "" || m[3] != "" {
+					required[m[1]+m[3]] = true
+				}
+			}
+		}
+	}
+
+	var missing, emptyRequired, emptyOptional []string
+	for key := range templateEnv {
+		value, ok := env[key]
+		switch {
+		case !ok:
+			missing = append(missing, key)
+		case strings.TrimSpace(value) == "" && required[key]:
+			emptyRequired = append(emptyRequired, key)
+		case strings.TrimSpace(value) == "":
+			emptyOptional = append(emptyOptional, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(emptyRequired)
+	sort.Strings(emptyOptional)
+
+	for _, key := range missing {
+		if required[key] {
+			c.issue(fmt.Sprintf("%s is missing from .env.", key), "docker-compose.yml uses it without a default.")
+		} else {
+			c.warn(fmt.Sprintf("%s is missing from .env.", key), "It is in env-template; add it, even if empty.")
+		}
+	}
+	for _, key := range emptyRequired {
+		c.issue(fmt.Sprintf("%s is empty in .env.", key), "docker-compose.yml uses it without a default.")
+	}
+	if len(missing) == 0 && len(emptyRequired) == 0 {
+		printSuccess(".env has every key of env-template.", fmt.Sprintf("%d key(s) checked.", len(templateEnv)))
+	}
+	if len(emptyOptional) > 0 {
+		printInfo("Empty optional keys:", strings.Join(emptyOptional, ", "))
+	}
+}
+
+// composePSEntry is one line of 'docker compose ps --format json'.
+type composePSEntry struct {
+	Service string `json:"Service"`
+	State   string `json:"State"`
+	Health  string `json:"Health"`
+}
+
+// doctorContainers reports the state and health of every service and returns the ones
+// that run.
+func doctorContainers(ctx context.Context, c *doctorCounts) map[string]bool {
+	running := make(map[string]bool)
+	out, err := doctorOutput(ctx, "docker", "compose", "ps", "--all", "--format", "json")
+	if err != nil {
+		c.issue("Could not ask Docker for the containers.", lastOutputLine(out))
+		return running
+	}
+	var entries []composePSEntry
+	if strings.HasPrefix(out, "[") {
+		_ = json.Unmarshal([]byte(out), &entries) // Compose before 2.21 prints one array
+	} else {
+		for _, line := range strings.Split(out, "\n") {
+			var e composePSEntry
+			if json.Unmarshal([]byte(line), &e) == nil && e.Service != "" {
+				entries = append(entries, e)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		printInfo("No containers.", "The instance has not been started; run "+commandStyle.Render("./manage start")+".")
+		return running
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Service < entries[j].Service })
+	for _, e := range entries {
+		state := strings.ToLower(e.State)
+		health := strings.ToLower(e.Health)
+		switch {
+		case state == "running" && health == "unhealthy":
+			running[e.Service] = true
+			c.issue(fmt.Sprintf("%s is unhealthy.", e.Service), "See "+commandStyle.Render("docker compose logs "+e.Service)+".")
+		case state == "running":
+			running[e.Service] = true
+			detail := "running"
+			if health != "" {
+				detail += " (" + health + ")"
+			}
+			printSuccess(fmt.Sprintf("%s is %s.", e.Service, detail))
+		case state == "restarting":
+			c.issue(fmt.Sprintf("%s keeps restarting.", e.Service), "See "+commandStyle.Render("docker compose logs "+e.Service)+".")
+		default:
+			c.warn(fmt.Sprintf("%s is %s.", e.Service, state))
+		}
+	}
+	return running
+}
+
+// composeConfig is the part of 'docker compose config --format json' the doctor reads.
+type composeConfig struct {
+	Services map[string]struct {
+		Environment map[string]*string `json:"environment"`
+	} `json:"services"`
+}
+
+func (cfg composeConfig) env(service, key string) string {
+	if v := cfg.Services[service].Environment[key]; v != nil {
+		return *v
+	}
+	return ""
+}
+
+// wpConfigDefineRe matches define( 'DB_NAME', <value> ); in wp-config.php.
+var wpConfigDefineRe = regexp.MustCompile(`define\(\s*['"](DB_NAME|DB_USER|DB_PASSWORD|DB_HOST)['"]\s*,\s*(.+?)\s*\);`)
+
+// phpStringRe matches a single- or double-quoted PHP string literal.
+var phpStringRe = regexp.MustCompile(`^'((?:[^'\\]|\\.)*)'$|^"((?:[^"\\]|\\.)*)"$`)
+
+// doctorDBCredentials checks that the wordpress and db services get the same credentials
+// from .env, and that wp-config.php uses them.
+func doctorDBCredentials(ctx context.Context, c *doctorCounts) {
+	out, err := doctorOutput(ctx, "docker", "compose", "config", "--format", "json")
+	var cfg composeConfig
+	if err == nil {
+		// Compose may print warnings before the document.
+		if start := strings.Index(out, "{"); start >= 0 {
+			err = json.Unmarshal([]byte(out[start:]), &cfg)
+		} else {
+			err = errors.New("no JSON in the output")
+		}
+	}
+	if err != nil {
+		c.warn("Could not resolve docker-compose.yml with .env.", lastOutputLine(out))
+		return
+	}
+
+	// What the wordpress container connects with, and what the db container creates.
+	wp := map[string]string{
+		"DB_NAME":     cfg.env("wordpress", "WORDPRESS_DB_NAME"),
+		"DB_USER":     cfg.env("wordpress", "WORDPRESS_DB_USER"),
+		"DB_PASSWORD": cfg.env("wordpress", "WORDPRESS_DB_PASSWORD"),
+		"DB_HOST":     cfg.env("wordpress", "WORDPRESS_DB_HOST"),
+	}
+	db := map[string]string{}
+	for _, prefix := range []string{"MYSQL_", "MARIADB_"} {
+		for wpKey, dbKey := range map[string]string{"DB_NAME": "DATABASE", "DB_USER": "USER", "DB_PASSWORD": "PASSWORD"} {
+			if v := cfg.env("db", prefix+dbKey); v != "" && db[wpKey] == "" {
+				db[wpKey] = v
+			}
+		}
+	}
+	mismatch := false
+	for _, key := range []string{"DB_NAME", "DB_USER", "DB_PASSWORD"} {
+		if db[key] != "" && wp[key] != db[key] {
+			mismatch = true
+			detail := fmt.Sprintf("wordpress gets '%s', db creates '%s'.", wp[key], db[key])
+			if key == "DB_PASSWORD" {
+				detail = "The passwords differ."
+			}
+			c.issue(fmt.Sprintf("%s differs between the wordpress and db services.", key), detail, "Check the WORDPRESS_DB_* and MYSQL_* keys in .env.")
+		}
+	}
+	if !mismatch {
+		printSuccess("The wordpress and db services agree on the credentials.", fmt.Sprintf("Database '%s', user '%s'.", wp["DB_NAME"], wp["DB_USER"]))
+	}
+
+	wpConfig, err := os.ReadFile(filepath.Join("wordpress", "wp-config.php"))
+	if errors.Is(err, fs.ErrNotExist) {
+		printInfo("wordpress/wp-config.php does not exist yet.", "The wordpress image writes it on first start.")
+		return
+	}
+	if err != nil {
+		c.warn("Could not read wordpress/wp-config.php.", err.Error())
+		return
+	}
+	found := 0
+	for _, m := range wpConfigDefineRe.FindAllStringSubmatch(string(wpConfig), -1) {
+		key, expr := m[1], m[2]
+		found++
+		lit := phpStringRe.FindStringSubmatch(expr)
+		if lit == nil {
+			// getenv_docker('WORDPRESS_DB_USER', ...) reads the container environment above.
+			printVerbose(key+" in wp-config.php is not a literal:", expr)
+			continue
+		}
+		value := lit[1] + lit[2]
+		if value == wp[key] {
+			continue
+		}
+		detail := fmt.Sprintf("wp-config.php has '%s', .env gives '%s'.", value, wp[key])
+		if key == "DB_PASSWORD" {
+			detail = "wp-config.php has a different password than .env."
+		}
+		c.issue(fmt.Sprintf("%s in wp-config.php does not match .env.", key), detail, "Edit wordpress/wp-config.php, or remove it to have it regenerated from .env.")
+		mismatch = true
+	}
+	switch {
+	case found == 0:
+		c.warn("No DB_* settings found in wordpress/wp-config.php.")
+	case !mismatch:
+		printSuccess("wp-config.php matches the database credentials in .env.")
+	}
+}
+
+// doctorWPCLI checks that WP-CLI runs in the wordpress container.
+func doctorWPCLI(ctx context.Context, c *doctorCounts) {
+	out, err := doctorOutput(ctx, "docker", "compose", "exec", "-T", "--user", "www-data", "wordpress", "wp", "cli", "version")
+	if err != nil {
+		c.issue("WP-CLI is not available in the wordpress container.", lastOutputLine(out), "Rebuild the image with "+commandStyle.Render("./manage update")+".")
+		return
+	}
+	printSuccess("WP-CLI is available.", lastOutputLine(out))
+}
+
+// doctorOwnership compares the host user with the container's www-data. The Dockerfile
+// gives www-data UID/GID 1000, so a host user with other ids can't edit what WordPress
+// writes into the bind mounts.
+func doctorOwnership(ctx context.Context, c *doctorCounts) {
+	if runtime.GOOS == "windows" {
+		printInfo("Skipped on Windows:", "Docker Desktop maps file ownership for bind mounts.")
+		return
+	}
+	uidOut, errUID := doctorOutput(ctx, "docker", "compose", "exec", "-T", "wordpress", "id", "-u", "www-data")
+	gidOut, errGID := doctorOutput(ctx, "docker", "compose", "exec", "-T", "wordpress", "id", "-g", "www-data")
+	if errUID != nil || errGID != nil {
+		c.warn("Could not read the www-data ids in the container.", lastOutputLine(uidOut+"\n"+gidOut))
+		return
+	}
+	hostUID, hostGID := strconv.Itoa(os.Getuid()), strconv.Itoa(os.Getgid())
+	detail := fmt.Sprintf("Host user %s:%s, container www-data %s:%s.", hostUID, hostGID, lastOutputLine(uidOut), lastOutputLine(gidOut))
+	switch {
+	case hostUID == lastOutputLine(uidOut) && hostGID == lastOutputLine(gidOut):
+		printSuccess("Host user and www-data have the same ids.", detail)
+	case runtime.GOOS == "darwin":
+		printInfo("Host user and www-data have different ids.", detail, "Docker Desktop maps file ownership on macOS, so this is harmless.")
+	default:
+		c.warn("Host user and www-data have different ids.", detail,
+			"Files WordPress creates belong to www-data. Run "+commandStyle.Render("./manage fix-perms")+" for group access,",
+			"or change the ids in the Dockerfile's groupmod/usermod line and run "+commandStyle.Render("./manage update")+".")
+	}
+}
+
+// doctorPermissions checks what cmdFixWPContentPermissions sets up: the host user in the
+// web group, and group-writable, setgid directories below ./wp-content and ./wordpress.
+func doctorPermissions(c *doctorCounts) {
+	dirs := []string{"./wp-content", "./wordpress"}
+	if runtime.GOOS == "windows" {
+		printInfo("Skipped on Windows:", "Linux-style permissions do not apply.")
+		return
+	}
+	webGroup := "www-data"
+	if runtime.GOOS == "darwin" {
+		webGroup = "_www"
+	}
+	fixHint := "Run " + commandStyle.Render("./manage fix-perms") + "."
+
+	if current, err := user.Current(); err == nil {
+		group, errGroup := user.LookupGroup(webGroup)
+		ids, errIDs := current.GroupIds()
+		switch {
+		case errGroup != nil:
+			printInfo(fmt.Sprintf("No '%s' group on this host.", webGroup), "Group access relies on the numeric GID instead.")
+		case errIDs == nil && containsString(ids, group.Gid):
+			printSuccess(fmt.Sprintf("%s is in the '%s' group.", current.Username, webGroup))
+		default:
+			c.warn(fmt.Sprintf("%s is not in the '%s' group.", current.Username, webGroup), fixHint, "Log in again afterwards for the group to apply.")
+		}
+	}
+
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			c.warn(fmt.Sprintf("'%s' not found.", dir))
+			continue
+		}
+		if !info.IsDir() {
+			c.issue(fmt.Sprintf("'%s' is not a directory.", dir))
+			continue
+		}
+		var total, notGroupWritable, noSetgid int
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			fi, errInfo := d.Info()
+			if errInfo != nil || fi.Mode()&fs.ModeSymlink != 0 {
+				return nil
+			}
+			total++
+			if fi.Mode().Perm()&0020 == 0 {
+				notGroupWritable++
+			}
+			if d.IsDir() && fi.Mode()&fs.ModeSetgid == 0 {
+				noSetgid++
+			}
+			return nil
+		})
+		if notGroupWritable > 0 || noSetgid > 0 {
+			c.warn(fmt.Sprintf("'%s' is not set up for group access.", dir),
+				fmt.Sprintf("%d of %d entries are not group-writable; %d directories lack setgid.", notGroupWritable, total, noSetgid), fixHint)
+		} else {
+			printSuccess(fmt.Sprintf("'%s' is group-writable with setgid directories.", dir), fmt.Sprintf("%d entries checked.", total))
+		}
+
+		probe := filepath.Join(dir, ".manage-doctor-write-test")
+		if err := os.WriteFile(probe, nil, 0644); err != nil {
+			c.issue(fmt.Sprintf("Cannot write to '%s' as %s.", dir, currentUserName()), err.Error(), fixHint)
+		} else {
+			_ = os.Remove(probe)
+		}
+	}
+}
+
+func currentUserName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "the current user"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// doctorDebugLog shows the last PHP errors and warnings from wp-content/debug.log.
+func doctorDebugLog(c *doctorCounts) {
+	path := filepath.Join("wp-content", "debug.log")
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		printInfo("No wp-content/debug.log.", "Nothing was logged, or WP_DEBUG_LOG is off.")
+		return
+	}
+	if err != nil {
+		c.warn("Could not read wp-content/debug.log.", err.Error())
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		c.warn("Could not read wp-content/debug.log.", err.Error())
+		return
+	}
+	if info.Size() > doctorDebugLogTail {
+		_, _ = f.Seek(-doctorDebugLogTail, io.SeekEnd)
+	}
+	data, _ := io.ReadAll(f)
+
+	var errorLines []string
+	errorCount, warningCount := 0, 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		m := debugLogErrorRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if m[1] == "Warning" {
+			warningCount++
+		} else {
+			errorCount++
+		}
+		if len(line) > 200 {
+			line = line[:197] + "..."
+		}
+		errorLines = append(errorLines, line)
+	}
+	modified := info.ModTime().Format("2006-01-02 15:04")
+	if len(errorLines) == 0 {
+		printSuccess("No PHP errors in the end of debug.log.", fmt.Sprintf("%s, last written %s.", formatSize(info.Size()), modified))
+		return
+	}
+	if len(errorLines) > 5 {
+		errorLines = errorLines[len(errorLines)-5:]
+	}
+	title := fmt.Sprintf("debug.log has %d error(s) and %d warning(s) in its last %s.",
+		errorCount, warningCount, formatSize(min(info.Size(), doctorDebugLogTail)))
+	details := append([]string{fmt.Sprintf("Last written %s. Latest entries:", modified)}, errorLines...)
+	c.warn(title, details...)
+}
+
+// formatSize renders bytes with binary units, e.g. "1.2 MiB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,125 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/regiellis/wp-manager-cli/internal/compose"
+)
+
+// memoryLimitRe accepts the sizes compose takes for deploy.resources.limits.memory,
+// e.g. 512M, 1.5g or 2GiB.
+var memoryLimitRe = regexp.MustCompile(`^\d+(\.\d+)?([bBkKmMgGtT]|[kKmMgGtT]i?[bB])?$`)
+
+// cmdLimits shows or changes the CPU and memory limits of the instance's services,
+// stored as deploy.resources.limits in docker-compose.yml.
+func cmdLimits(ctx context.Context, args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return limitsList()
+	}
+	service := args[0]
+	limitsFlags := flag.NewFlagSet("limits", flag.ContinueOnError)
+	cpus := limitsFlags.String("cpus", "", "CPU limit, e.g. 1.5")
+	memory := limitsFlags.String("memory", "", "Memory limit, e.g. 512M")
+	clearLimits := limitsFlags.Bool("clear", false, "Remove both limits")
+	if err := limitsFlags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if !*clearLimits && *cpus == "" && *memory == "" {
+		printError("Nothing to Change", "Usage: ./manage limits <service> [--cpus 1.5] [--memory 512M] | --clear")
+		return errors.New("no limit given")
+	}
+	if *cpus != "" {
+		if v, err := strconv.ParseFloat(*cpus, 64); err != nil || v <= 0 {
+			printError("Invalid CPU Limit", fmt.Sprintf("'%s' must be a positive number such as 0.5 or 2.", *cpus))
+			return errors.New("invalid cpus")
+		}
+	}
+	if *memory != "" && !memoryLimitRe.MatchString(*memory) {
+		printError("Invalid Memory Limit", fmt.Sprintf("'%s' must be a size such as 512M or 2G.", *memory))
+		return errors.New("invalid memory")
+	}
+
+	printSectionHeader("Resource Limits: " + service)
+	file, err := compose.Load(composeFileName)
+	if err != nil {
+		printError("Could not read "+composeFileName, err.Error())
+		return err
+	}
+	svc := file.Service(service)
+	if svc == nil {
+		printError("Unknown Service", fmt.Sprintf("'%s' is not in %s.", service, composeFileName), "Services: "+strings.Join(file.Services(), ", "))
+		return errors.New("unknown service")
+	}
+	newCPUs, newMemory := svc.ResourceLimits()
+	if *clearLimits {
+		newCPUs, newMemory = "", ""
+	}
+	if *cpus != "" {
+		newCPUs = *cpus
+	}
+	if *memory != "" {
+		newMemory = *memory
+	}
+	svc.SetResourceLimits(newCPUs, newMemory)
+	if err := file.Save(composeFileName); err != nil {
+		printError("Could not write "+composeFileName, err.Error())
+		return err
+	}
+	printSuccess("Limits Updated", fmt.Sprintf("%s: cpus %s, memory %s", service, limitOrNone(newCPUs), limitOrNone(newMemory)))
+
+	printInfo("Recreating " + service + " to apply the limits...")
+	if err := runCommand(ctx, "docker", "compose", "up", "-d", "--no-deps", service); err != nil {
+		printWarning("Could Not Recreate "+service, err.Error(), "The limits apply the next time the service starts.")
+	}
+	return nil
+}
+
+func limitsList() error {
+	printSectionHeader("Resource Limits")
+	file, err := compose.Load(composeFileName)
+	if err != nil {
+		printError("Could not read "+composeFileName, err.Error())
+		return err
+	}
+	nameStyle := lipgloss.NewStyle().Foreground(colorSecondary).Width(16)
+	fmt.Printf("  %s %-10s %s\n", nameStyle.Render("SERVICE"), "CPUS", "MEMORY")
+	for _, name := range file.Services() {
+		cpus, memory := file.Service(name).ResourceLimits()
+		fmt.Printf("  %s %-10s %s\n", nameStyle.Render(name), limitOrNone(cpus), limitOrNone(memory))
+	}
+	fmt.Println()
+	printInfo("Set one with " + commandStyle.Render("./manage limits <service> --cpus 1.5 --memory 512M") + ", remove it with " + commandStyle.Render("--clear") + ".")
+	return nil
+}
+
+func limitOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -138,6 +138,8 @@ const (
 	metaFileName      = ".wordpress-meta.json"
 	envFileName       = ".env"
 	backupsDirDefault = "backups"
+	composeFileName   = "docker-compose.yml"
+	proxyNetworkName  = "wpod-proxy" // external network shared with the wpod Traefik proxy
 )
 
 // --- Helper Functions ---
@@ -259,10 +261,15 @@ func wpCLIGetOutput(ctx context.Context, args ...string) (string, error) {
 }
 
 // --- Instance Metadata (Local) ---
+// LocalInstanceMeta holds the fields manage updates. The file is written by wpod with more
+// fields (directory, template, ...); those are kept in other and written back unchanged.
 type LocalInstanceMeta struct {
 	WordPressVersion string `json:"wordpress_version,omitempty"`
 	DBVersion        string `json:"db_version,omitempty"`
+	DBEngine         string `json:"db_engine,omitempty"`
 	Status           string `json:"status,omitempty"`
+
+	other map[string]json.RawMessage
 }
 
 func readLocalMeta() (*LocalInstanceMeta, error) {
@@ -282,11 +289,27 @@ func readLocalMeta() (*LocalInstanceMeta, error) {
 		}
 		return nil, fmt.Errorf("failed to unmarshal local meta file %s: %w", metaFileName, err)
 	}
+	_ = json.Unmarshal(data, &meta.other)
 	return &meta, nil
 }
 
 func writeLocalMeta(meta *LocalInstanceMeta) error {
-	data, err := json.MarshalIndent(meta, "", "  ")
+	fields := make(map[string]json.RawMessage, len(meta.other)+4)
+	for k, v := range meta.other {
+		fields[k] = v
+	}
+	own, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal local meta data: %w", err)
+	}
+	var ownFields map[string]json.RawMessage
+	if err := json.Unmarshal(own, &ownFields); err != nil {
+		return fmt.Errorf("failed to marshal local meta data: %w", err)
+	}
+	for k, v := range ownFields {
+		fields[k] = v
+	}
+	data, err := json.MarshalIndent(fields, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal local meta data: %w", err)
 	}
@@ -632,11 +655,30 @@ func cmdProdPrep(ctx context.Context) error {
 
 // --- Service Management Commands ---
 
+// ensureProxyNetwork creates the shared Traefik network when the compose file joins it,
+// since compose refuses to start with a missing external network.
+func ensureProxyNetwork(ctx context.Context) {
+	content, err := os.ReadFile(composeFileName)
+	if err != nil || !strings.Contains(string(content), proxyNetworkName) {
+		return
+	}
+	if err := exec.CommandContext(ctx, "docker", "network", "inspect", proxyNetworkName).Run(); err == nil {
+		return
+	}
+	if _, err := runCommandGetOutput(ctx, "docker", "network", "create", proxyNetworkName); err != nil {
+		printWarning("Could not create proxy network", proxyNetworkName, err.Error())
+		return
+	}
+	printInfo("Created shared proxy network:", proxyNetworkName, "Run 'wpod proxy up' to start Traefik.")
+}
+
 func cmdStart(ctx context.Context) error {
 	printSectionHeader("Starting Services")
+	ensureProxyNetwork(ctx)
 	err := runCommand(ctx, "docker", "compose", "up", "-d")
 	if err == nil {
 		updateLocalStatus("Running")
+		refreshDBServerVersion(ctx)
 		printSuccess("Services Started", "WordPress instance is now running in the background.")
 	} else {
 		printError("Failed to Start Services", err.Error())
@@ -688,6 +730,7 @@ func cmdUpdate(ctx context.Context) error {
 	}
 
 	printInfo("Starting updated services...")
+	ensureProxyNetwork(ctx)
 	err := runCommand(ctx, "docker", "compose", "up", "-d", "--force-recreate", "--remove-orphans")
 	if err == nil {
 		updateLocalStatus("Running")
@@ -837,16 +880,13 @@ func cmdWPInstall(ctx context.Context) error {
 	// --- Update Metadata ---
 	printInfo("Updating metadata file...")
 	wpVersion, _ := wpCLIGetOutput(ctx, "core", "version", "--quiet")
-	// dbVersionRaw, _ := wpCLIGetOutput(ctx, "db", "version", "--quiet")
-	// dbVersion := extractDBVersion(dbVersionRaw)
 
 	meta, readErr := readLocalMeta()
 	if readErr != nil {
 		printWarning("Meta Read Failed", "Could not update metadata.", readErr.Error())
 	} else {
 		meta.WordPressVersion = wpVersion
-		// TODO: Find a way to get the DB version from WP-CLI in the container, hard coded for now
-		meta.DBVersion = "8.0" // dbVersion
+		recordDBServerVersion(ctx, meta)
 		if writeErr := writeLocalMeta(meta); writeErr != nil {
 			printWarning("Meta Update Failed", writeErr.Error())
 		}
@@ -857,6 +897,12 @@ func cmdWPInstall(ctx context.Context) error {
 		"Admin User: "+commandStyle.Render(adminUser),
 		"Admin Password: "+commandStyle.Render(adminPassword)+" (Please save this securely!)",
 		"Admin Email: "+commandStyle.Render(adminEmail))
+
+	if _, err := os.Stat(blueprintFileName); err == nil {
+		if err := cmdApplyBlueprint(ctx); err != nil {
+			printWarning("Some blueprint plugins or themes were not applied.", "Fix the entries above and re-run "+commandStyle.Render("./manage blueprint")+".")
+		}
+	}
 	return nil
 }
 
@@ -1755,7 +1801,7 @@ func dbImport(ctx context.Context) error {
 
 	printInfo(fmt.Sprintf("Importing database '%s' from '%s' into 'db' container...", dbName, filepath.Base(absDbFileHostPath)))
 
-	mysqlCmdArgs := []string{"compose", "exec", "-T", "db", "mysql", "-u" + dbUser}
+	mysqlCmdArgs := []string{"compose", "exec", "-T", "db", dbClientCommand("mysql"), "-u" + dbUser}
 	if dbPassword != "" {
 		mysqlCmdArgs = append(mysqlCmdArgs, "-p"+dbPassword)
 	}
@@ -1922,7 +1968,7 @@ func dbExport(ctx context.Context) error {
 
 	printInfo(fmt.Sprintf("Exporting database '%s' from 'db' container to host file: %s", dbName, absExportFileHostPath))
 
-	dumpCmdArgs := []string{"compose", "exec", "-T", "db", "mysqldump", "--no-tablespaces", "-u" + dbUser}
+	dumpCmdArgs := []string{"compose", "exec", "-T", "db", dbClientCommand("mysqldump"), "--no-tablespaces", "-u" + dbUser}
 	if dbPassword != "" {
 		dumpCmdArgs = append(dumpCmdArgs, "-p"+dbPassword)
 	}
@@ -2086,7 +2132,7 @@ func dbExportToFile(ctx context.Context, filePath string) error {
 		return errCred
 	}
 
-	dumpCmdArgs := []string{"compose", "exec", "-T", "db", "mysqldump", "--no-tablespaces", "-u" + dbUser}
+	dumpCmdArgs := []string{"compose", "exec", "-T", "db", dbClientCommand("mysqldump"), "--no-tablespaces", "-u" + dbUser}
 	if dbPassword != "" {
 		dumpCmdArgs = append(dumpCmdArgs, "-p"+dbPassword)
 	}
@@ -2297,6 +2343,8 @@ func main() {
 		cmdShowStatus() // Status doesn't need context and doesn't return error currently
 	case "install":
 		cmdErr = cmdWPInstall(ctx)
+	case "blueprint", "apply-blueprint":
+		cmdErr = cmdApplyBlueprint(ctx)
 	case "plugins":
 		cmdErr = cmdManagePlugins(ctx)
 	case "themes":
@@ -2329,6 +2377,8 @@ func main() {
 		cmdErr = cmdDB(ctx)
 	case "prod-check":
 		cmdErr = cmdProdCheck(ctx)
+	case "doctor":
+		cmdErr = cmdDoctor(ctx)
 	case "prod-prep":
 		cmdErr = cmdProdPrep(ctx)
 	case "mysql-logs": // New command
@@ -2354,6 +2404,10 @@ func main() {
 		cmdXdebug(ctx, false)
 	case "detail-status":
 		cmdErr = cmdDetailStatus(ctx)
+	case "addon", "addons":
+		cmdErr = cmdAddon(ctx, actionArgs)
+	case "limits":
+		cmdErr = cmdLimits(ctx, actionArgs)
 	default:
 		printError("Unknown Command", fmt.Sprintf("Command '%s' is not recognized.", action))
 		showHelp()
@@ -2398,6 +2452,7 @@ func showHelp() {
 		"",
 		boldStyle.Render("WordPress Management:"),
 		fmt.Sprintf("  %s %s", commandStyle.Render("install"), subtleStyle.Render("- Run initial WordPress installation wizard")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("blueprint"), subtleStyle.Render("- Install & activate the plugins and themes listed in blueprint.json")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("plugins"), subtleStyle.Render("- Manage plugins (install, update, toggle, delete)")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("themes"), subtleStyle.Render("- Manage themes (install, update, activate, delete)")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("users"), subtleStyle.Render("- Manage users (list, create, update, delete)")),
@@ -2414,6 +2469,12 @@ func showHelp() {
 		fmt.Sprintf("  %s %s", commandStyle.Render("backup"), subtleStyle.Render("- Backup the database and wp-content directory")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("restore"), subtleStyle.Render("- Restore the database and wp-content from a backup")),
 		"",
+		boldStyle.Render("Service Add-ons:"),
+		fmt.Sprintf("  %s %s", commandStyle.Render("addon list"), subtleStyle.Render("- List the add-on catalogue and what this instance has installed")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("addon add <name>"), subtleStyle.Render("- Add redis, memcached, phpmyadmin or elasticsearch and configure WordPress for it")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("addon remove <name>"), subtleStyle.Render("- Remove an add-on: WordPress setup, container, volumes and .env keys")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("limits [<service> --cpus N --memory SIZE | --clear]"), subtleStyle.Render("- Show or set CPU and memory limits of a service")),
+		"",
 		boldStyle.Render("Convenience:"),
 		fmt.Sprintf("  %s %s", commandStyle.Render("open"), subtleStyle.Render("- Open instance site URL in browser")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("browse"), subtleStyle.Render("- (Alias for open)")),
@@ -2427,6 +2488,7 @@ func showHelp() {
 		"",
 		boldStyle.Render("Filesystem & Permissions:"), // New section or add to existing
 		fmt.Sprintf("  %s %s", commandStyle.Render("fix-perms"), subtleStyle.Render("- Set wp-content host permissions for www-data group access")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("doctor"), subtleStyle.Render("- Diagnose .env, DB credentials, containers, WP-CLI, ownership and debug.log")),
 		"",
 		boldStyle.Render("Production & Security:"),
 		fmt.Sprintf("  %s %s", commandStyle.Render("prod-check"), subtleStyle.Render("- Run checks for production readiness")),
@@ -2456,7 +2518,7 @@ func cmdMySQLLogs(ctx context.Context) error {
 	var found bool
 	for _, logPath := range logPaths {
 		printVerbose("Checking for log file:", logPath)
-		cmd := exec.CommandContext(ctx, "docker", "compose", "exec", "-T", "mysql", "sh", "-c", "test -f "+logPath+" && cat "+logPath)
+		cmd := exec.CommandContext(ctx, "docker", "compose", "exec", "-T", "db", "sh", "-c", "test -f "+logPath+" && cat "+logPath)
 		var out bytes.Buffer
 		cmd.Stdout = &out
 		cmd.Stderr = &out
@@ -2492,10 +2554,8 @@ func cmdXdebug(ctx context.Context, enable bool) error {
 		printError("Failed to "+strings.ToLower(action)+" Xdebug", err.Error())
 		return err
 	}
-	printInfo("Restarting Apache in container...")
-	err = runCommand(ctx, "docker", "compose", "exec", "-T", "wordpress", "apache2ctl", "restart")
-	if err != nil {
-		printWarning("Failed to restart Apache. You may need to restart the container manually.", err.Error())
+	if err := reloadPHP(ctx); err != nil {
+		printWarning("Failed to reload PHP. You may need to restart the container manually.", err.Error())
 	}
 	// Check status (interactive is fine for output)
 	output, _ := runCommandGetOutput(ctx, "docker", "compose", "exec", "wordpress", "php", "-m")
@@ -2507,6 +2567,36 @@ func cmdXdebug(ctx context.Context, enable bool) error {
 	return nil
 }
 
+// containerWebServer returns "apache" or "nginx-fpm". WEB_SERVER from .env decides; older
+// instances without it are probed for apache2ctl.
+func containerWebServer(ctx context.Context) string {
+	switch strings.ToLower(os.Getenv("WEB_SERVER")) {
+	case "apache":
+		return "apache"
+	case "nginx-fpm":
+		return "nginx-fpm"
+	}
+	if _, err := runCommandGetOutput(ctx, "docker", "compose", "exec", "-T", "wordpress", "sh", "-c", "command -v apache2ctl"); err != nil {
+		return "nginx-fpm"
+	}
+	return "apache"
+}
+
+// reloadPHP makes PHP pick up changed extensions and ini files: Apache restarts mod_php,
+// php-fpm (PID 1 in the fpm image) re-executes its master on SIGUSR2.
+func reloadPHP(ctx context.Context) error {
+	if containerWebServer(ctx) == "apache" {
+		printInfo("Restarting Apache in container...")
+		return runCommand(ctx, "docker", "compose", "exec", "-T", "wordpress", "apache2ctl", "restart")
+	}
+	printInfo("Reloading php-fpm in container...")
+	if err := runCommand(ctx, "docker", "compose", "exec", "-T", "wordpress", "kill", "-USR2", "1"); err != nil {
+		printInfo("Signal failed; restarting the wordpress container instead...")
+		return runCommand(ctx, "docker", "compose", "restart", "wordpress")
+	}
+	return nil
+}
+
 // --- Detail Status Command (Lazydocker) ---
 func cmdDetailStatus(ctx context.Context) error {
 	printSectionHeader("Detailed Docker Status (Lazydocker)")
@@ -0,0 +1,154 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// auditLogFileName is the append-only record of actions wpod took on its own, one JSON
+// object per line, next to the registry.
+const auditLogFileName = "audit.log"
+
+// auditEntry is one line of the audit trail.
+type auditEntry struct {
+	Time     string `json:"time"`   // RFC 3339
+	Actor    string `json:"actor"`  // command that acted, e.g. "wpod daemon"
+	Action   string `json:"action"` // e.g. "idle-stop"
+	Instance string `json:"instance"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+func getAuditLogPath() (string, error) {
+	dir, err := getConfigStorageDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, auditLogFileName), nil
+}
+
+// appendAudit adds an entry to the audit trail. The time is filled in when empty.
+func appendAudit(entry auditEntry) error {
+	if entry.Time == "" {
+		entry.Time = time.Now().Format(time.RFC3339)
+	}
+	path, err := getAuditLogPath()
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log %s: %w", path, err)
+	}
+	defer f.Close()
+	// One write per line: appends of this size don't interleave between processes.
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// readAudit returns the audit trail, oldest first. Lines that don't parse are skipped.
+func readAudit() ([]auditEntry, error) {
+	path, err := getAuditLogPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e auditEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// auditCommand implements 'wpod audit [--limit n] [--instance name] [--json]'.
+func auditCommand(args []string) {
+	auditFlags := flag.NewFlagSet("audit", flag.ExitOnError)
+	limit := auditFlags.Int("limit", 50, "Show at most this many of the latest entries (0 = all)")
+	instance := auditFlags.String("instance", "", "Only entries for this instance")
+	jsonOut := auditFlags.Bool("json", false, "Print the entries as JSON")
+	_ = auditFlags.Parse(args)
+
+	entries, err := readAudit()
+	if err != nil {
+		printError("Failed to Read Audit Log", err.Error())
+		os.Exit(1)
+	}
+	if *instance != "" {
+		name := *instance
+		if managerMeta, errMeta := readManagerMeta(); errMeta == nil {
+			if key, _, ok := lookupInstance(managerMeta, name); ok {
+				name = key
+			}
+		}
+		filtered := entries[:0]
+		for _, e := range entries {
+			if e.Instance == name {
+				filtered = append(filtered, e)
+			}
+		}
+		entries = filtered
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+
+	if *jsonOut {
+		if entries == nil {
+			entries = []auditEntry{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(entries)
+		return
+	}
+	printSectionHeader("Audit Trail")
+	if len(entries) == 0 {
+		printInfo("No entries.", "Actions wpod takes on its own, such as idle auto-stop, are recorded here.")
+		return
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("%s  %-12s %-28s %s", e.Time, e.Action, e.Instance, subtleStyle.Render(e.Actor))
+		if e.Detail != "" {
+			line += "  " + e.Detail
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,857 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/regiellis/wp-manager-cli/internal/compose"
+)
+
+// blueprintSchemaVersion is the newest blueprint.json schema wpod understands. Files
+// without a "schema" key are version 1 (name, description, folders, plugins, themes).
+const blueprintSchemaVersion = 2
+
+// Blueprint is the parsed blueprint.json of a template.
+type Blueprint struct {
+	Schema      int                 `json:"schema,omitempty"`
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Folders     []string            `json:"folders,omitempty"`
+	Plugins     []BlueprintPackage  `json:"plugins,omitempty"`
+	Themes      []BlueprintPackage  `json:"themes,omitempty"`
+	Variables   []BlueprintVariable `json:"variables,omitempty"`
+	Services    []BlueprintService  `json:"services,omitempty"`
+	Env         []BlueprintEnvKey   `json:"env,omitempty"`
+	PostCreate  []BlueprintStep     `json:"post_create,omitempty"`
+}
+
+// BlueprintPackage is a plugin or theme installed by 'manage blueprint'. In blueprint.json
+// it is either a string ("query-monitor", "query-monitor@3.16.0", "./plugins/acme.zip")
+// or an object with the fields below.
+type BlueprintPackage struct {
+	Slug     string `json:"slug,omitempty"`
+	Version  string `json:"version,omitempty"`  // wp.org version to pin
+	Source   string `json:"source,omitempty"`   // zip file, zip URL or directory, relative to the instance
+	Activate *bool  `json:"activate,omitempty"` // default true
+}
+
+// parsePackageSpec reads the string form of a BlueprintPackage.
+func parsePackageSpec(spec string) BlueprintPackage {
+	spec = strings.TrimSpace(spec)
+	if isPackageSource(spec) {
+		return BlueprintPackage{Source: spec}
+	}
+	if slug, version, ok := strings.Cut(spec, "@"); ok {
+		return BlueprintPackage{Slug: slug, Version: version}
+	}
+	return BlueprintPackage{Slug: spec}
+}
+
+// isPackageSource reports whether a spec names a zip or directory rather than a wp.org slug.
+func isPackageSource(spec string) bool {
+	return strings.ContainsAny(spec, `/\`) || strings.HasSuffix(strings.ToLower(spec), ".zip")
+}
+
+func (p *BlueprintPackage) UnmarshalJSON(data []byte) error {
+	var spec string
+	if err := json.Unmarshal(data, &spec); err == nil {
+		*p = parsePackageSpec(spec)
+		return nil
+	}
+	type plain BlueprintPackage
+	var obj plain
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("plugin/theme entries must be a string or an object: %w", err)
+	}
+	*p = BlueprintPackage(obj)
+	return nil
+}
+
+// MarshalJSON writes the short string form whenever it carries all the information.
+func (p BlueprintPackage) MarshalJSON() ([]byte, error) {
+	short := p.Slug != "" && p.Source == "" || p.Source != "" && p.Slug == "" && p.Version == ""
+	if p.Activate == nil && short {
+		return json.Marshal(p.String())
+	}
+	type plain BlueprintPackage
+	return json.Marshal(plain(p))
+}
+
+func (p BlueprintPackage) String() string {
+	switch {
+	case p.Source != "":
+		return p.Source
+	case p.Version != "":
+		return p.Slug + "@" + p.Version
+	default:
+		return p.Slug
+	}
+}
+
+// BlueprintVariable is a value asked for at create time.
+type BlueprintVariable struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type,omitempty"` // string (default), int, bool, choice, password
+	Default     interface{} `json:"default,omitempty"`
+	Prompt      string      `json:"prompt,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Options     []string    `json:"options,omitempty"` // for choice
+	Required    bool        `json:"required,omitempty"`
+	Env         string      `json:"env,omitempty"` // env-template key that receives the value
+}
+
+// BlueprintService is a compose service the template runs.
+type BlueprintService struct {
+	Name  string          `json:"name"`
+	Ports []BlueprintPort `json:"ports,omitempty"`
+}
+
+// BlueprintPort maps a host port held in an env key to a container port. Ports with a
+// range are allocated automatically when the env key is still empty after create.
+type BlueprintPort struct {
+	Env       string `json:"env"`
+	Container int    `json:"container"`
+	Range     string `json:"range,omitempty"` // e.g. "9200-9299"
+}
+
+// BlueprintEnvKey documents a key of the template's env-template.
+type BlueprintEnvKey struct {
+	Key         string `json:"key"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+	Secret      bool   `json:"secret,omitempty"`
+}
+
+// BlueprintStep is run once after the instance is first started.
+type BlueprintStep struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"`           // wp (WP-CLI in the wordpress service), manage, shell
+	Args []string `json:"args,omitempty"` // for wp and manage
+	Run  string   `json:"run,omitempty"`  // for shell, run with sh -c in the instance directory
+}
+
+var (
+	blueprintVariableTypes = []string{"string", "int", "bool", "choice", "password"}
+	blueprintStepTypes     = []string{"wp", "manage", "shell"}
+	blueprintVarNameRe     = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	envKeyRe               = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+)
+
+// parseBlueprint decodes blueprint.json. Unknown keys are not an error here; 'template
+// validate' reports them.
+func parseBlueprint(data []byte) (*Blueprint, error) {
+	var bp Blueprint
+	if err := json.Unmarshal(data, &bp); err != nil {
+		return nil, err
+	}
+	if bp.Schema == 0 {
+		bp.Schema = 1
+	}
+	return &bp, nil
+}
+
+func readBlueprint(templateFS fs.FS) (*Blueprint, error) {
+	data, err := fs.ReadFile(templateFS, blueprintFileName)
+	if err != nil {
+		return nil, err
+	}
+	bp, err := parseBlueprint(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", blueprintFileName, err)
+	}
+	return bp, nil
+}
+
+func (v BlueprintVariable) kind() string {
+	if v.Type == "" {
+		return "string"
+	}
+	return v.Type
+}
+
+func (v BlueprintVariable) title() string {
+	if v.Prompt != "" {
+		return v.Prompt
+	}
+	return v.Name
+}
+
+// defaultValue renders the default as the string stored in .env and instance metadata.
+func (v BlueprintVariable) defaultValue() string {
+	switch d := v.Default.(type) {
+	case nil:
+		return ""
+	case bool:
+		return boolEnvValue(d)
+	case float64:
+		return strconv.FormatFloat(d, 'f', -1, 64)
+	case string:
+		return d
+	default:
+		return fmt.Sprint(d)
+	}
+}
+
+// boolEnvValue is how bool variables are written to .env ("1"/"0", which PHP and the
+// WordPress image both read correctly).
+func boolEnvValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// check validates a value for the variable's type.
+func (v BlueprintVariable) check(value string) error {
+	if value == "" {
+		if v.Required {
+			return fmt.Errorf("%s is required", v.Name)
+		}
+		return nil
+	}
+	switch v.kind() {
+	case "int":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s must be a whole number", v.Name)
+		}
+	case "bool":
+		if value != "1" && value != "0" && value != "true" && value != "false" {
+			return fmt.Errorf("%s must be true or false", v.Name)
+		}
+	case "choice":
+		for _, opt := range v.Options {
+			if opt == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be one of: %s", v.Name, strings.Join(v.Options, ", "))
+	}
+	return nil
+}
+
+// normalizeValue converts bool spellings to their .env form.
+func (v BlueprintVariable) normalizeValue(value string) string {
+	if v.kind() == "bool" {
+		switch value {
+		case "true":
+			return "1"
+		case "false":
+			return "0"
+		}
+	}
+	return value
+}
+
+// promptBlueprintVariables builds a huh form from the blueprint's variables and returns
+// the chosen values keyed by variable name.
+func promptBlueprintVariables(bp *Blueprint) (map[string]string, error) {
+	values := make(map[string]string, len(bp.Variables))
+	if len(bp.Variables) == 0 {
+		return values, nil
+	}
+	strs := make(map[string]*string)
+	bools := make(map[string]*bool)
+	var fields []huh.Field
+	for _, v := range bp.Variables {
+		value := v.normalizeValue(v.defaultValue())
+		switch v.kind() {
+		case "bool":
+			b := value == "1"
+			bools[v.Name] = &b
+			fields = append(fields, huh.NewConfirm().Title(v.title()).Description(v.Description).Value(&b))
+		case "choice":
+			str := value
+			strs[v.Name] = &str
+			fields = append(fields, huh.NewSelect[string]().Title(v.title()).Description(v.Description).Options(huh.NewOptions(v.Options...)...).Value(&str))
+		default:
+			str := value
+			strs[v.Name] = &str
+			input := huh.NewInput().Title(v.title()).Description(v.Description).Value(&str).Validate(v.check)
+			if v.kind() == "password" {
+				input = input.EchoMode(huh.EchoModePassword)
+			}
+			fields = append(fields, input)
+		}
+	}
+	if err := huh.NewForm(huh.NewGroup(fields...).Title("Template Options")).WithTheme(theme).Run(); err != nil {
+		return nil, err
+	}
+	for name, str := range strs {
+		values[name] = *str
+	}
+	for name, b := range bools {
+		values[name] = boolEnvValue(*b)
+	}
+	return values, nil
+}
+
+// resolveBlueprintVariables fills defaults for values not supplied (JSON creation) and
+// validates the result.
+func resolveBlueprintVariables(bp *Blueprint, supplied map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(bp.Variables))
+	known := make(map[string]bool, len(bp.Variables))
+	var problems []string
+	for _, v := range bp.Variables {
+		known[v.Name] = true
+		value, ok := supplied[v.Name]
+		if !ok {
+			value = v.defaultValue()
+		}
+		if err := v.check(value); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		values[v.Name] = v.normalizeValue(value)
+	}
+	for name := range supplied {
+		if !known[name] {
+			problems = append(problems, fmt.Sprintf("unknown variable '%s'", name))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return values, nil
+}
+
+// setEnvValue replaces KEY=... in env content; it reports false if the key is absent.
+func setEnvValue(content, key, value string) (string, bool) {
+	re := regexp.MustCompile(fmt.Sprintf(`(?m)^%s=.*$`, regexp.QuoteMeta(key)))
+	if !re.MatchString(content) {
+		return content, false
+	}
+	return re.ReplaceAllLiteralString(content, key+"="+value), true
+}
+
+// setOrAppendEnvValue is setEnvValue that appends keys an older env-template does not
+// declare yet.
+func setOrAppendEnvValue(content, key, value string) string {
+	if updated, ok := setEnvValue(content, key, value); ok {
+		return updated
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + key + "=" + value + "\n"
+}
+
+// applyBlueprintToEnv writes variable values, env defaults and allocated service ports
+// into .env content. Keys already holding a value are only overwritten by variables.
+func applyBlueprintToEnv(content string, bp *Blueprint, values map[string]string, usedPorts map[int]bool) (string, []string) {
+	var notes []string
+	current := func(key string) string {
+		return parseEnvValue([]byte(content), key)
+	}
+	for _, v := range bp.Variables {
+		if v.Env == "" {
+			continue
+		}
+		var ok bool
+		if content, ok = setEnvValue(content, v.Env, values[v.Name]); !ok {
+			notes = append(notes, fmt.Sprintf("variable %s: key %s not in env-template", v.Name, v.Env))
+		}
+	}
+	for _, e := range bp.Env {
+		if e.Default != "" && current(e.Key) == "" {
+			content, _ = setEnvValue(content, e.Key, e.Default)
+		}
+	}
+	for _, svc := range bp.Services {
+		for _, p := range svc.Ports {
+			if p.Range == "" || current(p.Env) != "" {
+				continue
+			}
+			low, high, err := parsePortRange(p.Range)
+			if err != nil {
+				notes = append(notes, fmt.Sprintf("service %s: %v", svc.Name, err))
+				continue
+			}
+			port, err := findAvailablePort(low, high, usedPorts)
+			if err != nil {
+				notes = append(notes, fmt.Sprintf("service %s: %v", svc.Name, err))
+				continue
+			}
+			usedPorts[port] = true
+			content, _ = setEnvValue(content, p.Env, strconv.Itoa(port))
+			notes = append(notes, fmt.Sprintf("%s (%s) -> %d", p.Env, svc.Name, port))
+		}
+	}
+	return content, notes
+}
+
+func parsePortRange(r string) (int, int, error) {
+	parts := strings.SplitN(r, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("port range '%s' must look like 9200-9299", r)
+	}
+	low, errLow := strconv.Atoi(strings.TrimSpace(parts[0]))
+	high, errHigh := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if errLow != nil || errHigh != nil || low < 1 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("port range '%s' is invalid", r)
+	}
+	return low, high, nil
+}
+
+// describeStep renders a post-create step as the command it runs.
+func describeStep(step BlueprintStep) string {
+	switch step.Type {
+	case "wp":
+		return "docker compose exec -T wordpress wp " + strings.Join(step.Args, " ")
+	case "manage":
+		return "./manage " + strings.Join(step.Args, " ")
+	default:
+		return step.Run
+	}
+}
+
+// runBlueprintSteps runs post-create steps in order and stops at the first failure.
+func runBlueprintSteps(instanceDir string, steps []BlueprintStep) error {
+	for i, step := range steps {
+		label := step.Name
+		if label == "" {
+			label = describeStep(step)
+		}
+		printInfo(fmt.Sprintf("Post-create step %d/%d:", i+1, len(steps)), label)
+		var cmd *exec.Cmd
+		switch step.Type {
+		case "wp":
+			cmd = exec.Command("docker", append([]string{"compose", "exec", "-T", "wordpress", "wp"}, step.Args...)...)
+		case "manage":
+			cmd = exec.Command("./"+manageBinaryName(), step.Args...)
+		case "shell":
+			cmd = exec.Command("sh", "-c", step.Run)
+		default:
+			return fmt.Errorf("step %d: unknown type '%s'", i+1, step.Type)
+		}
+		cmd.Dir = instanceDir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("step %d (%s) failed: %w", i+1, label, err)
+		}
+	}
+	return nil
+}
+
+// wordPressInstallWait is how long post-create steps wait for 'wp core is-installed'
+// after './manage install', while the containers finish starting.
+const wordPressInstallWait = 2 * time.Minute
+
+// stepsOfType reports whether any step has the given type.
+func stepsOfType(steps []BlueprintStep, stepType string) bool {
+	for _, step := range steps {
+		if step.Type == stepType {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmPostCreateSteps lists the steps of a template and asks before running them.
+// Shell steps run on the host, so steps from user templates, which may come from any
+// git URL or tarball, need consent unless assumeYes is set. Built-in templates are trusted.
+func confirmPostCreateSteps(steps []BlueprintStep, tmpl templateInfo, assumeYes bool) bool {
+	if assumeYes || tmpl.Source == templateSourceBuiltin || !stepsOfType(steps, "shell") {
+		return true
+	}
+	details := make([]string, 0, len(steps)+1)
+	for _, step := range steps {
+		prefix := "  "
+		if step.Type == "shell" {
+			prefix = "! " // runs on this machine
+		}
+		details = append(details, prefix+describeStep(step))
+	}
+	details = append(details, "Steps marked ! run on this machine with your permissions.")
+	printWarning(fmt.Sprintf("Template '%s' has post-create steps:", tmpl.Dir), details...)
+
+	run := false
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Run these steps?").
+				Description("Only run steps from templates you trust. Pass --yes to skip this question.").
+				Affirmative("Yes, run them").
+				Negative("No, skip them").
+				Value(&run),
+		),
+	).WithTheme(theme)
+	if err := form.Run(); err != nil {
+		return false
+	}
+	return run
+}
+
+// wordPressInstalled reports whether 'wp core is-installed' succeeds in the instance.
+func wordPressInstalled(instanceDir string) bool {
+	cmd := exec.Command("docker", "compose", "exec", "-T", "wordpress", "wp", "core", "is-installed")
+	cmd.Dir = instanceDir
+	return cmd.Run() == nil
+}
+
+// waitForWordPressInstall polls 'wp core is-installed' until it succeeds or timeout passes.
+func waitForWordPressInstall(instanceDir string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if wordPressInstalled(instanceDir) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// --- Template linting ---
+
+type templateIssue struct {
+	Severity string // "error" or "warning"
+	Message  string
+}
+
+func (i templateIssue) isError() bool { return i.Severity == "error" }
+
+var composeVarRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:?[-?+][^}]*)?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// composeEnvReferences returns the variables interpolated in a compose file and whether
+// each one carries a default (${KEY:-x}). Escaped $$ sequences are ignored.
+func composeEnvReferences(content string) map[string]bool {
+	refs := make(map[string]bool)
+	content = strings.ReplaceAll(content, "$$", "")
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, m := range composeVarRe.FindAllStringSubmatch(line, -1) {
+			key := m[1]
+			if key == "" {
+				key = m[3]
+			}
+			hasDefault := strings.HasPrefix(m[2], ":-") || strings.HasPrefix(m[2], "-")
+			refs[key] = refs[key] || hasDefault
+		}
+	}
+	return refs
+}
+
+// envTemplateKeys returns the KEY= entries of an env-template in file order.
+func envTemplateKeys(content string) []string {
+	var keys []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, _, ok := strings.Cut(line, "="); ok {
+			keys = append(keys, strings.TrimSpace(strings.TrimPrefix(key, "export ")))
+		}
+	}
+	return keys
+}
+
+// validateTemplate lints a template directory: blueprint schema, variables, steps, and
+// that env-template, docker-compose.yml and the blueprint agree on env keys and services.
+func validateTemplate(templateFS fs.FS) []templateIssue {
+	var issues []templateIssue
+	errorf := func(format string, args ...interface{}) {
+		issues = append(issues, templateIssue{"error", fmt.Sprintf(format, args...)})
+	}
+	warnf := func(format string, args ...interface{}) {
+		issues = append(issues, templateIssue{"warning", fmt.Sprintf(format, args...)})
+	}
+
+	data, err := fs.ReadFile(templateFS, blueprintFileName)
+	if err != nil {
+		errorf("%s is missing", blueprintFileName)
+		return issues
+	}
+	bp, err := parseBlueprint(data)
+	if err != nil {
+		errorf("%s is not valid JSON: %v", blueprintFileName, err)
+		return issues
+	}
+	strict := json.NewDecoder(bytes.NewReader(data))
+	strict.DisallowUnknownFields()
+	if err := strict.Decode(&Blueprint{}); err != nil {
+		warnf("%s: %v", blueprintFileName, err)
+	}
+	if bp.Schema > blueprintSchemaVersion {
+		errorf("blueprint schema %d is newer than this wpod supports (%d)", bp.Schema, blueprintSchemaVersion)
+	}
+	if strings.TrimSpace(bp.Name) == "" {
+		errorf("blueprint name is empty")
+	}
+
+	renderedCompose := false
+	composeData, err := fs.ReadFile(templateFS, "docker-compose.yml")
+	if err != nil {
+		// A rendered compose file is checked in its template form; {{ }} actions do not
+		// interfere with the ${KEY} scan below.
+		composeData, err = fs.ReadFile(templateFS, "docker-compose.yml"+renderedTemplateSuffix)
+		renderedCompose = err == nil
+	}
+	if err != nil {
+		errorf("docker-compose.yml is missing")
+	}
+	envData, err := fs.ReadFile(templateFS, envTemplateFileName)
+	if err != nil {
+		envData, err = fs.ReadFile(templateFS, ".env-template")
+		if err != nil {
+			errorf("%s is missing", envTemplateFileName)
+		} else {
+			warnf("only .env-template found; wpod prefers %s", envTemplateFileName)
+		}
+	}
+
+	envKeys := make(map[string]bool)
+	for _, key := range envTemplateKeys(string(envData)) {
+		if envKeys[key] {
+			warnf("%s: key %s is listed twice", envTemplateFileName, key)
+		}
+		envKeys[key] = true
+	}
+	declared := make(map[string]bool)
+	for _, e := range bp.Env {
+		if !envKeyRe.MatchString(e.Key) {
+			errorf("env: '%s' is not a valid env key", e.Key)
+		} else if !envKeys[e.Key] {
+			errorf("env: %s is declared in the blueprint but missing from %s", e.Key, envTemplateFileName)
+		}
+		declared[e.Key] = true
+	}
+
+	// Compose and env-template must agree on keys.
+	refs := composeEnvReferences(string(composeData))
+	var refNames []string
+	for key := range refs {
+		refNames = append(refNames, key)
+	}
+	sort.Strings(refNames)
+	for _, key := range refNames {
+		if envKeys[key] {
+			continue
+		}
+		if refs[key] {
+			warnf("docker-compose.yml uses ${%s} (with a default) but %s does not define it", key, envTemplateFileName)
+		} else {
+			errorf("docker-compose.yml uses ${%s} but %s does not define it", key, envTemplateFileName)
+		}
+	}
+	for _, key := range envTemplateKeys(string(envData)) {
+		if _, used := refs[key]; !used && !declared[key] && composeData != nil {
+			warnf("%s defines %s, which docker-compose.yml never uses (declare it under \"env\" if it is used elsewhere)", envTemplateFileName, key)
+		}
+	}
+
+	// Variables.
+	seenVars := make(map[string]bool)
+	for _, v := range bp.Variables {
+		if !blueprintVarNameRe.MatchString(v.Name) {
+			errorf("variable '%s': name must be letters, digits and underscores", v.Name)
+		}
+		if seenVars[v.Name] {
+			errorf("variable '%s' is declared twice", v.Name)
+		}
+		seenVars[v.Name] = true
+		if !containsString(blueprintVariableTypes, v.kind()) {
+			errorf("variable '%s': unknown type '%s' (use %s)", v.Name, v.Type, strings.Join(blueprintVariableTypes, ", "))
+			continue
+		}
+		if v.kind() == "choice" && len(v.Options) == 0 {
+			errorf("variable '%s': choice variables need options", v.Name)
+		}
+		if v.Default != nil {
+			if err := v.check(v.defaultValue()); err != nil {
+				errorf("variable '%s': default: %v", v.Name, err)
+			}
+		}
+		if v.Env != "" && !envKeys[v.Env] {
+			errorf("variable '%s': env key %s is missing from %s", v.Name, v.Env, envTemplateFileName)
+		}
+	}
+
+	// Services and ports.
+	// A rendered compose template is only valid YAML once rendered, so its services are
+	// not checked.
+	composeFile, errParse := compose.Parse(composeData)
+	if errParse != nil && composeData != nil && !renderedCompose {
+		errorf("docker-compose.yml: %v", errParse)
+	}
+	hostPorts := make(map[string]string)
+	for _, svc := range bp.Services {
+		if composeData != nil && composeFile != nil && !renderedCompose && !composeFile.HasService(svc.Name) {
+			errorf("service '%s' is declared in the blueprint but not defined in docker-compose.yml", svc.Name)
+		}
+		for _, p := range svc.Ports {
+			if !envKeys[p.Env] {
+				errorf("service '%s': port env key %s is missing from %s", svc.Name, p.Env, envTemplateFileName)
+			}
+			if other, dup := hostPorts[p.Env]; dup {
+				errorf("port env key %s is used by both '%s' and '%s'", p.Env, other, svc.Name)
+			}
+			hostPorts[p.Env] = svc.Name
+			if p.Container < 1 || p.Container > 65535 {
+				errorf("service '%s': container port %d is invalid", svc.Name, p.Container)
+			}
+			if p.Range != "" {
+				if _, _, err := parsePortRange(p.Range); err != nil {
+					errorf("service '%s': %v", svc.Name, err)
+				}
+			}
+			if _, used := refs[p.Env]; !used && composeData != nil {
+				warnf("service '%s': docker-compose.yml never uses ${%s}", svc.Name, p.Env)
+			}
+		}
+	}
+
+	// Plugins and themes.
+	checkPackages := func(kind string, packages []BlueprintPackage) {
+		for _, p := range packages {
+			if p.Slug == "" && p.Source == "" {
+				errorf("%s entry needs a slug or a source", kind)
+				continue
+			}
+			if p.Version != "" && p.Source != "" {
+				warnf("%s %s: version is ignored for zip and directory sources", kind, p)
+			}
+			if p.Source == "" || strings.Contains(p.Source, "://") {
+				continue
+			}
+			if filepath.IsAbs(p.Source) {
+				warnf("%s %s: absolute source paths only work on this machine", kind, p)
+				continue
+			}
+			if _, err := fs.Stat(templateFS, path.Clean(filepath.ToSlash(p.Source))); err != nil {
+				errorf("%s %s: source not found in the template", kind, p)
+			}
+		}
+	}
+	checkPackages("plugin", bp.Plugins)
+	checkPackages("theme", bp.Themes)
+
+	// Rendered files must parse and execute against a sample instance.
+	sample := sampleTemplateContext(bp)
+	_ = fs.WalkDir(templateFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, renderedTemplateSuffix) {
+			return nil
+		}
+		if p == envTemplateFileName+renderedTemplateSuffix {
+			warnf("%s is not rendered; env-template is filled in by key instead", p)
+			return nil
+		}
+		content, errRead := fs.ReadFile(templateFS, p)
+		if errRead != nil {
+			errorf("%s: %v", p, errRead)
+			return nil
+		}
+		tmpl, errParse := parseFileTemplate(p, content)
+		if errParse != nil {
+			errorf("%v", errParse)
+			return nil
+		}
+		if errExec := tmpl.Execute(io.Discard, sample); errExec != nil {
+			errorf("%v", errExec)
+		}
+		return nil
+	})
+
+	// Post-create steps.
+	for i, step := range bp.PostCreate {
+		if !containsString(blueprintStepTypes, step.Type) {
+			errorf("post_create step %d: unknown type '%s' (use %s)", i+1, step.Type, strings.Join(blueprintStepTypes, ", "))
+			continue
+		}
+		if step.Type == "shell" && strings.TrimSpace(step.Run) == "" {
+			errorf("post_create step %d: shell steps need \"run\"", i+1)
+		}
+		if step.Type != "shell" && len(step.Args) == 0 {
+			errorf("post_create step %d: %s steps need \"args\"", i+1, step.Type)
+		}
+	}
+
+	if bp.Schema < blueprintSchemaVersion {
+		warnf("blueprint uses schema %d; add \"schema\": %d to declare variables, services, env keys and post-create steps", bp.Schema, blueprintSchemaVersion)
+	}
+	return issues
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// templateValidate implements 'wpod template validate <dir|name>'.
+func templateValidate(args []string) {
+	if len(args) < 1 {
+		printError("Template Required", "Usage: wpod template validate <dir|template-name>")
+		os.Exit(1)
+	}
+	target := args[0]
+	printSectionHeader("Validate Template: " + target)
+
+	var templateFS fs.FS
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		templateFS = os.DirFS(target)
+	} else {
+		t, errFind := findTemplate(target)
+		if errFind != nil {
+			printError("Template Not Found", fmt.Sprintf("'%s' is neither a directory nor a known template.", target))
+			os.Exit(1)
+		}
+		templateFS = t.FS
+	}
+
+	issues := validateTemplate(templateFS)
+	errorsFound := 0
+	for _, issue := range issues {
+		if issue.isError() {
+			errorsFound++
+			printError(issue.Message)
+		} else {
+			printWarning(issue.Message)
+		}
+	}
+	if errorsFound > 0 {
+		printError("Template Invalid", fmt.Sprintf("%d error(s), %d warning(s).", errorsFound, len(issues)-errorsFound))
+		os.Exit(1)
+	}
+	printSuccess("Template OK", fmt.Sprintf("%d warning(s).", len(issues)))
+}
@@ -0,0 +1,517 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pkg/browser"
+)
+
+// dashRefreshInterval is how often the dashboard asks Docker for fresh status.
+const dashRefreshInterval = 5 * time.Second
+
+// dashStyles are the dashboard colors. The shared styles in ui.go are tuned for dark
+// terminals, so the light theme gets its own, darker palette.
+type dashStyles struct {
+	title, header, selected, muted, key lipgloss.Style
+	running, degraded, stopped, failed  lipgloss.Style
+}
+
+func newDashStyles(light bool) dashStyles {
+	primary, muted, success, warning, failure, emphasis := colorPrimary, colorMuted, colorSuccess, colorWarning, colorError, colorTextEmphasis
+	selectedBg := lipgloss.Color("#3B3B4F")
+	if light {
+		primary = lipgloss.Color("#1D4ED8")
+		muted = lipgloss.Color("#6B7280")
+		success = lipgloss.Color("#15803D")
+		warning = lipgloss.Color("#A16207")
+		failure = lipgloss.Color("#B91C1C")
+		emphasis = lipgloss.Color("#111827")
+		selectedBg = lipgloss.Color("#E5E7EB")
+	}
+	return dashStyles{
+		title:    lipgloss.NewStyle().Bold(true).Foreground(primary),
+		header:   lipgloss.NewStyle().Bold(true).Foreground(emphasis),
+		selected: lipgloss.NewStyle().Bold(true).Foreground(emphasis).Background(selectedBg),
+		muted:    lipgloss.NewStyle().Foreground(muted),
+		key:      lipgloss.NewStyle().Bold(true).Foreground(primary),
+		running:  lipgloss.NewStyle().Foreground(success),
+		degraded: lipgloss.NewStyle().Foreground(warning).Bold(true),
+		stopped:  lipgloss.NewStyle().Foreground(warning),
+		failed:   lipgloss.NewStyle().Foreground(failure),
+	}
+}
+
+func (s dashStyles) status(status string) lipgloss.Style {
+	switch status {
+	case statusRunning:
+		return s.running
+	case statusDegraded:
+		return s.degraded
+	case statusStopped:
+		return s.stopped
+	case statusMissing:
+		return s.failed
+	default:
+		return s.muted
+	}
+}
+
+func (s dashStyles) service(svc ServiceStatus) lipgloss.Style {
+	switch {
+	case svc.ok():
+		return s.running
+	case (svc.State == "exited" && svc.ExitCode == 0) || svc.State == "created":
+		return s.stopped
+	default:
+		return s.failed
+	}
+}
+
+// Messages driving the dashboard.
+type (
+	dashTickMsg    time.Time
+	dashRefreshMsg struct {
+		entries  []instanceListEntry
+		timedOut []string // instances docker did not answer for in time
+		err      error
+	}
+	// dashActionMsg reports a finished start, stop, delete, log or shell action.
+	dashActionMsg struct {
+		name, action string
+		err          error
+	}
+)
+
+type dashModel struct {
+	entries       []instanceListEntry
+	cursor        int
+	width, height int
+	loaded        bool
+	refreshing    bool
+	refreshedAt   time.Time
+	busy          map[string]string // instance name -> action in progress
+	confirmDelete string            // instance waiting for a y/n answer
+	message       string
+	messageErr    bool
+	styles        dashStyles
+}
+
+// runDashboard opens the full-screen instance dashboard ('wpod dash').
+func runDashboard() {
+	// pkg/browser echoes the opener's output to stdout, which would tear the screen.
+	browser.Stdout, browser.Stderr = io.Discard, io.Discard
+
+	m := dashModel{busy: make(map[string]string), styles: newDashStyles(useLightTheme), refreshing: true}
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		printError("Dashboard Failed", err.Error())
+		os.Exit(1)
+	}
+}
+
+func (m dashModel) Init() tea.Cmd {
+	return tea.Batch(dashRefresh, dashTick())
+}
+
+func dashTick() tea.Cmd {
+	return tea.Tick(dashRefreshInterval, func(t time.Time) tea.Msg { return dashTickMsg(t) })
+}
+
+// dashRefresh reads the registry and asks Docker for the live status of every instance,
+// in parallel and bounded by defaultStatusTimeout so a stalled docker can't freeze the view.
+func dashRefresh() tea.Msg {
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		return dashRefreshMsg{err: err}
+	}
+	names := make([]string, 0, len(managerMeta))
+	for name := range managerMeta {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	statuses := checkStatuses(managerMeta, defaultStatusWorkers, defaultStatusTimeout)
+	entries := make([]instanceListEntry, 0, len(names))
+	var timedOut []string
+	for _, name := range names {
+		entry := newInstanceListEntry(name, managerMeta[name])
+		entry.applyStatus(statuses[name])
+		if statuses[name].TimedOut {
+			timedOut = append(timedOut, name)
+		}
+		entries = append(entries, entry)
+	}
+	return dashRefreshMsg{entries: entries, timedOut: timedOut}
+}
+
+func (m dashModel) selected() (instanceListEntry, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.entries) {
+		return instanceListEntry{}, false
+	}
+	return m.entries[m.cursor], true
+}
+
+func (m dashModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case dashTickMsg:
+		if m.refreshing {
+			return m, dashTick()
+		}
+		m.refreshing = true
+		return m, tea.Batch(dashRefresh, dashTick())
+
+	case dashRefreshMsg:
+		m.refreshing = false
+		m.loaded = true
+		if msg.err != nil {
+			m.setMessage(fmt.Sprintf("Refresh failed: %v", msg.err), true)
+			return m, nil
+		}
+		if len(msg.timedOut) > 0 {
+			m.setMessage("Docker did not answer in time for: "+strings.Join(msg.timedOut, ", "), true)
+		}
+		// Keep the cursor on the same instance when the list changes.
+		current, _ := m.selected()
+		m.entries = msg.entries
+		m.cursor = 0
+		for i, e := range m.entries {
+			if e.Name == current.Name {
+				m.cursor = i
+				break
+			}
+		}
+		m.refreshedAt = time.Now()
+		return m, nil
+
+	case dashActionMsg:
+		delete(m.busy, msg.name)
+		if msg.err != nil {
+			m.setMessage(fmt.Sprintf("%s %s: %v", msg.action, msg.name, msg.err), true)
+		} else if msg.action != "logs" && msg.action != "shell" {
+			m.setMessage(fmt.Sprintf("%s %s: done", msg.action, msg.name), false)
+		}
+		if m.refreshing {
+			return m, nil
+		}
+		m.refreshing = true
+		return m, dashRefresh
+
+	case tea.KeyMsg:
+		if m.confirmDelete != "" {
+			return m.confirmKey(msg)
+		}
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m *dashModel) setMessage(text string, isErr bool) {
+	m.message, m.messageErr = text, isErr
+}
+
+func (m dashModel) confirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	name := m.confirmDelete
+	m.confirmDelete = ""
+	if msg.String() != "y" && msg.String() != "Y" {
+		m.setMessage(fmt.Sprintf("Instance '%s' was not deleted.", name), false)
+		return m, nil
+	}
+	for _, e := range m.entries {
+		if e.Name == name {
+			m.busy[name] = "deleting"
+			m.setMessage(fmt.Sprintf("Deleting %s...", name), false)
+			return m, dashDelete(e)
+		}
+	}
+	return m, nil
+}
+
+func (m dashModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return m, nil
+	case "down", "j":
+		if m.cursor < len(m.entries)-1 {
+			m.cursor++
+		}
+		return m, nil
+	case "home", "g":
+		m.cursor = 0
+		return m, nil
+	case "end", "G":
+		m.cursor = max(len(m.entries)-1, 0)
+		return m, nil
+	case "r":
+		if m.refreshing {
+			return m, nil
+		}
+		m.refreshing = true
+		return m, dashRefresh
+	}
+
+	entry, ok := m.selected()
+	if !ok {
+		return m, nil
+	}
+	if action, busy := m.busy[entry.Name]; busy {
+		m.setMessage(fmt.Sprintf("%s is busy (%s).", entry.Name, action), true)
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "s":
+		m.busy[entry.Name] = "starting"
+		m.setMessage(fmt.Sprintf("Starting %s...", entry.Name), false)
+		return m, dashManage(entry, "start")
+	case "x":
+		m.busy[entry.Name] = "stopping"
+		m.setMessage(fmt.Sprintf("Stopping %s...", entry.Name), false)
+		return m, dashManage(entry, "stop")
+	case "o", "a", "m":
+		url, err := dashURL(entry, msg.String())
+		if err == nil {
+			err = browser.OpenURL(url)
+		}
+		if err != nil {
+			m.setMessage(err.Error(), true)
+		} else {
+			m.setMessage("Opened "+url, false)
+		}
+		return m, nil
+	case "l":
+		return m, dashExec(entry, "logs")
+	case "c":
+		return m, dashExec(entry, "shell")
+	case "d":
+		m.confirmDelete = entry.Name
+		return m, nil
+	}
+	return m, nil
+}
+
+// dashURL builds the same URLs as './manage open|admin|mail'.
+func dashURL(entry instanceListEntry, key string) (string, error) {
+	switch key {
+	case "m":
+		port := entry.Ports["mailpit_web"]
+		if port == 0 {
+			return "", fmt.Errorf("%s: MAILPIT_PORT_WEB not found in .env", entry.Name)
+		}
+		return fmt.Sprintf("http://localhost:%d", port), nil
+	default:
+		if entry.Port == 0 {
+			return "", fmt.Errorf("%s: WORDPRESS_PORT not found in .env", entry.Name)
+		}
+		if key == "a" {
+			return fmt.Sprintf("http://localhost:%d/wp-admin/", entry.Port), nil
+		}
+		return fmt.Sprintf("http://localhost:%d", entry.Port), nil
+	}
+}
+
+// manageCommand runs the instance's own manage binary, so the dashboard does exactly what
+// './manage <args>' does in the instance directory.
+func manageCommand(entry instanceListEntry, args ...string) (*exec.Cmd, error) {
+	binary := filepath.Join(entry.Directory, manageBinaryName())
+	if _, err := os.Stat(binary); err != nil {
+		return nil, fmt.Errorf("manage binary not found in %s", entry.Directory)
+	}
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = entry.Directory
+	return cmd, nil
+}
+
+// dashManage runs './manage start' or './manage stop' in the background.
+func dashManage(entry instanceListEntry, action string) tea.Cmd {
+	return func() tea.Msg {
+		cmd, err := manageCommand(entry, action)
+		if err != nil {
+			return dashActionMsg{name: entry.Name, action: action, err: err}
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return dashActionMsg{name: entry.Name, action: action, err: fmt.Errorf("%v: %s", err, lastLine(out))}
+		}
+		return dashActionMsg{name: entry.Name, action: action}
+	}
+}
+
+// dashExec hands the terminal to './manage logs' or './manage console' until it exits.
+func dashExec(entry instanceListEntry, action string) tea.Cmd {
+	manageAction := "logs"
+	if action == "shell" {
+		manageAction = "console"
+	}
+	cmd, err := manageCommand(entry, manageAction)
+	if err != nil {
+		return func() tea.Msg { return dashActionMsg{name: entry.Name, action: action, err: err} }
+	}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return dashActionMsg{name: entry.Name, action: action, err: err}
+	})
+}
+
+// dashDelete removes containers, volumes, the instance directory and the registry entry,
+// like 'wpod delete' does without the interactive prompts.
+func dashDelete(entry instanceListEntry) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := os.Stat(entry.Directory); err == nil {
+			down := exec.Command("docker", "compose", "down", "--volumes", "--remove-orphans")
+			down.Dir = entry.Directory
+			_ = down.Run() // containers may already be gone
+			if err := os.RemoveAll(entry.Directory); err != nil {
+				return dashActionMsg{name: entry.Name, action: "delete", err: fmt.Errorf("%w (run 'wpod delete' to retry with elevated permissions)", err)}
+			}
+		}
+		// Under the registry lock, so status writes from 'wpod watch' or 'wpod daemon' survive.
+		err := updateManagerMeta(func(managerMeta ManagerMeta) bool {
+			delete(managerMeta, entry.Name)
+			return true
+		})
+		return dashActionMsg{name: entry.Name, action: "delete", err: err}
+	}
+}
+
+// lastLine returns the last non-empty line of command output.
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func (m dashModel) View() string {
+	s := m.styles
+	var b strings.Builder
+
+	header := s.title.Render("WPOD // Dashboard")
+	switch {
+	case m.refreshing:
+		header += "  " + s.muted.Render("refreshing...")
+	case !m.refreshedAt.IsZero():
+		header += "  " + s.muted.Render("updated "+m.refreshedAt.Format("15:04:05"))
+	}
+	b.WriteString(header + "\n\n")
+
+	if !m.loaded {
+		b.WriteString(s.muted.Render("Loading instances...") + "\n")
+		return b.String()
+	}
+	if len(m.entries) == 0 {
+		b.WriteString(s.muted.Render("No instances registered. Create one with 'wpod create'.") + "\n\n")
+		b.WriteString(m.helpLine())
+		return b.String()
+	}
+
+	nameWidth := len("NAME")
+	for _, e := range m.entries {
+		nameWidth = max(nameWidth, len(e.Name))
+	}
+	row := func(name, status, port, services, template string) string {
+		return fmt.Sprintf("  %-*s  %-9s  %-6s  %-9s  %s", nameWidth, name, status, port, services, template)
+	}
+	b.WriteString(s.header.Render(row("NAME", "STATUS", "PORT", "SERVICES", "TEMPLATE")) + "\n")
+
+	// Show a window of rows around the cursor when the list is taller than the screen.
+	selected, _ := m.selected()
+	visible := len(m.entries)
+	if m.height > 0 {
+		visible = max(m.height-12-len(selected.Services), 3)
+	}
+	start := 0
+	if m.cursor >= visible {
+		start = m.cursor - visible + 1
+	}
+	end := min(start+visible, len(m.entries))
+	for i := start; i < end; i++ {
+		e := m.entries[i]
+		status := e.Status
+		if action, ok := m.busy[e.Name]; ok {
+			status = action
+		}
+		port := "-"
+		if e.Port > 0 {
+			port = fmt.Sprint(e.Port)
+		}
+		up := 0
+		for _, svc := range e.Services {
+			if svc.ok() {
+				up++
+			}
+		}
+		services := fmt.Sprintf("%d/%d up", up, len(e.Services))
+		line := row(e.Name, status, port, services, e.Template)
+		if i == m.cursor {
+			b.WriteString(s.selected.Render(line) + "\n")
+			continue
+		}
+		// Pad before styling so ANSI codes don't skew the columns.
+		statusCell := fmt.Sprintf("%-9s", status)
+		b.WriteString(fmt.Sprintf("  %-*s  %s  %-6s  %-9s  %s\n", nameWidth, e.Name, s.status(e.Status).Render(statusCell), port, services, s.muted.Render(e.Template)))
+	}
+	if end < len(m.entries) || start > 0 {
+		b.WriteString(s.muted.Render(fmt.Sprintf("  (%d-%d of %d)", start+1, end, len(m.entries))) + "\n")
+	}
+
+	b.WriteString("\n" + s.header.Render(selected.Name) + "  " + s.muted.Render(selected.Directory) + "\n")
+	for _, svc := range selected.Services {
+		b.WriteString(fmt.Sprintf("  %-14s %s\n", svc.Service, s.service(svc).Render(svc.describe())))
+	}
+	b.WriteString("\n")
+
+	switch {
+	case m.confirmDelete != "":
+		b.WriteString(s.failed.Render(fmt.Sprintf("Delete %s, its containers, volumes and directory? This is irreversible. [y/N]", m.confirmDelete)) + "\n")
+	case m.message != "" && m.messageErr:
+		b.WriteString(s.failed.Render(m.message) + "\n")
+	case m.message != "":
+		b.WriteString(s.running.Render(m.message) + "\n")
+	default:
+		b.WriteString("\n")
+	}
+	b.WriteString(m.helpLine())
+	return b.String()
+}
+
+func (m dashModel) helpLine() string {
+	keys := [][2]string{
+		{"↑/↓", "select"}, {"s", "start"}, {"x", "stop"}, {"o", "site"}, {"a", "admin"}, {"m", "mail"},
+		{"l", "logs"}, {"c", "shell"}, {"d", "delete"}, {"r", "refresh"}, {"q", "quit"},
+	}
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = m.styles.key.Render(k[0]) + " " + m.styles.muted.Render(k[1])
+	}
+	return strings.Join(parts, "  ")
+}
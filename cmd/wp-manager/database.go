@@ -0,0 +1,204 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/regiellis/wp-manager-cli/internal/compose"
+)
+
+const (
+	dbEngineMySQL   = "mysql"
+	dbEngineMariaDB = "mariadb"
+
+	defaultDBEngine       = dbEngineMySQL
+	defaultDBVersion      = "8.0"
+	defaultMariaDBVersion = "11.4" // used when only db_engine=mariadb is given
+)
+
+// dbChoice is one database offered by the create prompt.
+type dbChoice struct {
+	Engine  string
+	Version string
+}
+
+// dbChoices are the prompt options; other MySQL 5.7/8.x and MariaDB 10.x/11.x tags are
+// accepted through JSON creation.
+var dbChoices = []dbChoice{
+	{dbEngineMySQL, "8.0"},
+	{dbEngineMySQL, "8.4"},
+	{dbEngineMySQL, "5.7"},
+	{dbEngineMariaDB, "10.6"},
+	{dbEngineMariaDB, "10.11"},
+	{dbEngineMariaDB, "11.4"},
+}
+
+var dbVersionRe = regexp.MustCompile(`^(\d+)\.(\d+)(\.\d+)?$`)
+
+// normalizeDBEngine accepts "mysql" and "mariadb" (or "maria"); empty stays empty.
+func normalizeDBEngine(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return "", nil
+	case dbEngineMySQL:
+		return dbEngineMySQL, nil
+	case dbEngineMariaDB, "maria":
+		return dbEngineMariaDB, nil
+	}
+	return "", fmt.Errorf("'%s' must be '%s' or '%s'", value, dbEngineMySQL, dbEngineMariaDB)
+}
+
+// validateDBVersion checks that a version tag belongs to a supported series: MySQL 5.7 or
+// 8.x, MariaDB 10.x or 11.x.
+func validateDBVersion(engine, version string) error {
+	match := dbVersionRe.FindStringSubmatch(version)
+	if match == nil {
+		return fmt.Errorf("'%s' is not a version such as 8.0 or 10.11", version)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	switch engine {
+	case dbEngineMySQL:
+		if major == 8 || (major == 5 && minor == 7) {
+			return nil
+		}
+		return fmt.Errorf("MySQL %s is not supported; use 5.7 or 8.x", version)
+	case dbEngineMariaDB:
+		if major == 10 || major == 11 {
+			return nil
+		}
+		return fmt.Errorf("MariaDB %s is not supported; use 10.x or 11.x", version)
+	}
+	return fmt.Errorf("unknown database engine '%s'", engine)
+}
+
+// dbEngineLabel returns the display name of an engine; instances that predate the option
+// run MySQL.
+func dbEngineLabel(engine string) string {
+	if engine == dbEngineMariaDB {
+		return "MariaDB"
+	}
+	return "MySQL"
+}
+
+// describeDB renders e.g. "MySQL 8.0.39" or "MariaDB 11.4"; empty without a version.
+func describeDB(engine, version string) string {
+	if version == "" {
+		return ""
+	}
+	return dbEngineLabel(engine) + " " + version
+}
+
+// warnUnsupportedDBArch warns about MySQL 5.7, which has no arm64 image.
+func warnUnsupportedDBArch(engine, version string) {
+	if runtime.GOARCH == "arm64" && engine == dbEngineMySQL && strings.HasPrefix(version, "5.7") {
+		printWarning("MySQL 5.7 has no arm64 image.", "Docker will run the amd64 image under emulation, which is slow; MariaDB 10.x is the closer native option.")
+	}
+}
+
+func dbImage(engine, version string) string {
+	return engine + ":" + version
+}
+
+// dbHealthcheckTest is the compose healthcheck for the db service. The MariaDB images ship
+// healthcheck.sh (and no longer mysqladmin from 11.0 on).
+func dbHealthcheckTest(engine string) []string {
+	if engine == dbEngineMariaDB {
+		return []string{"CMD", "healthcheck.sh", "--connect", "--innodb_initialized"}
+	}
+	return []string{"CMD", "mysqladmin", "ping", "-h", "localhost"}
+}
+
+// applyDBToEnv writes DB_ENGINE, DB_VERSION and DB_IMAGE into .env content.
+func applyDBToEnv(content, engine, version string) string {
+	values := [][2]string{
+		{"DB_ENGINE", engine},
+		{"DB_VERSION", version},
+		{"DB_IMAGE", dbImage(engine, version)},
+	}
+	for _, kv := range values {
+		content = setOrAppendEnvValue(content, kv[0], kv[1])
+	}
+	return content
+}
+
+// applyDBEngineToCompose points the db service's healthcheck at the engine's own check.
+// Compose files without a db healthcheck are left alone.
+func applyDBEngineToCompose(content, engine string) (string, error) {
+	file, err := compose.Parse([]byte(content))
+	if err != nil {
+		return content, err
+	}
+	db := file.Service("db")
+	if db == nil || !db.SetHealthcheckTest(dbHealthcheckTest(engine)) {
+		return content, nil
+	}
+	out, err := file.Bytes()
+	if err != nil {
+		return content, err
+	}
+	return string(out), nil
+}
+
+// configureInstanceDatabase adapts the compose file of a new instance to its engine.
+func configureInstanceDatabase(instanceDir, engine string) error {
+	composePath := filepath.Join(instanceDir, "docker-compose.yml")
+	content, err := os.ReadFile(composePath)
+	if err != nil {
+		return fmt.Errorf("read docker-compose.yml: %w", err)
+	}
+	patched, err := applyDBEngineToCompose(string(content), engine)
+	if err != nil {
+		return err
+	}
+	if patched == string(content) {
+		return nil
+	}
+	if err := os.WriteFile(composePath, []byte(patched), 0644); err != nil {
+		return fmt.Errorf("write docker-compose.yml: %w", err)
+	}
+	return nil
+}
+
+// syncDetectedVersions copies the WordPress and database versions that './manage' wrote
+// to the instance's local meta file into the registry entry. It reports whether anything
+// changed.
+func syncDetectedVersions(meta *InstanceMeta, local *InstanceMeta) bool {
+	changed := false
+	if local.WordPressVersion != "" && local.WordPressVersion != meta.WordPressVersion {
+		meta.WordPressVersion = local.WordPressVersion
+		changed = true
+	}
+	if local.DBVersion != "" && local.DBVersion != meta.DBVersion {
+		meta.DBVersion = local.DBVersion
+		changed = true
+	}
+	if local.DBEngine != "" && local.DBEngine != meta.DBEngine {
+		meta.DBEngine = local.DBEngine
+		changed = true
+	}
+	return changed
+}
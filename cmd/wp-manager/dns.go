@@ -0,0 +1,351 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	defaultDNSListen = "127.0.0.1:15353" // 5353 is mDNS, usually held by mDNSResponder or avahi
+	dnsAnswerTTL     = 5                 // seconds; short so new instances show up quickly
+)
+
+func configuredDNSListen(config GlobalManagerConfig) string {
+	if config.DNSListen != "" {
+		return config.DNSListen
+	}
+	return defaultDNSListen
+}
+
+// devDNSResolver answers queries for one dev domain suffix from the instance registry.
+// The registry is re-read when the metadata file changes, so instances created while
+// the server runs resolve without a restart.
+type devDNSResolver struct {
+	suffix  string // normalised, e.g. ".test"
+	address net.IP
+
+	mu       sync.Mutex
+	hosts    map[string]string // dev host name -> instance name
+	loadedAt time.Time
+}
+
+func newDevDNSResolver(suffix string, address net.IP) *devDNSResolver {
+	suffix = strings.ToLower(strings.TrimSuffix(suffix, "."))
+	if !strings.HasPrefix(suffix, ".") {
+		suffix = "." + suffix
+	}
+	return &devDNSResolver{suffix: suffix, address: address}
+}
+
+// refresh reloads the host table if the registry changed since the last load.
+func (r *devDNSResolver) refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metaPath, err := getManagerMetaPath()
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(metaPath)
+	if err == nil && r.hosts != nil && !info.ModTime().After(r.loadedAt) {
+		return
+	}
+
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		return
+	}
+	hosts := make(map[string]string)
+	for name, meta := range managerMeta {
+		devHost := instanceDevHostName(name, meta)
+		if strings.HasSuffix(devHost, r.suffix) {
+			hosts[devHost] = name
+		}
+	}
+	r.hosts = hosts
+	r.loadedAt = time.Now()
+}
+
+// hostsOutsideSuffix lists the registered instances whose dev host name is not under
+// suffix, e.g. ones created with .example.local before the default became .test.
+func hostsOutsideSuffix(suffix string) []string {
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		return nil
+	}
+	var outside []string
+	for name, meta := range managerMeta {
+		if devHost := instanceDevHostName(name, meta); !strings.HasSuffix(devHost, suffix) {
+			outside = append(outside, fmt.Sprintf("%s (%s)", name, devHost))
+		}
+	}
+	sort.Strings(outside)
+	return outside
+}
+
+// lookup reports whether name (lower case, no trailing dot) belongs to the served suffix
+// and, if so, which instance owns it. Any subdomain of an instance's dev host matches,
+// which covers multisite subdomains and adminer./mail. prefixes.
+func (r *devDNSResolver) lookup(name string) (instance string, inZone bool) {
+	if name != strings.TrimPrefix(r.suffix, ".") && !strings.HasSuffix(name, r.suffix) {
+		return "", false
+	}
+	r.refresh()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for candidate := name; candidate != ""; {
+		if inst, ok := r.hosts[candidate]; ok {
+			return inst, true
+		}
+		dot := strings.Index(candidate, ".")
+		if dot < 0 {
+			break
+		}
+		candidate = candidate[dot+1:]
+	}
+	return "", true
+}
+
+// answer builds the response for a single DNS request packet.
+func (r *devDNSResolver) answer(request []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(request)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	respHeader := dnsmessage.Header{
+		ID:               header.ID,
+		Response:         true,
+		OpCode:           header.OpCode,
+		RecursionDesired: header.RecursionDesired,
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+	instance, inZone := r.lookup(name)
+	switch {
+	case header.OpCode != 0 || question.Class != dnsmessage.ClassINET:
+		respHeader.RCode = dnsmessage.RCodeNotImplemented
+	case !inZone:
+		// Not ours: refuse rather than recurse so the OS falls back to its other resolvers.
+		respHeader.RCode = dnsmessage.RCodeRefused
+	case instance == "":
+		respHeader.Authoritative = true
+		respHeader.RCode = dnsmessage.RCodeNameError
+	default:
+		respHeader.Authoritative = true
+		respHeader.RCode = dnsmessage.RCodeSuccess
+	}
+
+	builder := dnsmessage.NewBuilder(make([]byte, 0, 512), respHeader)
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	if respHeader.RCode == dnsmessage.RCodeSuccess {
+		rrHeader := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: dnsAnswerTTL}
+		ip4 := r.address.To4()
+		switch {
+		case ip4 != nil && (question.Type == dnsmessage.TypeA || question.Type == dnsmessage.TypeALL):
+			var a [4]byte
+			copy(a[:], ip4)
+			if err := builder.AResource(rrHeader, dnsmessage.AResource{A: a}); err != nil {
+				return nil, err
+			}
+		case ip4 == nil && (question.Type == dnsmessage.TypeAAAA || question.Type == dnsmessage.TypeALL):
+			var aaaa [16]byte
+			copy(aaaa[:], r.address.To16())
+			if err := builder.AAAAResource(rrHeader, dnsmessage.AAAAResource{AAAA: aaaa}); err != nil {
+				return nil, err
+			}
+		}
+		// Other record types get an empty NOERROR answer (the name exists, the type does not).
+	}
+	return builder.Finish()
+}
+
+// serveDevDNS runs the UDP server until ctx is cancelled.
+func serveDevDNS(ctx context.Context, listen string, resolver *devDNSResolver) error {
+	conn, err := net.ListenPacket("udp", listen)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", listen, err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("read failed: %w", err)
+		}
+		response, err := resolver.answer(buf[:n])
+		if err != nil {
+			continue // Malformed query; drop it like most resolvers do.
+		}
+		_, _ = conn.WriteTo(response, addr)
+	}
+}
+
+// dnsResolverInstructions explains how to point the OS resolver at wpod for one suffix.
+func dnsResolverInstructions(suffix, listen string) []string {
+	zone := strings.TrimPrefix(suffix, ".")
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		host, port = listen, "53"
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			"macOS: create a per-domain resolver file (only '" + zone + "' is sent to wpod):",
+			fmt.Sprintf("  sudo mkdir -p /etc/resolver && printf 'nameserver %s\\nport %s\\n' | sudo tee /etc/resolver/%s", host, port, zone),
+			"Remove /etc/resolver/" + zone + " to undo.",
+		}
+	case "windows":
+		lines := []string{
+			"Windows: add a Name Resolution Policy Table rule (elevated PowerShell):",
+			fmt.Sprintf("  Add-DnsClientNrptRule -Namespace '%s' -NameServers '%s'", suffix, host),
+		}
+		if port != "53" {
+			lines = append(lines, "NRPT rules cannot use a custom port; run 'wpod dns serve --listen "+host+":53' from an elevated terminal.")
+		}
+		return append(lines, "Remove it again with Get-DnsClientNrptRule | Remove-DnsClientNrptRule.")
+	default:
+		return []string{
+			"Linux with systemd-resolved: route only '" + zone + "' to wpod:",
+			fmt.Sprintf("  printf '[Resolve]\\nDNS=%s:%s\\nDomains=~%s\\n' | sudo tee /etc/systemd/resolved.conf.d/wpod.conf", host, port, zone),
+			"  sudo systemctl restart systemd-resolved",
+			"Linux with dnsmasq / NetworkManager:",
+			fmt.Sprintf("  server=/%s/%s#%s", zone, host, port),
+			"Test with: dig @" + host + " -p " + port + " <name>" + suffix,
+		}
+	}
+}
+
+// handleDNSCommand handles subcommands for 'wpod dns'.
+func handleDNSCommand(args []string) {
+	if len(args) < 1 {
+		printError("DNS Subcommand Required", "Usage: wpod dns <serve|instructions> [--listen <addr:port>] [--suffix <.suffix>]")
+		return
+	}
+	subcommand := strings.ToLower(args[0])
+	switch subcommand {
+	case "serve":
+		dnsServe(args[1:])
+	case "instructions", "setup":
+		dnsInstructions(args[1:])
+	default:
+		printError("Unknown DNS Subcommand", fmt.Sprintf("Subcommand '%s' not recognized.", subcommand), "Valid subcommands are: serve, instructions")
+	}
+}
+
+func dnsFlags(name string, args []string) (listen, suffix, bind string) {
+	config, _ := readGlobalManagerConfig()
+	dnsFlagSet := flag.NewFlagSet(name, flag.ExitOnError)
+	listenFlag := dnsFlagSet.String("listen", configuredDNSListen(config), "UDP address to listen on")
+	suffixFlag := dnsFlagSet.String("suffix", configuredDevDomainSuffix(config), "Dev domain suffix to answer for")
+	bindFlag := dnsFlagSet.String("bind", configuredBindAddress(config), "Address returned for dev host names")
+	_ = dnsFlagSet.Parse(args)
+	suffix = *suffixFlag
+	if !strings.HasPrefix(suffix, ".") {
+		suffix = "." + suffix
+	}
+	return *listenFlag, strings.ToLower(suffix), *bindFlag
+}
+
+func dnsInstructions(args []string) {
+	listen, suffix, _ := dnsFlags("dns instructions", args)
+	printSectionHeader("Use wpod as Resolver for " + suffix)
+	printInfo("Resolver setup", dnsResolverInstructions(suffix, listen)...)
+}
+
+func dnsServe(args []string) {
+	listen, suffix, bind := dnsFlags("dns serve", args)
+	printSectionHeader("wpod DNS Responder")
+
+	address := net.ParseIP(bind)
+	if address == nil {
+		printError("Invalid Bind Address", fmt.Sprintf("'%s' is not a valid IP address.", bind))
+		return
+	}
+	if strings.Count(suffix, ".") < 1 || suffix == "." {
+		printError("Invalid Suffix", "A dev domain suffix such as '.test' is required.")
+		return
+	}
+
+	if err := backfillDevHostNames(); err != nil {
+		printWarning("Could not record dev host names in the registry.", err.Error())
+	}
+	resolver := newDevDNSResolver(suffix, address)
+	resolver.refresh()
+	printInfo("Serving dev domain:", commandStyle.Render("*"+suffix)+" -> "+address.String())
+	printInfo("Listening on:", commandStyle.Render(listen+" (udp)"))
+	printInfo("Queries outside "+suffix+" are refused.", fmt.Sprintf("%d instance(s) currently registered under this suffix.", len(resolver.hosts)))
+	if outside := hostsOutsideSuffix(suffix); len(outside) > 0 {
+		printWarning("These instances use another suffix and won't resolve here:",
+			append(outside, "Map them with '"+commandStyle.Render("wpod hosts sync")+"' or serve their suffix with --suffix.")...)
+	}
+	printInfo("Resolver setup", dnsResolverInstructions(suffix, listen)...)
+	printInfo("Press Ctrl+C to stop.")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := serveDevDNS(ctx, listen, resolver); err != nil {
+		printError("DNS Server Failed", err.Error())
+		if strings.Contains(err.Error(), "permission denied") {
+			printInfo("Ports below 1024 need elevated permissions; try the default " + defaultDNSListen + ".")
+		}
+		if strings.Contains(err.Error(), "address already in use") {
+			printInfo("Another service holds "+listen+".", "Pick a free port with --listen or 'wpod config set dns_listen <host:port>'.")
+		}
+		os.Exit(1)
+	}
+	printSuccess("DNS responder stopped.")
+}
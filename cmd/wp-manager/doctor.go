@@ -0,0 +1,975 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// 'wpod doctor' exit codes, documented in docs/cli.md.
+const (
+	doctorExitOK     = 0 // no error findings remain
+	doctorExitIssues = 1 // at least one error finding remains
+	doctorExitUsage  = 2 // the doctor could not run, e.g. --json --fix without --yes
+)
+
+// Finding severities, from best to worst.
+const (
+	doctorOK      = "ok"
+	doctorInfo    = "info"
+	doctorWarning = "warning"
+	doctorError   = "error"
+)
+
+// Minimum versions 'wpod doctor' accepts. wpod drives instances through the Compose v2
+// plugin ('docker compose') and parses the JSON output of releases from 20.10 on.
+const (
+	minDockerVersion  = "20.10.0"
+	minComposeVersion = "2.0.0"
+)
+
+// Free disk space below doctorDiskLow is a warning, below doctorDiskCritical an error.
+const (
+	doctorDiskLow      = 5 << 30
+	doctorDiskCritical = 1 << 30
+)
+
+// Port range use at or above doctorPortsBusy is a warning, at or above doctorPortsFull an
+// error: 'wpod create' picks ports at random and starts failing well before 100%.
+const (
+	doctorPortsBusy = 0.75
+	doctorPortsFull = 0.95
+)
+
+// doctorStaleLockAge is when a registry lock counts as left behind by a crashed process.
+// wpod holds the lock for milliseconds.
+const doctorStaleLockAge = time.Minute
+
+// doctorFinding is one result of 'wpod doctor'. IDs are stable so CI can act on them;
+// checks that run once per template, instance, port or project also set Subject.
+type doctorFinding struct {
+	ID       string     `json:"id"`
+	Section  string     `json:"section"`
+	Severity string     `json:"severity"`
+	Subject  string     `json:"subject,omitempty"`
+	Title    string     `json:"title"`
+	Details  []string   `json:"details,omitempty"`
+	Fix      *doctorFix `json:"fix,omitempty"`
+}
+
+// doctorFix is the remediation of a finding. Fixes without an apply func are hints the
+// user has to follow by hand.
+type doctorFix struct {
+	Description string `json:"description"`
+	Automatic   bool   `json:"automatic"`
+	Applied     bool   `json:"applied"`
+	Error       string `json:"error,omitempty"`
+
+	apply func() error
+}
+
+// doctorReport is the 'wpod doctor --json' document.
+type doctorReport struct {
+	OK       bool             `json:"ok"`
+	Errors   int              `json:"errors"`   // error findings that remain after fixes
+	Warnings int              `json:"warnings"` // warning findings that remain after fixes
+	Fixed    int              `json:"fixed"`
+	Findings []*doctorFinding `json:"findings"`
+}
+
+// doctorRun collects findings. Unless quiet, each finding is printed once the next one
+// starts, so a remediation attached with fix() is printed along with it.
+type doctorRun struct {
+	quiet    bool
+	section  string
+	findings []*doctorFinding
+	pending  *doctorFinding
+}
+
+func (d *doctorRun) begin(section string) {
+	d.flush()
+	d.section = section
+	if !d.quiet {
+		fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- " + section + " ---"))
+	}
+}
+
+func (d *doctorRun) add(severity, id, subject, title string, details ...string) *doctorFinding {
+	d.flush()
+	f := &doctorFinding{ID: id, Section: d.section, Severity: severity, Subject: subject, Title: title, Details: details}
+	d.findings = append(d.findings, f)
+	d.pending = f
+	return f
+}
+
+func (d *doctorRun) ok(id, subject, title string, details ...string) *doctorFinding {
+	return d.add(doctorOK, id, subject, title, details...)
+}
+
+func (d *doctorRun) info(id, subject, title string, details ...string) *doctorFinding {
+	return d.add(doctorInfo, id, subject, title, details...)
+}
+
+func (d *doctorRun) warn(id, subject, title string, details ...string) *doctorFinding {
+	return d.add(doctorWarning, id, subject, title, details...)
+}
+
+func (d *doctorRun) fail(id, subject, title string, details ...string) *doctorFinding {
+	return d.add(doctorError, id, subject, title, details...)
+}
+
+// fix attaches a remediation; apply may be nil for a hint.
+func (f *doctorFinding) fix(description string, apply func() error) {
+	f.Fix = &doctorFix{Description: description, Automatic: apply != nil, apply: apply}
+}
+
+func (d *doctorRun) flush() {
+	f := d.pending
+	d.pending = nil
+	if f == nil || d.quiet {
+		return
+	}
+	details := f.Details
+	if f.Fix != nil {
+		label := "Fix: "
+		if !f.Fix.Automatic {
+			label = "Hint: "
+		}
+		details = append(append([]string{}, details...), label+f.Fix.Description)
+	}
+	switch f.Severity {
+	case doctorOK:
+		printSuccess(f.Title, details...)
+	case doctorInfo:
+		printInfo(f.Title, details...)
+	case doctorWarning:
+		printWarning(f.Title, details...)
+	default:
+		printError(f.Title, details...)
+	}
+}
+
+// report counts what remains after fixes.
+func (d *doctorRun) report() doctorReport {
+	r := doctorReport{Findings: d.findings}
+	for _, f := range d.findings {
+		if f.Fix != nil && f.Fix.Applied {
+			r.Fixed++
+			continue
+		}
+		switch f.Severity {
+		case doctorError:
+			r.Errors++
+		case doctorWarning:
+			r.Warnings++
+		}
+	}
+	r.OK = r.Errors == 0
+	return r
+}
+
+// doctorCommand implements 'wpod doctor [--fix] [--yes] [--json]'.
+func doctorCommand(args []string) {
+	doctorFlags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	applyFixes := doctorFlags.Bool("fix", false, "Offer to apply the remediation of each fixable finding")
+	assumeYes := doctorFlags.Bool("yes", false, "With --fix, apply every remediation without asking")
+	jsonOut := doctorFlags.Bool("json", false, "Print the findings as JSON")
+	_ = doctorFlags.Parse(args)
+
+	if *jsonOut && *applyFixes && !*assumeYes {
+		fmt.Fprintln(os.Stderr, "wpod doctor: --json --fix cannot ask for confirmation; add --yes")
+		os.Exit(doctorExitUsage)
+	}
+
+	d := &doctorRun{quiet: *jsonOut}
+	if !d.quiet {
+		printSectionHeader("System Doctor: Environment Check")
+	}
+	runDoctorChecks(d)
+	d.flush()
+	if *applyFixes {
+		d.applyFixes(*assumeYes)
+	}
+
+	report := d.report()
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(report)
+	} else {
+		fixable := 0
+		for _, f := range report.Findings {
+			if f.Fix != nil && f.Fix.Automatic && !f.Fix.Applied && f.Severity != doctorOK && f.Severity != doctorInfo {
+				fixable++
+			}
+		}
+		fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- Doctor Check Summary ---"))
+		if report.Fixed > 0 {
+			printSuccess(fmt.Sprintf("Fixed %d finding(s).", report.Fixed))
+		}
+		if report.OK {
+			printSuccess("All critical checks passed!", "Your environment seems ready.")
+		} else {
+			errorStr := "issues"
+			if report.Errors == 1 {
+				errorStr = "issue"
+			}
+			printError(fmt.Sprintf("Found %d critical %s.", report.Errors, errorStr), "Please review the errors above.")
+		}
+		if fixable > 0 && !*applyFixes {
+			printInfo(fmt.Sprintf("%d finding(s) can be fixed automatically.", fixable), "Run "+commandStyle.Render("wpod doctor --fix")+" to apply them.")
+		}
+	}
+	if !report.OK {
+		os.Exit(doctorExitIssues)
+	}
+}
+
+// applyFixes runs the automatic remediations of warning and error findings, asking first
+// unless assumeYes.
+func (d *doctorRun) applyFixes(assumeYes bool) {
+	var fixable []*doctorFinding
+	for _, f := range d.findings {
+		if f.Fix != nil && f.Fix.Automatic && (f.Severity == doctorWarning || f.Severity == doctorError) {
+			fixable = append(fixable, f)
+		}
+	}
+	if !d.quiet {
+		fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- Fixes ---"))
+		if len(fixable) == 0 {
+			printInfo("Nothing to fix automatically.")
+		}
+	}
+	for _, f := range fixable {
+		if !assumeYes {
+			confirm := false
+			_ = huh.NewConfirm().
+				Title(f.Title).
+				Description(f.Fix.Description).
+				Affirmative("Yes, fix it").
+				Negative("No, skip").
+				Value(&confirm).WithTheme(theme).Run()
+			if !confirm {
+				printInfo("Skipped:", f.Title)
+				continue
+			}
+		}
+		if err := f.Fix.apply(); err != nil {
+			f.Fix.Error = err.Error()
+			if !d.quiet {
+				printError("Fix Failed: "+f.Title, err.Error())
+			}
+			continue
+		}
+		f.Fix.Applied = true
+		if !d.quiet {
+			printSuccess("Fixed: "+f.Title, f.Fix.Description)
+		}
+	}
+}
+
+// runDoctorChecks runs every check in display order. Checks that need Docker are skipped
+// when it is missing or not responding.
+func runDoctorChecks(d *doctorRun) {
+	d.begin("System")
+	d.info("system.platform", "", "Platform", fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH))
+
+	d.begin("Docker Environment")
+	daemonOk := doctorDocker(d)
+
+	d.begin("Templates")
+	doctorTemplates(d)
+
+	d.begin("Go Build Environment (for wpod itself)")
+	if goPath, goOk := checkExecutable("go"); goOk {
+		d.ok("go.installed", "", "Go Compiler Found", fmt.Sprintf("Executable path: %s", goPath))
+	} else {
+		d.info("go.installed", "", "Go Compiler Not Found", "Not needed to run a pre-compiled wpod, only for development.")
+	}
+
+	d.begin("Filesystem Permissions")
+	doctorWritable(d)
+
+	d.begin("Disk Space")
+	doctorDiskSpace(d, daemonOk)
+
+	d.begin("Instances")
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		d.fail("registry.readable", "", "Failed to Read Manager Metadata", err.Error())
+	} else {
+		doctorRegistry(d, managerMeta)
+		doctorPorts(d, managerMeta)
+		if daemonOk {
+			doctorOrphans(d, managerMeta)
+		}
+	}
+
+	d.begin("Network Connectivity")
+	networkOk := true
+	for _, host := range []string{"hub.docker.com", "github.com", "raw.githubusercontent.com"} {
+		if _, err := net.LookupHost(host); err != nil {
+			d.warn("network.dns", host, "Network Resolution Failed", fmt.Sprintf("Could not resolve '%s'.", host), fmt.Sprintf("Error: %v", err))
+			networkOk = false
+		}
+	}
+	if networkOk {
+		d.ok("network.dns", "", "Network Resolution OK", "Able to resolve common external hostnames.")
+	}
+}
+
+// doctorDocker checks the docker CLI, the daemon, Compose and their versions. It reports
+// whether the daemon answers.
+func doctorDocker(d *doctorRun) bool {
+	dockerPath, dockerOk := checkExecutable("docker")
+	if !dockerOk {
+		d.fail("docker.installed", "", "Docker Not Found", "The 'docker' command is required but not found in your system's PATH.").
+			fix("Install Docker: https://docs.docker.com/get-docker/", nil)
+		return false
+	}
+	d.ok("docker.installed", "", "Docker Found", fmt.Sprintf("Executable path: %s", dockerPath))
+
+	if _, err := dockerStdout("info"); err != nil {
+		d.fail("docker.daemon", "", "Docker Daemon Not Responding", "Could not connect to the Docker daemon.", err.Error()).
+			fix(dockerStartHint(), nil)
+		return false
+	}
+	d.ok("docker.daemon", "", "Docker Daemon Responding")
+
+	if out, err := dockerStdout("compose", "version"); err == nil {
+		d.ok("docker.compose", "", "Docker Compose Found", fmt.Sprintf("'docker compose' (v2) is available. (%s)", lastLine(out)))
+		checkVersion(d, "compose.version", "Docker Compose", minComposeVersion, "compose", "version", "--short")
+	} else if _, v1Ok := checkExecutable("docker-compose"); v1Ok {
+		d.fail("docker.compose", "", "Only Docker Compose v1 Found", "'docker-compose' (v1) is installed, but wpod runs 'docker compose' (v2).").
+			fix("Install the Docker Compose v2 plugin: https://docs.docker.com/compose/install/", nil)
+	} else {
+		d.fail("docker.compose", "", "Docker Compose Not Found", "'docker compose' (v2) did not run.").
+			fix("Install the Docker Compose v2 plugin: https://docs.docker.com/compose/install/", nil)
+	}
+	checkVersion(d, "docker.version", "Docker Engine", minDockerVersion, "version", "--format", "{{.Server.Version}}")
+	return true
+}
+
+// dockerStartHint tells how to start the Docker daemon on this platform.
+func dockerStartHint() string {
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return "Start Docker Desktop and wait until it reports that the engine is running."
+	default:
+		return "Start the daemon with 'sudo systemctl start docker' (add 'enable --now' to start it at boot), or start Docker Desktop if you use it."
+	}
+}
+
+func checkVersion(d *doctorRun, id, label, minimum string, args ...string) {
+	out, err := dockerStdout(args...)
+	version := strings.TrimSpace(string(out))
+	switch {
+	case err != nil || version == "":
+		d.warn(id, "", label+" Version Unknown", fmt.Sprintf("Could not read the %s version.", label))
+	case !versionAtLeast(version, minimum):
+		d.fail(id, "", label+" Too Old", fmt.Sprintf("Version %s is installed; wpod needs %s or newer.", version, minimum))
+	default:
+		d.ok(id, "", label+" Version OK", fmt.Sprintf("%s (minimum %s)", version, minimum))
+	}
+}
+
+// versionAtLeast compares dotted versions such as "v2.27.1-desktop.1" numerically.
+// Suffixes after the numeric part are ignored.
+func versionAtLeast(version, minimum string) bool {
+	parse := func(v string) []int {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+		if i := strings.IndexAny(v, "-+ "); i >= 0 {
+			v = v[:i]
+		}
+		var parts []int
+		for _, p := range strings.Split(v, ".") {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				break
+			}
+			parts = append(parts, n)
+		}
+		return parts
+	}
+	have, want := parse(version), parse(minimum)
+	for i := range want {
+		h := 0
+		if i < len(have) {
+			h = have[i]
+		}
+		if h != want[i] {
+			return h > want[i]
+		}
+	}
+	return true
+}
+
+// doctorTemplates validates every available template, as 'wpod template validate' does,
+// and checks that the default template carries the manage binary other templates borrow.
+func doctorTemplates(d *doctorRun) {
+	templates, err := loadTemplates()
+	if err != nil {
+		d.fail("templates.valid", "", "No Templates Available", err.Error())
+		return
+	}
+	for _, t := range templates {
+		label := fmt.Sprintf("%s (%s)", t.Dir, t.Source)
+		var errs []string
+		warnings := 0
+		for _, issue := range validateTemplate(t.FS) {
+			if issue.isError() {
+				errs = append(errs, issue.Message)
+			} else {
+				warnings++
+			}
+		}
+		if t.Dir == defaultTemplateName {
+			if _, errStat := fs.Stat(t.FS, manageBinaryName()); errStat != nil {
+				errs = append(errs, fmt.Sprintf("%s binary is missing; new instances get no manage tool (build wpod with 'task build-current')", manageBinaryName()))
+			}
+		}
+		var details []string
+		if warnings > 0 {
+			details = append(details, fmt.Sprintf("%d warning(s); see 'wpod template validate %s'.", warnings, t.Dir))
+		}
+		if len(errs) > 0 {
+			d.fail("templates.valid", t.Dir, "Template Invalid: "+label, append(errs, details...)...)
+		} else {
+			d.ok("templates.valid", t.Dir, "Template OK: "+label, details...)
+		}
+	}
+}
+
+func doctorWritable(d *doctorRun) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		d.fail("fs.cwd_writable", "", "Cannot Check Permissions", "Failed to get current working directory.", err.Error())
+		return
+	}
+	tempFilePath := filepath.Join(cwd, ".wpod-doctor-write-test."+strconv.FormatInt(time.Now().UnixNano(), 10))
+	if err := os.WriteFile(tempFilePath, []byte("test"), 0600); err != nil {
+		d.fail("fs.cwd_writable", "", "Write Permission Denied (Current Directory)", fmt.Sprintf("Cannot write files in '%s'.", cwd), fmt.Sprintf("Error: %v", err))
+		return
+	}
+	_ = os.Remove(tempFilePath)
+	d.ok("fs.cwd_writable", "", "Write Permission Granted (Current Directory)", fmt.Sprintf("Can write files in '%s'.", cwd))
+}
+
+// doctorSitesDirectory returns where new instances go: the configured sites base
+// directory, or the current directory when none is set.
+func doctorSitesDirectory() (string, bool) {
+	config, _ := readGlobalManagerConfig()
+	if config.SitesBaseDirectory != "" {
+		if abs, err := filepath.Abs(config.SitesBaseDirectory); err == nil {
+			return abs, true
+		}
+		return config.SitesBaseDirectory, true
+	}
+	cwd, _ := os.Getwd()
+	return cwd, false
+}
+
+// doctorDiskSpace reports free space for the sites directory and Docker's data root.
+func doctorDiskSpace(d *doctorRun, daemonOk bool) {
+	report := func(id, label, path string) {
+		free, total, err := diskSpace(path)
+		if err != nil {
+			d.warn(id, "", label+": Free Space Unknown", path, err.Error())
+			return
+		}
+		detail := fmt.Sprintf("%s free of %s on the filesystem holding %s", formatByteSize(int64(free)), formatByteSize(int64(total)), path)
+		switch {
+		case free < doctorDiskCritical:
+			d.fail(id, "", label+": Disk Almost Full", detail, "Free up space before creating or starting instances.")
+		case free < doctorDiskLow:
+			d.warn(id, "", label+": Disk Space Low", detail)
+		default:
+			d.ok(id, "", label+": Disk Space OK", detail)
+		}
+	}
+
+	sitesDir, configured := doctorSitesDirectory()
+	label := "Sites Directory"
+	if !configured {
+		label = "Current Directory (no sites_base_directory set)"
+	}
+	measured := sitesDir
+	if _, err := os.Stat(sitesDir); errors.Is(err, os.ErrNotExist) {
+		dir := sitesDir
+		d.warn("sites.base_dir", "", "Sites Directory Missing", fmt.Sprintf("'%s' does not exist.", dir)).
+			fix(fmt.Sprintf("Create %s.", dir), func() error { return os.MkdirAll(dir, 0755) })
+		// Measure the filesystem it will be created on.
+		for measured = filepath.Dir(sitesDir); ; measured = filepath.Dir(measured) {
+			if _, errStat := os.Stat(measured); errStat == nil || measured == filepath.Dir(measured) {
+				break
+			}
+		}
+	}
+	report("disk.sites", label, measured)
+
+	if !daemonOk {
+		return
+	}
+	out, err := dockerStdout("info", "--format", "{{.DockerRootDir}}")
+	root := strings.TrimSpace(string(out))
+	if err != nil || root == "" {
+		d.warn("disk.docker_root", "", "Docker Root Unknown", "Could not read DockerRootDir from 'docker info'.")
+		return
+	}
+	if _, errStat := os.Stat(root); errStat != nil {
+		d.info("disk.docker_root", "", "Docker Root:", fmt.Sprintf("%s is not on this host's filesystem (Docker Desktop keeps it in a VM); check the VM disk in Docker Desktop's settings.", root))
+		return
+	}
+	report("disk.docker_root", "Docker Root", root)
+}
+
+// doctorRegistry checks for a registry lock left behind by a crashed wpod and for
+// registrations whose directory is gone.
+func doctorRegistry(d *doctorRun, managerMeta ManagerMeta) {
+	if metaPath, err := getManagerMetaPath(); err == nil {
+		lockPath := metaPath + ".lock"
+		if info, errStat := os.Stat(lockPath); errStat == nil {
+			age := time.Since(info.ModTime()).Round(time.Second)
+			if age >= doctorStaleLockAge {
+				d.fail("registry.lock", "", "Stale Registry Lock", fmt.Sprintf("%s is %s old; commands that change the registry fail until it is gone.", lockPath, age)).
+					fix("Remove "+lockPath+".", func() error { return os.Remove(lockPath) })
+			} else {
+				d.info("registry.lock", "", "Registry Locked", fmt.Sprintf("Another wpod process holds %s.", lockPath))
+			}
+		} else {
+			d.ok("registry.lock", "", "Registry Not Locked")
+		}
+	}
+
+	names := make([]string, 0, len(managerMeta))
+	for name, meta := range managerMeta {
+		if _, err := os.Stat(meta.Directory); errors.Is(err, os.ErrNotExist) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := name
+		d.warn("registry.missing_dir", name, "Instance Directory Missing: "+name, fmt.Sprintf("%s no longer exists.", managerMeta[name].Directory)).
+			fix("Remove the registration, as 'wpod prune' does.", func() error {
+				return updateManagerMeta(func(current ManagerMeta) bool {
+					_, ok := current[key]
+					delete(current, key)
+					return ok
+				})
+			})
+	}
+	if len(names) == 0 {
+		d.ok("registry.missing_dir", "", "All Instance Directories Found", fmt.Sprintf("%d registered instance(s).", len(managerMeta)))
+	}
+}
+
+// templatePortRange is one automatically allocated port, as declared in blueprints.
+type templatePortRange struct {
+	Env       string
+	Low, High int
+}
+
+// allocatedPortRanges collects the port ranges of every available template. Templates
+// that share a key and range count once.
+func allocatedPortRanges() []templatePortRange {
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil
+	}
+	seen := make(map[templatePortRange]bool)
+	var ranges []templatePortRange
+	for _, t := range templates {
+		bp, err := readBlueprint(t.FS)
+		if err != nil {
+			continue
+		}
+		for _, svc := range bp.Services {
+			for _, p := range svc.Ports {
+				low, high, err := parsePortRange(p.Range)
+				if p.Range == "" || err != nil {
+					continue
+				}
+				r := templatePortRange{Env: p.Env, Low: low, High: high}
+				if !seen[r] {
+					seen[r] = true
+					ranges = append(ranges, r)
+				}
+			}
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Low != ranges[j].Low {
+			return ranges[i].Low < ranges[j].Low
+		}
+		return ranges[i].Env < ranges[j].Env
+	})
+	return ranges
+}
+
+// portUser is one instance's env key holding an allocated port.
+type portUser struct {
+	Instance string
+	Key      string // empty when the port comes from the registry, not .env
+}
+
+func (u portUser) String() string {
+	if u.Key == "" {
+		return u.Instance + " (registry)"
+	}
+	return fmt.Sprintf("%s (%s)", u.Instance, u.Key)
+}
+
+// registeredPorts reads the allocated ports of every registered instance from its .env.
+// Instances without a readable .env fall back to the WordPress port in the registry.
+func registeredPorts(managerMeta ManagerMeta, ranges []templatePortRange) map[int][]portUser {
+	keys := map[string]bool{"WORDPRESS_PORT": true}
+	for _, r := range ranges {
+		keys[r.Env] = true
+	}
+	users := make(map[int][]portUser)
+	for name, meta := range managerMeta {
+		data, err := os.ReadFile(filepath.Join(meta.Directory, ".env"))
+		if err != nil {
+			if meta.WordPressPort > 0 {
+				users[meta.WordPressPort] = append(users[meta.WordPressPort], portUser{Instance: name})
+			}
+			continue
+		}
+		for key, value := range parseEnvContent(string(data)) {
+			if port, errAtoi := strconv.Atoi(value); errAtoi == nil && port > 0 && keys[key] {
+				users[port] = append(users[port], portUser{Instance: name, Key: key})
+			}
+		}
+	}
+	for port := range users {
+		sort.Slice(users[port], func(i, j int) bool { return users[port][i].String() < users[port][j].String() })
+	}
+	return users
+}
+
+// doctorPorts reports how full each template port range is and which registered
+// instances were given the same port.
+func doctorPorts(d *doctorRun, managerMeta ManagerMeta) {
+	ranges := allocatedPortRanges()
+	users := registeredPorts(managerMeta, ranges)
+
+	for _, r := range ranges {
+		used := 0
+		for port := range users {
+			if port >= r.Low && port <= r.High {
+				used++
+			}
+		}
+		size := r.High - r.Low + 1
+		share := float64(used) / float64(size)
+		label := fmt.Sprintf("%s %d-%d", r.Env, r.Low, r.High)
+		detail := fmt.Sprintf("%d of %d ports used by registered instances (%.1f%%)", used, size, share*100)
+		switch {
+		case share >= doctorPortsFull:
+			d.fail("ports.range", label, "Port Range Nearly Full: "+label, detail, "Delete unused instances or widen the range in the template's blueprint.json.")
+		case share >= doctorPortsBusy:
+			d.warn("ports.range", label, "Port Range Busy: "+label, detail)
+		default:
+			d.ok("ports.range", label, "Port Range: "+label, detail)
+		}
+	}
+
+	var clashes []int
+	for port, who := range users {
+		if len(who) > 1 {
+			clashes = append(clashes, port)
+		}
+	}
+	sort.Ints(clashes)
+	for _, port := range clashes {
+		names := make([]string, len(users[port]))
+		for i, u := range users[port] {
+			names[i] = u.String()
+		}
+		f := d.fail("ports.clash", strconv.Itoa(port), fmt.Sprintf("Port Clash: %d", port), "Assigned to "+strings.Join(names, ", ")+".",
+			"Only one of them can be bound at a time.")
+		if moves := portClashMoves(users[port]); len(moves) > 0 {
+			movedNames := make([]string, len(moves))
+			for i, u := range moves {
+				movedNames[i] = u.String()
+			}
+			f.fix(fmt.Sprintf("Give %s a free port from its template range; restart those instances afterwards.", strings.Join(movedNames, ", ")), nil)
+			f.Fix.Automatic = true
+			f.Fix.apply = func() error {
+				assigned, err := reassignPorts(managerMeta, ranges, port, moves)
+				if len(assigned) > 0 {
+					f.Fix.Description = "Moved " + strings.Join(assigned, ", ") + "; restart those instances."
+				}
+				return err
+			}
+		}
+	}
+	if len(clashes) == 0 {
+		d.ok("ports.clash", "", "No Port Clashes", fmt.Sprintf("%d registered instance(s) use distinct ports.", len(managerMeta)))
+	}
+}
+
+// portClashMoves picks the users that give up a clashing port. One user keeps it,
+// preferably a WORDPRESS_PORT: moving that one means rewriting the site URL stored in the
+// database, which only works while the instance is running.
+func portClashMoves(users []portUser) []portUser {
+	keep := 0
+	for i, u := range users {
+		if u.Key == "WORDPRESS_PORT" {
+			keep = i
+			break
+		}
+	}
+	var moves []portUser
+	for i, u := range users {
+		if i != keep && u.Key != "" {
+			moves = append(moves, u)
+		}
+	}
+	return moves
+}
+
+// reassignPorts moves each user off oldPort to a free port of its key's template range.
+// WordPress ports are also recorded in the registry and the instance meta file, and the
+// home and siteurl options are rewritten to the new port. It returns the moves made, e.g.
+// "blog (ADMINER_PORT) to 8412", with the manual step when the options could not be set.
+func reassignPorts(managerMeta ManagerMeta, ranges []templatePortRange, oldPort int, moves []portUser) ([]string, error) {
+	var assigned []string
+	usedPorts := make(map[int]bool)
+	for p := range registeredPorts(managerMeta, ranges) {
+		usedPorts[p] = true
+	}
+	for _, u := range moves {
+		var r *templatePortRange
+		for i := range ranges {
+			if ranges[i].Env == u.Key {
+				r = &ranges[i]
+				break
+			}
+		}
+		if r == nil {
+			return assigned, fmt.Errorf("%s: no template declares a range for %s", u.Instance, u.Key)
+		}
+		newPort, err := findAvailablePort(r.Low, r.High, usedPorts)
+		if err != nil {
+			return assigned, fmt.Errorf("%s: %w", u, err)
+		}
+		usedPorts[newPort] = true
+		dir := managerMeta[u.Instance].Directory
+		envPath := filepath.Join(dir, ".env")
+		data, err := os.ReadFile(envPath)
+		if err != nil {
+			return assigned, err
+		}
+		content, _ := setEnvValue(string(data), u.Key, strconv.Itoa(newPort))
+		if err := os.WriteFile(envPath, []byte(content), 0644); err != nil {
+			return assigned, err
+		}
+		if u.Key != "WORDPRESS_PORT" {
+			assigned = append(assigned, fmt.Sprintf("%s to %d", u, newPort))
+			continue
+		}
+		if err := updateSiteURLPort(dir, oldPort, newPort); err != nil {
+			assigned = append(assigned, fmt.Sprintf("%s to %d (point home and siteurl at port %d with './manage wpcli option update' once it runs: %v)", u, newPort, newPort, err))
+		} else {
+			assigned = append(assigned, fmt.Sprintf("%s to %d", u, newPort))
+		}
+		err = updateManagerMeta(func(current ManagerMeta) bool {
+			meta, ok := current[u.Instance]
+			if ok {
+				meta.WordPressPort = newPort
+				current[u.Instance] = meta
+			}
+			return ok
+		})
+		if err != nil {
+			return assigned, err
+		}
+		if local, errLocal := readInstanceMeta(dir); errLocal == nil {
+			local.WordPressPort = newPort
+			_ = writeInstanceMeta(dir, local)
+		}
+	}
+	return assigned, nil
+}
+
+// updateSiteURLPort rewrites the home and siteurl options of a running instance that use
+// oldPort to newPort. URLs on another port, such as a proxied domain, are left alone.
+func updateSiteURLPort(instanceDir string, oldPort, newPort int) error {
+	for _, option := range []string{"home", "siteurl"} {
+		get := exec.Command("docker", "compose", "exec", "-T", "--user", "www-data", "wordpress", "wp", "option", "get", option)
+		get.Dir = instanceDir
+		out, err := get.Output()
+		if err != nil {
+			return fmt.Errorf("wp option get %s: %w", option, err)
+		}
+		u, err := url.Parse(strings.TrimSpace(string(out)))
+		if err != nil {
+			return fmt.Errorf("%s: %w", option, err)
+		}
+		if u.Port() != strconv.Itoa(oldPort) {
+			continue
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(newPort))
+		update := exec.Command("docker", "compose", "exec", "-T", "--user", "www-data", "wordpress", "wp", "option", "update", option, u.String())
+		update.Dir = instanceDir
+		if out, err := update.CombinedOutput(); err != nil {
+			return fmt.Errorf("wp option update %s: %w: %s", option, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// wpodProjectRe matches the compose project names of wpod instance directories, such as
+// www-blog-wordpress. Compose derives them from the directory name wpod creates.
+var wpodProjectRe = regexp.MustCompile(`^www-[a-z0-9_-]+-wordpress$`)
+
+// orphanProject is a compose project that looks like a wpod instance but matches no
+// registry entry.
+type orphanProject struct {
+	Project    string
+	WorkingDir string
+	DirGone    bool // the working directory no longer exists
+	Containers []string
+	Volumes    []string
+}
+
+// findOrphans lists compose projects left behind by wpod: containers whose working
+// directory holds an instance meta file, containers of a wpod-named project whose working
+// directory is gone, and the volumes of those projects or of wpod-named projects. Other
+// compose projects are never reported, so the hints can't point at someone else's data.
+func findOrphans(managerMeta ManagerMeta) ([]orphanProject, error) {
+	knownProjects := make(map[string]bool)
+	knownDirs := make(map[string]bool)
+	for _, meta := range managerMeta {
+		knownProjects[instanceProjectName(meta.Directory)] = true
+		knownDirs[canonicalDir(meta.Directory)] = true
+	}
+
+	orphans := make(map[string]*orphanProject)
+	otherProjects := make(map[string]bool)
+	out, err := dockerStdout("ps", "-a", "--filter", "label=com.docker.compose.project", "--format",
+		`{{.Names}}	{{.Label "com.docker.compose.project"}}	{{.Label "com.docker.compose.project.working_dir"}}`)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || fields[2] == "" || knownProjects[fields[1]] || knownDirs[canonicalDir(fields[2])] {
+			continue
+		}
+		name, project, dir := fields[0], fields[1], fields[2]
+		_, errDir := os.Stat(dir)
+		dirGone := errors.Is(errDir, os.ErrNotExist)
+		_, errMeta := os.Stat(filepath.Join(dir, metaFileName))
+		if errMeta != nil && !(dirGone && wpodProjectRe.MatchString(project)) {
+			otherProjects[project] = true // some other compose project
+			continue
+		}
+		o := orphans[project]
+		if o == nil {
+			o = &orphanProject{Project: project, WorkingDir: dir, DirGone: dirGone}
+			orphans[project] = o
+		}
+		o.Containers = append(o.Containers, name)
+	}
+
+	out, err = dockerStdout("volume", "ls", "--filter", "label=com.docker.compose.project", "--format",
+		`{{.Name}}	{{.Label "com.docker.compose.project"}}`)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 2 || knownProjects[fields[1]] {
+			continue
+		}
+		project := fields[1]
+		o := orphans[project]
+		if o == nil {
+			if otherProjects[project] || !wpodProjectRe.MatchString(project) {
+				continue
+			}
+			o = &orphanProject{Project: project}
+			orphans[project] = o
+		}
+		o.Volumes = append(o.Volumes, fields[0])
+	}
+
+	list := make([]orphanProject, 0, len(orphans))
+	for _, o := range orphans {
+		sort.Strings(o.Containers)
+		sort.Strings(o.Volumes)
+		list = append(list, *o)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Project < list[j].Project })
+	return list, nil
+}
+
+// doctorOrphans lists leftovers of deleted or unregistered instances. They use disk space
+// and ports but are not critical, and wpod never removes them itself.
+func doctorOrphans(d *doctorRun, managerMeta ManagerMeta) {
+	orphans, err := findOrphans(managerMeta)
+	if err != nil {
+		d.warn("orphans.project", "", "Could Not List Containers and Volumes", err.Error())
+		return
+	}
+	if len(orphans) == 0 {
+		d.ok("orphans.project", "", "No Orphaned Containers or Volumes")
+		return
+	}
+	for _, o := range orphans {
+		var details []string
+		if len(o.Containers) > 0 {
+			details = append(details, "Containers: "+strings.Join(o.Containers, ", "))
+		}
+		if len(o.Volumes) > 0 {
+			details = append(details, "Volumes: "+strings.Join(o.Volumes, ", "))
+		}
+		f := d.warn("orphans.project", o.Project, "Orphaned Compose Project: "+o.Project, details...)
+		switch {
+		case o.WorkingDir != "" && !o.DirGone:
+			f.fix(fmt.Sprintf("The instance at %s is not registered; run 'wpod register' to manage it again.", o.WorkingDir), nil)
+		case len(o.Containers) > 0:
+			f.fix(fmt.Sprintf("Its directory %s is gone; remove the leftovers with 'docker compose -p %s down -v'.", o.WorkingDir, o.Project), nil)
+		default:
+			f.fix("Remove them with 'docker volume rm "+strings.Join(o.Volumes, " ")+"' if the data is no longer needed.", nil)
+		}
+	}
+}
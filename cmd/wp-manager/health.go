@@ -0,0 +1,272 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// 'wpod health' exit codes, documented in docs/cli.md.
+const (
+	healthExitOK     = 0 // every check passed or was skipped
+	healthExitFailed = 1 // at least one check failed
+	healthExitError  = 2 // the checks could not run: bad arguments, unknown instance
+)
+
+// Check results.
+const (
+	healthPass = "pass"
+	healthFail = "fail"
+	healthSkip = "skip" // the instance has no such service, e.g. no ADMINER_PORT in .env
+)
+
+// healthCheck is the result of one probe. IDs are stable so scripts can pick checks out.
+type healthCheck struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// healthReport is the 'wpod health --json' document.
+type healthReport struct {
+	Name      string        `json:"name"`
+	Directory string        `json:"directory"`
+	Healthy   bool          `json:"healthy"`
+	Checks    []healthCheck `json:"checks"`
+}
+
+// healthProbe is one check to run against an instance.
+type healthProbe struct {
+	id, name string
+	run      func(ctx context.Context) (status, detail string)
+}
+
+// healthCommand implements 'wpod health <name> [--json] [--timeout d]'.
+func healthCommand(args []string) {
+	healthFlags := flag.NewFlagSet("health", flag.ExitOnError)
+	jsonOut := healthFlags.Bool("json", false, "Print the results as JSON")
+	timeout := healthFlags.Duration("timeout", 10*time.Second, "Time limit for each check")
+	_ = healthFlags.Parse(reorderFlags(args, "timeout"))
+
+	fail := func(title string, details ...string) {
+		if *jsonOut {
+			fmt.Fprintln(os.Stderr, "wpod health: "+title+": "+strings.Join(details, " "))
+		} else {
+			printError(title, details...)
+		}
+		os.Exit(healthExitError)
+	}
+
+	if healthFlags.NArg() != 1 {
+		fail("Usage", "wpod health <name> [--json] [--timeout 10s]")
+	}
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		fail("Failed to Read Manager Metadata", err.Error())
+	}
+	key, meta, ok := lookupInstance(managerMeta, healthFlags.Arg(0))
+	if !ok {
+		fail("Instance Not Found", fmt.Sprintf("No registered instance named '%s'.", healthFlags.Arg(0)))
+	}
+	if _, err := os.Stat(meta.Directory); err != nil {
+		fail("Instance Directory Missing", meta.Directory)
+	}
+
+	report := runHealthChecks(key, meta, *timeout)
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(report)
+	} else {
+		printSectionHeader(fmt.Sprintf("Health of %s", key))
+		renderHealthReport(os.Stdout, report)
+	}
+	if !report.Healthy {
+		os.Exit(healthExitFailed)
+	}
+}
+
+// runHealthChecks runs every probe in parallel, each bounded by timeout.
+func runHealthChecks(name string, meta InstanceMeta, timeout time.Duration) healthReport {
+	entry := newInstanceListEntry(name, meta)
+	probes := healthProbes(entry)
+
+	checks := make([]healthCheck, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			start := time.Now()
+			status, detail := p.run(ctx)
+			if ctx.Err() == context.DeadlineExceeded && status == healthFail {
+				detail = fmt.Sprintf("timed out after %s", timeout)
+			}
+			checks[i] = healthCheck{ID: p.id, Name: p.name, Status: status, Detail: detail, DurationMS: time.Since(start).Milliseconds()}
+		}()
+	}
+	wg.Wait()
+
+	report := healthReport{Name: name, Directory: meta.Directory, Healthy: true, Checks: checks}
+	for _, c := range checks {
+		if c.Status == healthFail {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+func healthProbes(entry instanceListEntry) []healthProbe {
+	site := ""
+	if entry.Port > 0 {
+		site = fmt.Sprintf("http://localhost:%d", entry.Port)
+	}
+	return []healthProbe{
+		{"home", "WordPress home page", func(ctx context.Context) (string, string) {
+			return probeSite(ctx, site, "/", "")
+		}},
+		{"login", "Login page (/wp-login.php)", func(ctx context.Context) (string, string) {
+			// The login form's username field; an uninstalled site redirects to install.php instead.
+			return probeSite(ctx, site, "/wp-login.php", `name="log"`)
+		}},
+		{"db", "Database query (db service)", func(ctx context.Context) (string, string) {
+			return probeCompose(ctx, entry.Directory, "db", "sh", "-c",
+				`client=mariadb; command -v mariadb >/dev/null 2>&1 || client=mysql; `+
+					`$client -h localhost -u"$MYSQL_USER" -p"$MYSQL_PASSWORD" -e "SELECT 1" "$MYSQL_DATABASE"`)
+		}},
+		{"wp_installed", "WordPress installed (wp core is-installed)", func(ctx context.Context) (string, string) {
+			status, detail := probeCompose(ctx, entry.Directory, "--user", "www-data", "wordpress", "wp", "core", "is-installed")
+			if status == healthFail && detail == "exit status 1" {
+				detail = "not installed; run './manage install'"
+			}
+			return status, detail
+		}},
+		{"mailpit", "Mailpit web UI", func(ctx context.Context) (string, string) {
+			return probeHTTP(ctx, localURL(entry.Ports["mailpit_web"]), "/", "")
+		}},
+		{"adminer", "Adminer web UI", func(ctx context.Context) (string, string) {
+			return probeHTTP(ctx, localURL(entry.Ports["adminer"]), "/", "")
+		}},
+	}
+}
+
+func localURL(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return fmt.Sprintf("http://localhost:%d", port)
+}
+
+// probeSite is probeHTTP for the WordPress site itself, which every instance has: a
+// missing WORDPRESS_PORT is a broken .env, not an absent optional service.
+func probeSite(ctx context.Context, base, path, want string) (string, string) {
+	if base == "" {
+		return healthFail, "no WORDPRESS_PORT in .env or the registry"
+	}
+	return probeHTTP(ctx, base, path, want)
+}
+
+// probeHTTP fetches base+path, following redirects. It passes on a status below 400 and,
+// when want is set, a body containing want. An empty base means the service has no port.
+func probeHTTP(ctx context.Context, base, path, want string) (string, string) {
+	if base == "" {
+		return healthSkip, "no port configured in .env"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+	if err != nil {
+		return healthFail, err.Error()
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return healthFail, err.Error()
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	detail := fmt.Sprintf("%s -> %d", base+path, resp.StatusCode)
+	if final := resp.Request.URL.String(); final != base+path {
+		detail += " (" + final + ")"
+	}
+	if resp.StatusCode >= 400 {
+		return healthFail, detail
+	}
+	if want != "" && !bytes.Contains(body, []byte(want)) {
+		return healthFail, detail + ", unexpected page content"
+	}
+	return healthPass, detail
+}
+
+// probeCompose runs 'docker compose exec -T <args>' in the instance directory and passes
+// when the command exits 0.
+func probeCompose(ctx context.Context, instanceDir string, args ...string) (string, string) {
+	cmd := exec.CommandContext(ctx, "docker", append([]string{"compose", "exec", "-T"}, args...)...)
+	cmd.Dir = instanceDir
+	cmd.WaitDelay = time.Second // see instanceStatusContext
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		detail := lastLine(out)
+		if detail == "" {
+			detail = err.Error()
+		}
+		return healthFail, detail
+	}
+	return healthPass, "ok"
+}
+
+func renderHealthReport(w io.Writer, report healthReport) {
+	nameWidth := 46
+	for _, c := range report.Checks {
+		mark := statusRunningStyle.Render("✔ pass")
+		switch c.Status {
+		case healthFail:
+			mark = statusErrorStyle.Render("✖ fail")
+		case healthSkip:
+			mark = subtleStyle.Render("- skip")
+		}
+		fmt.Fprintln(w, lipgloss.JoinHorizontal(lipgloss.Top,
+			tableCellStyle.Width(10).Render(mark),
+			tableCellStyle.Width(nameWidth).Render(c.Name),
+			subtleStyle.Render(fmt.Sprintf("%s (%dms)", c.Detail, c.DurationMS)),
+		))
+	}
+	fmt.Fprintln(w)
+	if report.Healthy {
+		printSuccess("Healthy", "Every check passed.")
+	} else {
+		printError("Unhealthy", "At least one check failed.")
+	}
+}
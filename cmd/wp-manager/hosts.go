@@ -0,0 +1,500 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Fence lines delimiting the part of the hosts file owned by wpod.
+// Everything between them is regenerated on every 'wpod hosts sync'.
+const (
+	hostsBlockBegin = "# >>> wpod managed block >>>"
+	hostsBlockEnd   = "# <<< wpod managed block <<<"
+)
+
+// --- Configuration helpers ---
+
+func configuredDevDomainSuffix(config GlobalManagerConfig) string {
+	if config.DevDomainSuffix != "" {
+		return config.DevDomainSuffix
+	}
+	return defaultDevDomain
+}
+
+func configuredHostsFile(config GlobalManagerConfig) string {
+	if config.HostsFile != "" {
+		return config.HostsFile
+	}
+	if runtime.GOOS == "windows" {
+		systemRoot := os.Getenv("SystemRoot")
+		if systemRoot == "" {
+			systemRoot = `C:\Windows`
+		}
+		return filepath.Join(systemRoot, "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
+
+func configuredBindAddress(config GlobalManagerConfig) string {
+	if config.BindAddress != "" {
+		return config.BindAddress
+	}
+	return defaultBindAddress
+}
+
+// --- Instance host names ---
+
+// instanceNameBase turns a directory or registry name like "www-myblog-wordpress"
+// back into the short name ("myblog") the user typed at creation time.
+func instanceNameBase(name string) string {
+	base := filepath.Base(name)
+	base = strings.TrimPrefix(base, "www-")
+	base = strings.TrimSuffix(base, "-wordpress")
+	return base
+}
+
+// legacyDevDomain is the suffix instances were created with before the dev host name was
+// recorded in the registry. Unlike the configured suffix, it can't change under them.
+const legacyDevDomain = ".example.local"
+
+// instanceDevHostName returns the dev host name recorded for an instance. Older
+// registrations have none; their config/Caddyfile holds the name they were created with,
+// and failing that the name is derived with the legacy suffix.
+func instanceDevHostName(instanceName string, meta InstanceMeta) string {
+	if meta.DevHostName != "" {
+		return strings.ToLower(meta.DevHostName)
+	}
+	source := instanceName
+	if meta.Directory != "" {
+		if host := caddyfileHostName(filepath.Join(meta.Directory, "config", "Caddyfile")); host != "" {
+			return strings.ToLower(host)
+		}
+		source = meta.Directory
+	}
+	return strings.ToLower(instanceNameBase(source) + legacyDevDomain)
+}
+
+// caddyfileHostName returns the host of the first site block in a Caddyfile, without
+// scheme or port, or "" when the file is missing or has no usable site address.
+func caddyfileHostName(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	depth := 0
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if depth == 0 && strings.HasSuffix(line, "{") {
+			if fields := strings.FieldsFunc(strings.TrimSuffix(line, "{"), func(r rune) bool { return r == ',' || r == ' ' }); len(fields) > 0 {
+				host := fields[0]
+				if i := strings.Index(host, "://"); i >= 0 {
+					host = host[i+3:]
+				}
+				if h, _, err := net.SplitHostPort(host); err == nil {
+					host = h
+				}
+				if !isValidHostName(host) {
+					return ""
+				}
+				return host
+			}
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+	}
+	return ""
+}
+
+// backfillDevHostNames records the dev host name of registry entries that predate it, so
+// the names published for them stay the ones their Caddyfiles answer on.
+func backfillDevHostNames() error {
+	return updateManagerMeta(func(managerMeta ManagerMeta) bool {
+		changed := false
+		for name, meta := range managerMeta {
+			if meta.DevHostName == "" {
+				meta.DevHostName = instanceDevHostName(name, meta)
+				managerMeta[name] = meta
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// instanceHostNames lists every host name an instance answers on.
+func instanceHostNames(devHostName string) []string {
+	return []string{devHostName, "adminer." + devHostName, "mail." + devHostName}
+}
+
+func isValidHostName(name string) bool {
+	if name == "" || len(name) > 253 || strings.HasPrefix(name, ".") || strings.Contains(name, "..") {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// --- Hosts block generation ---
+
+// buildHostsBlock renders the fenced block for all registered instances, sorted by name,
+// and returns how many instances it maps. Instances whose host name is not valid are
+// skipped and reported via the last return value.
+func buildHostsBlock(managerMeta ManagerMeta, bindAddress string) ([]string, int, []string) {
+	names := make([]string, 0, len(managerMeta))
+	for name := range managerMeta {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var skipped []string
+	mapped := 0
+	block := []string{hostsBlockBegin, "# Generated by 'wpod hosts sync'. Changes inside this block are overwritten."}
+	for _, name := range names {
+		devHost := instanceDevHostName(name, managerMeta[name])
+		if !isValidHostName(devHost) {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", name, devHost))
+			continue
+		}
+		block = append(block, "# "+name)
+		block = append(block, bindAddress+" "+strings.Join(instanceHostNames(devHost), " "))
+		mapped++
+	}
+	block = append(block, hostsBlockEnd)
+	return block, mapped, skipped
+}
+
+// splitHostsBlock separates the hosts file into the lines before, inside and after the
+// wpod block. found is false when the file has no block yet.
+func splitHostsBlock(lines []string) (before, block, after []string, found bool, err error) {
+	begin, end := -1, -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == hostsBlockBegin {
+			if begin != -1 {
+				return nil, nil, nil, false, errors.New("hosts file contains more than one wpod block")
+			}
+			begin = i
+		} else if trimmed == hostsBlockEnd {
+			if begin == -1 {
+				return nil, nil, nil, false, errors.New("hosts file has a wpod end marker without a begin marker")
+			}
+			end = i
+		}
+	}
+	if begin == -1 {
+		return lines, nil, nil, false, nil
+	}
+	if end == -1 {
+		return nil, nil, nil, false, errors.New("hosts file has an unterminated wpod block")
+	}
+	return lines[:begin], lines[begin : end+1], lines[end+1:], true, nil
+}
+
+// replaceHostsBlock returns the new hosts file content with the wpod block replaced,
+// appended, or (when there are no instances) removed. Line endings are preserved.
+func replaceHostsBlock(content string, newBlock []string, hasEntries bool) (string, []string, error) {
+	newline := "\n"
+	if strings.Contains(content, "\r\n") {
+		newline = "\r\n"
+	}
+	trimmed := strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var lines []string
+	if trimmed != "" {
+		lines = strings.Split(trimmed, "\n")
+	}
+
+	before, oldBlock, after, found, err := splitHostsBlock(lines)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var result []string
+	result = append(result, before...)
+	if hasEntries {
+		if !found && len(result) > 0 && strings.TrimSpace(result[len(result)-1]) != "" {
+			result = append(result, "")
+		}
+		result = append(result, newBlock...)
+	} else if found {
+		// Drop the blank separator we added when the block was first written.
+		for len(result) > 0 && strings.TrimSpace(result[len(result)-1]) == "" {
+			result = result[:len(result)-1]
+		}
+	}
+	result = append(result, after...)
+
+	return strings.Join(result, newline) + newline, oldBlock, nil
+}
+
+// diffLines produces a minimal line diff (LCS based), prefixing lines with ' ', '-' or '+'.
+func diffLines(oldLines, newLines []string) []string {
+	n, m := len(oldLines), len(newLines)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			out = append(out, "  "+oldLines[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+oldLines[i])
+			i++
+		default:
+			out = append(out, "+ "+newLines[j])
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		out = append(out, "- "+oldLines[i])
+	}
+	for ; j < m; j++ {
+		out = append(out, "+ "+newLines[j])
+	}
+	return out
+}
+
+func printDiff(diff []string) {
+	removed := lipgloss.NewStyle().Foreground(colorError)
+	added := lipgloss.NewStyle().Foreground(colorSuccess)
+	for _, line := range diff {
+		switch {
+		case strings.HasPrefix(line, "- "):
+			fmt.Println(removed.Render(line))
+		case strings.HasPrefix(line, "+ "):
+			fmt.Println(added.Render(line))
+		default:
+			fmt.Println(subtleStyle.Render(line))
+		}
+	}
+	fmt.Println()
+}
+
+// --- Commands ---
+
+// handleHostsCommand handles subcommands for 'wpod hosts'.
+func handleHostsCommand(args []string) {
+	if len(args) < 1 {
+		printError("Hosts Subcommand Required", "Usage: wpod hosts <sync|show> [--dry-run] [--hosts-file <path>] [--bind <address>]")
+		return
+	}
+	subcommand := strings.ToLower(args[0])
+	switch subcommand {
+	case "sync":
+		hostsSync(args[1:])
+	case "show":
+		hostsShow(args[1:])
+	default:
+		printError("Unknown Hosts Subcommand", fmt.Sprintf("Subcommand '%s' not recognized.", subcommand), "Valid subcommands are: sync, show")
+	}
+}
+
+// hostsShow prints the wpod block currently present in the hosts file.
+func hostsShow(args []string) {
+	config, _ := readGlobalManagerConfig()
+	showFlags := flag.NewFlagSet("hosts show", flag.ExitOnError)
+	hostsFile := showFlags.String("hosts-file", configuredHostsFile(config), "Path to the hosts file")
+	_ = showFlags.Parse(args)
+
+	printSectionHeader("Managed Hosts Entries")
+	data, err := os.ReadFile(*hostsFile)
+	if err != nil {
+		printError("Failed to Read Hosts File", err.Error())
+		return
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	_, block, _, found, err := splitHostsBlock(lines)
+	if err != nil {
+		printError("Malformed Hosts File", err.Error())
+		return
+	}
+	if !found {
+		printInfo("No wpod block found.", fmt.Sprintf("Run '%s' to create one in %s.", commandStyle.Render("wpod hosts sync"), *hostsFile))
+		return
+	}
+	fmt.Println(strings.Join(block, "\n"))
+}
+
+// hostsSync rewrites the wpod block in the hosts file from the instance registry.
+func hostsSync(args []string) {
+	config, errConfig := readGlobalManagerConfig()
+	if errConfig != nil {
+		printWarning("Could not read global config; using defaults.", errConfig.Error())
+	}
+
+	syncFlags := flag.NewFlagSet("hosts sync", flag.ExitOnError)
+	dryRun := syncFlags.Bool("dry-run", false, "Show the changes without writing the hosts file")
+	hostsFile := syncFlags.String("hosts-file", configuredHostsFile(config), "Path to the hosts file")
+	bindAddress := syncFlags.String("bind", configuredBindAddress(config), "Address the dev host names resolve to")
+	assumeYes := syncFlags.Bool("yes", false, "Do not prompt before using elevated permissions")
+	_ = syncFlags.Parse(args)
+
+	printSectionHeader("Sync Hosts File")
+
+	if net.ParseIP(*bindAddress) == nil {
+		printError("Invalid Bind Address", fmt.Sprintf("'%s' is not a valid IP address.", *bindAddress))
+		return
+	}
+
+	if err := backfillDevHostNames(); err != nil {
+		printWarning("Could not record dev host names in the registry.", err.Error())
+	}
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		printError("Failed to Read Manager Metadata", err.Error())
+		return
+	}
+
+	current, err := os.ReadFile(*hostsFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		printError("Failed to Read Hosts File", err.Error())
+		return
+	}
+
+	newBlock, mapped, skipped := buildHostsBlock(managerMeta, *bindAddress)
+	for _, s := range skipped {
+		printWarning("Skipping instance with invalid host name:", s)
+	}
+	hasEntries := mapped > 0
+
+	updated, oldBlock, err := replaceHostsBlock(string(current), newBlock, hasEntries)
+	if err != nil {
+		printError("Malformed Hosts File", err.Error(), fmt.Sprintf("Fix the wpod block in %s by hand and try again.", *hostsFile))
+		return
+	}
+
+	if updated == string(current) {
+		printSuccess("Hosts File Up-to-Date", fmt.Sprintf("No changes needed in %s.", *hostsFile))
+		return
+	}
+
+	if !hasEntries {
+		newBlock = nil
+	}
+	printInfo("Changes to " + commandStyle.Render(*hostsFile) + ":")
+	printDiff(diffLines(oldBlock, newBlock))
+
+	if *dryRun {
+		printInfo("Dry run: hosts file not modified.")
+		return
+	}
+
+	if err := writeHostsFile(*hostsFile, []byte(updated), *assumeYes); err != nil {
+		printError("Failed to Write Hosts File", err.Error())
+		return
+	}
+	printSuccess("Hosts File Updated", fmt.Sprintf("%d instance(s) mapped to %s.", mapped, *bindAddress))
+}
+
+// writeHostsFile writes the hosts file directly when possible and only falls back
+// to an elevated copy (sudo / UAC) when the direct write is denied.
+func writeHostsFile(path string, data []byte, assumeYes bool) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	err := os.WriteFile(path, data, perm)
+	if err == nil || !os.IsPermission(err) {
+		return err
+	}
+
+	printWarning("Permission Denied", fmt.Sprintf("Cannot write %s as the current user.", path))
+	if !assumeYes {
+		var confirmElevate bool
+		confirmPrompt := huh.NewConfirm().
+			Title("Elevated Permission Required").
+			Description(fmt.Sprintf("Write '%s' with elevated permissions?\nOnly the final copy runs elevated.", path)).
+			Affirmative("Yes, use elevated permissions").
+			Negative("No, cancel")
+		_ = confirmPrompt.Value(&confirmElevate).WithTheme(theme).Run()
+		if !confirmElevate {
+			return errors.New("write cancelled")
+		}
+	}
+
+	tempFile, err := os.CreateTemp("", "wpod-hosts-*")
+	if err != nil {
+		return fmt.Errorf("could not create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("could not write temp file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("could not close temp file: %w", err)
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		copyCmd := fmt.Sprintf("Copy-Item -Force -LiteralPath '%s' -Destination '%s'", tempPath, path)
+		cmd = exec.Command("powershell", "-Command", fmt.Sprintf("Start-Process powershell -Verb RunAs -Wait -ArgumentList \"-Command\",\"%s\"", copyCmd))
+	} else {
+		// cp keeps the owner and mode of the existing hosts file.
+		cmd = exec.Command("sudo", "cp", tempPath, path)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("elevated write failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testHostsBlock = []string{hostsBlockBegin, "# blog", "127.0.0.1 blog.test", hostsBlockEnd}
+
+// hostsLines joins lines with newline and ends the text with it, like a hosts file.
+func hostsLines(newline string, lines ...string) string {
+	return strings.Join(lines, newline) + newline
+}
+
+func TestReplaceHostsBlock(t *testing.T) {
+	oldBlock := []string{hostsBlockBegin, "# old", "127.0.0.1 old.test", hostsBlockEnd}
+	tests := []struct {
+		name       string
+		content    string
+		hasEntries bool
+		want       string
+		wantOld    []string
+	}{
+		{
+			name:       "empty file",
+			content:    "",
+			hasEntries: true,
+			want:       hostsLines("\n", testHostsBlock...),
+		},
+		{
+			name:       "no block",
+			content:    "127.0.0.1 localhost\n",
+			hasEntries: true,
+			want:       hostsLines("\n", append([]string{"127.0.0.1 localhost", ""}, testHostsBlock...)...),
+		},
+		{
+			name:       "existing block",
+			content:    hostsLines("\n", append(append([]string{"127.0.0.1 localhost", ""}, oldBlock...), "::1 localhost")...),
+			hasEntries: true,
+			want:       hostsLines("\n", append(append([]string{"127.0.0.1 localhost", ""}, testHostsBlock...), "::1 localhost")...),
+			wantOld:    oldBlock,
+		},
+		{
+			name:       "crlf",
+			content:    hostsLines("\r\n", append([]string{"127.0.0.1 localhost", ""}, oldBlock...)...),
+			hasEntries: true,
+			want:       hostsLines("\r\n", append([]string{"127.0.0.1 localhost", ""}, testHostsBlock...)...),
+			wantOld:    oldBlock,
+		},
+		{
+			name:       "block at eof without trailing newline",
+			content:    strings.Join(append([]string{"127.0.0.1 localhost", ""}, oldBlock...), "\n"),
+			hasEntries: true,
+			want:       hostsLines("\n", append([]string{"127.0.0.1 localhost", ""}, testHostsBlock...)...),
+			wantOld:    oldBlock,
+		},
+		{
+			name:       "no entries removes block and separator",
+			content:    hostsLines("\n", append([]string{"127.0.0.1 localhost", ""}, oldBlock...)...),
+			hasEntries: false,
+			want:       "127.0.0.1 localhost\n",
+			wantOld:    oldBlock,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, old, err := replaceHostsBlock(tt.content, testHostsBlock, tt.hasEntries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("content:\n%q\nwant:\n%q", got, tt.want)
+			}
+			if !slices.Equal(old, tt.wantOld) {
+				t.Errorf("old block = %q, want %q", old, tt.wantOld)
+			}
+		})
+	}
+}
+
+func TestReplaceHostsBlockIsIdempotent(t *testing.T) {
+	first, _, err := replaceHostsBlock("127.0.0.1 localhost\n", testHostsBlock, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := replaceHostsBlock(first, testHostsBlock, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Errorf("second sync changed the file:\n%q\nwant:\n%q", second, first)
+	}
+}
+
+func TestSplitHostsBlockErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"two blocks", []string{hostsBlockBegin, hostsBlockEnd, hostsBlockBegin, hostsBlockEnd}},
+		{"end without begin", []string{"127.0.0.1 localhost", hostsBlockEnd}},
+		{"unterminated", []string{hostsBlockBegin, "127.0.0.1 blog.test"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, _, err := splitHostsBlock(tt.lines); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new []string
+		want     []string
+	}{
+		{"added block", nil, []string{"a", "b"}, []string{"+ a", "+ b"}},
+		{"removed block", []string{"a", "b"}, nil, []string{"- a", "- b"}},
+		{"changed line", []string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{"  a", "- b", "+ x", "  c"}},
+		{"unchanged", []string{"a"}, []string{"a"}, []string{"  a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.old, tt.new); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstanceDevHostName(t *testing.T) {
+	withCaddyfile := func(content string) string {
+		dir := filepath.Join(t.TempDir(), "www-blog-wordpress")
+		if err := os.MkdirAll(filepath.Join(dir, "config"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "config", "Caddyfile"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	tests := []struct {
+		name string
+		meta InstanceMeta
+		want string
+	}{
+		{"recorded", InstanceMeta{DevHostName: "Blog.Test", Directory: withCaddyfile("blog.local {\n}\n")}, "blog.test"},
+		{"caddyfile", InstanceMeta{Directory: withCaddyfile("# Caddyfile for www-blog-wordpress\n\nblog.mysite.local {\n    reverse_proxy 0.0.0.0:80\n}\n")}, "blog.mysite.local"},
+		{"caddyfile with global options", InstanceMeta{Directory: withCaddyfile("{\n    email dev@example.com\n}\n\nhttps://blog.dev:8443, www.blog.dev {\n}\n")}, "blog.dev"},
+		{"legacy suffix", InstanceMeta{Directory: filepath.Join(t.TempDir(), "www-blog-wordpress")}, "blog" + legacyDevDomain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := instanceDevHostName("www-blog-wordpress", tt.meta); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
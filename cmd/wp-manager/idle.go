@@ -0,0 +1,405 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// idleNever is the per-instance idle_stop_minutes value that opts out of the global policy.
+const idleNever = -1
+
+// Idle check outcomes.
+const (
+	idleActionSkip    = "skip"    // no policy, or not running
+	idleActionKeep    = "keep"    // served requests recently or started recently
+	idleActionStop    = "stop"    // idle; would be stopped with --apply
+	idleActionStopped = "stopped" // idle and stopped
+	idleActionFailed  = "failed"  // the check or the stop failed
+)
+
+// idleDecision is the idle check result for one instance.
+type idleDecision struct {
+	Name          string `json:"name"`
+	Status        string `json:"status"`
+	PolicyMinutes int    `json:"policy_minutes"`         // 0: no policy applies
+	LastRequest   string `json:"last_request,omitempty"` // RFC 3339, within the policy window
+	Action        string `json:"action"`
+	Reason        string `json:"reason"`
+}
+
+// accessLogRequestRe finds the request of an Apache or nginx access log line.
+var accessLogRequestRe = regexp.MustCompile(`"(GET|POST|HEAD|PUT|PATCH|DELETE|OPTIONS) \S+ HTTP/[0-9.]+"`)
+
+// parseIdleMinutes reads a policy such as "30", "45m" or "2h" as minutes.
+func parseIdleMinutes(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		return n, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < time.Minute {
+		return 0, fmt.Errorf("'%s' is not a number of minutes or a duration of at least 1m (e.g. 30, 45m, 2h)", value)
+	}
+	return int(d / time.Minute), nil
+}
+
+// effectiveIdleMinutes combines the instance setting with the global one: a positive
+// instance value wins, idleNever opts out, zero inherits.
+func effectiveIdleMinutes(meta InstanceMeta, config GlobalManagerConfig) int {
+	switch {
+	case meta.IdleStopMinutes == idleNever:
+		return 0
+	case meta.IdleStopMinutes > 0:
+		return meta.IdleStopMinutes
+	default:
+		return max(config.IdleStopMinutes, 0)
+	}
+}
+
+// handleIdleCommand implements 'wpod idle [--apply] [--json]' and
+// 'wpod idle set <name> <minutes|off|default>'.
+func handleIdleCommand(args []string) {
+	if len(args) > 0 && args[0] == "set" {
+		idleSet(args[1:])
+		return
+	}
+	idleFlags := flag.NewFlagSet("idle", flag.ExitOnError)
+	apply := idleFlags.Bool("apply", false, "Stop the idle instances (default: only report)")
+	jsonOut := idleFlags.Bool("json", false, "Print the decisions as JSON")
+	_ = idleFlags.Parse(args)
+
+	decisions, err := runIdleCheck(*apply, "wpod idle")
+	if err != nil {
+		if *jsonOut {
+			fmt.Fprintln(os.Stderr, "wpod idle: "+err.Error())
+		} else {
+			printError("Idle Check Failed", err.Error())
+		}
+		os.Exit(1)
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(decisions)
+		return
+	}
+	printSectionHeader("Idle Instances")
+	for _, d := range decisions {
+		action := fmt.Sprintf("%-8s", d.Action) // pad before styling
+		switch d.Action {
+		case idleActionStop, idleActionStopped:
+			action = statusStoppedStyle.Render(action)
+		case idleActionFailed:
+			action = statusErrorStyle.Render(action)
+		case idleActionKeep:
+			action = statusRunningStyle.Render(action)
+		default:
+			action = subtleStyle.Render(action)
+		}
+		fmt.Printf("  %-30s %s %s\n", d.Name, action, subtleStyle.Render(d.Reason))
+	}
+	if !*apply {
+		for _, d := range decisions {
+			if d.Action == idleActionStop {
+				printInfo("Dry run.", "Run "+commandStyle.Render("wpod idle --apply")+" to stop the idle instances.")
+				break
+			}
+		}
+	}
+}
+
+// idleSet implements 'wpod idle set <name> <minutes|off|default>'.
+func idleSet(args []string) {
+	if len(args) != 2 {
+		printError("Usage: wpod idle set <name> <minutes|off|default>")
+		return
+	}
+	var minutes int
+	switch strings.ToLower(args[1]) {
+	case "off", "never":
+		minutes = idleNever
+	case "default", "inherit":
+		minutes = 0
+	default:
+		var err error
+		if minutes, err = parseIdleMinutes(args[1]); err != nil {
+			printError("Invalid Idle Policy", err.Error())
+			return
+		}
+		if minutes == 0 {
+			minutes = idleNever
+		}
+	}
+	var key string
+	err := updateManagerMeta(func(managerMeta ManagerMeta) bool {
+		k, meta, ok := lookupInstance(managerMeta, args[0])
+		if !ok {
+			return false
+		}
+		key = k
+		meta.IdleStopMinutes = minutes
+		managerMeta[k] = meta
+		return true
+	})
+	switch {
+	case err != nil:
+		printError("Failed to Update Manager Metadata", err.Error())
+	case key == "":
+		printError("Instance Not Found", fmt.Sprintf("No registered instance named '%s'.", args[0]))
+	case minutes == idleNever:
+		printSuccess(fmt.Sprintf("%s is never stopped for being idle.", key))
+	case minutes == 0:
+		printSuccess(fmt.Sprintf("%s follows the global idle policy (idle_stop_minutes).", key))
+	default:
+		printSuccess(fmt.Sprintf("%s is stopped after %d idle minute(s).", key, minutes))
+	}
+}
+
+// runIdleCheck decides, for every registered instance, whether it has been idle for
+// longer than its policy allows and, with apply, stops it and records that in the audit
+// trail under actor.
+func runIdleCheck(apply bool, actor string) ([]idleDecision, error) {
+	config, err := readGlobalManagerConfig()
+	if err != nil {
+		return nil, err
+	}
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	withPolicy := make(ManagerMeta)
+	for name, meta := range managerMeta {
+		if effectiveIdleMinutes(meta, config) > 0 {
+			withPolicy[name] = meta
+		}
+	}
+	statuses := checkStatuses(withPolicy, defaultStatusWorkers, defaultStatusTimeout)
+
+	names := make([]string, 0, len(managerMeta))
+	for name := range managerMeta {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	decisions := make([]idleDecision, 0, len(names))
+	for _, name := range names {
+		meta := managerMeta[name]
+		d := idleDecision{Name: name, Status: meta.Status, PolicyMinutes: effectiveIdleMinutes(meta, config)}
+		if d.PolicyMinutes == 0 {
+			d.Action, d.Reason = idleActionSkip, "no idle policy"
+			decisions = append(decisions, d)
+			continue
+		}
+		result := statuses[name]
+		d.Status = result.Status
+		if result.Err != nil {
+			d.Action, d.Reason = idleActionFailed, result.Err.Error()
+			decisions = append(decisions, d)
+			continue
+		}
+		if result.Status != statusRunning && result.Status != statusDegraded {
+			d.Action, d.Reason = idleActionSkip, "not running"
+			decisions = append(decisions, d)
+			continue
+		}
+		evaluateIdle(&d, meta)
+		if d.Action == idleActionStop && apply {
+			stopIdleInstance(&d, meta, actor)
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, nil
+}
+
+// evaluateIdle looks for HTTP requests in the access log of the container that serves
+// WordPress (nginx for nginx-fpm stacks) within the policy window. Requests from inside
+// the container, such as healthchecks, don't count.
+func evaluateIdle(d *idleDecision, meta InstanceMeta) {
+	window := time.Duration(d.PolicyMinutes) * time.Minute
+	service := "wordpress"
+	if instanceWebServer(meta) == webServerNginxFPM {
+		service = "nginx"
+	}
+
+	last, err := lastAccessTime(meta.Directory, service, window)
+	if err != nil {
+		d.Action, d.Reason = idleActionFailed, err.Error()
+		return
+	}
+	if !last.IsZero() {
+		d.LastRequest = last.Format(time.RFC3339)
+		d.Action, d.Reason = idleActionKeep, fmt.Sprintf("last request %s ago", time.Since(last).Round(time.Second))
+		return
+	}
+	started, err := serviceStartedAt(meta.Directory, service)
+	if err != nil {
+		d.Action, d.Reason = idleActionFailed, err.Error()
+		return
+	}
+	if time.Since(started) < window {
+		d.Action, d.Reason = idleActionKeep, fmt.Sprintf("started %s ago, no requests yet", time.Since(started).Round(time.Second))
+		return
+	}
+	d.Action, d.Reason = idleActionStop, fmt.Sprintf("no HTTP requests for %d minute(s)", d.PolicyMinutes)
+}
+
+// lastAccessTime returns the time of the newest external request in the service's log
+// within window, or the zero time if there was none.
+func lastAccessTime(instanceDir, service string, window time.Duration) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStatusTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "docker", "compose", "logs", "--no-color", "--no-log-prefix", "--timestamps",
+		"--since", strconv.Itoa(int(window.Seconds()))+"s", service)
+	cmd.Dir = instanceDir
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("docker compose logs %s: %v: %s", service, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var last time.Time
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		stamp, line, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || !accessLogRequestRe.MatchString(line) || accessLogFromLoopback(line) {
+			continue
+		}
+		if t, errTime := time.Parse(time.RFC3339Nano, stamp); errTime == nil && t.After(last) {
+			last = t
+		}
+	}
+	return last, nil
+}
+
+// accessLogFromLoopback reports whether an access log line records a request from the
+// container itself. Apache's vhost_combined format puts "host:port" before the client.
+func accessLogFromLoopback(line string) bool {
+	fields := strings.Fields(line)
+	for i := 0; i < len(fields) && i < 2; i++ {
+		if ip := net.ParseIP(fields[i]); ip != nil {
+			return ip.IsLoopback()
+		}
+	}
+	return false
+}
+
+// serviceStartedAt asks docker when the service's container last started.
+func serviceStartedAt(instanceDir, service string) (time.Time, error) {
+	idCmd := exec.Command("docker", "compose", "ps", "-q", service)
+	idCmd.Dir = instanceDir
+	ids, err := idCmd.Output()
+	if err != nil || len(bytes.TrimSpace(ids)) == 0 {
+		return time.Time{}, fmt.Errorf("no %s container found", service)
+	}
+	id := strings.Fields(string(ids))[0]
+	out, err := dockerStdout("inspect", "--format", "{{.State.StartedAt}}", id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(string(out)))
+}
+
+// stopIdleInstance stops the instance with its own './manage stop' (plain 'docker compose
+// down' without one), marks it stopped in the registry and writes the audit entry.
+func stopIdleInstance(d *idleDecision, meta InstanceMeta, actor string) {
+	binary := filepath.Join(meta.Directory, manageBinaryName())
+	cmd := exec.Command(binary, "stop")
+	if _, err := os.Stat(binary); err != nil {
+		cmd = exec.Command("docker", "compose", "down")
+	}
+	cmd.Dir = meta.Directory
+	if out, err := cmd.CombinedOutput(); err != nil {
+		d.Action, d.Reason = idleActionFailed, fmt.Sprintf("stop failed: %v: %s", err, lastLine(out))
+		return
+	}
+	d.Action = idleActionStopped
+	d.Status = statusStopped
+
+	checkedAt := time.Now().Format(time.RFC3339)
+	if err := updateManagerMeta(func(current ManagerMeta) bool {
+		m, ok := current[d.Name]
+		if !ok {
+			return false
+		}
+		m.Status, m.Services, m.StatusCheckedAt = statusStopped, nil, checkedAt
+		current[d.Name] = m
+		return true
+	}); err != nil {
+		printWarning("Failed to Update Manager Metadata", err.Error())
+	}
+	if err := appendAudit(auditEntry{Time: checkedAt, Actor: actor, Action: "idle-stop", Instance: d.Name, Detail: d.Reason}); err != nil {
+		printWarning("Failed to Write Audit Log", err.Error())
+	}
+}
+
+// daemonCommand implements 'wpod daemon [--interval d]': it applies the idle policy
+// every interval until interrupted.
+func daemonCommand(args []string) {
+	daemonFlags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	interval := daemonFlags.Duration("interval", time.Minute, "How often idle instances are checked")
+	_ = daemonFlags.Parse(args)
+	if *interval < 10*time.Second {
+		*interval = 10 * time.Second
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	printSectionHeader("wpod daemon")
+	printInfo(fmt.Sprintf("Stopping idle instances, checked every %s.", *interval), "Stops are recorded in the audit trail ("+commandStyle.Render("wpod audit")+"). Press Ctrl+C to stop.")
+	for {
+		decisions, err := runIdleCheck(true, "wpod daemon")
+		if err != nil {
+			printWarning("Idle Check Failed", err.Error())
+		}
+		for _, d := range decisions {
+			switch d.Action {
+			case idleActionStopped:
+				fmt.Printf("%s  %s stopped: %s\n", time.Now().Format("15:04:05"), boldStyle.Render(d.Name), d.Reason)
+			case idleActionFailed:
+				fmt.Printf("%s  %s %s\n", time.Now().Format("15:04:05"), boldStyle.Render(d.Name), statusErrorStyle.Render(d.Reason))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*interval):
+		}
+	}
+}
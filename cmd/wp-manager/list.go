@@ -0,0 +1,385 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// instanceListEntry is one instance in 'wpod list' output. The JSON, YAML and CSV field
+// names are a documented interface (docs/cli.md); add fields, never rename them.
+type instanceListEntry struct {
+	Name             string          `json:"name" yaml:"name"`
+	Directory        string          `json:"directory" yaml:"directory"`
+	Status           string          `json:"status" yaml:"status"`
+	Services         []ServiceStatus `json:"services" yaml:"services"`
+	Port             int             `json:"port" yaml:"port"`   // WordPress host port
+	Ports            map[string]int  `json:"ports" yaml:"ports"` // every *_PORT key in .env, e.g. "adminer", "mailpit_web"
+	Template         string          `json:"template" yaml:"template"`
+	WordPressVersion string          `json:"wordpress_version" yaml:"wordpress_version"`
+	PHPVersion       string          `json:"php_version" yaml:"php_version"`
+	WebServer        string          `json:"web_server" yaml:"web_server"`
+	DBEngine         string          `json:"db_engine" yaml:"db_engine"`
+	DBVersion        string          `json:"db_version" yaml:"db_version"`
+	DevHostName      string          `json:"dev_hostname" yaml:"dev_hostname"`
+	ProxyBackend     string          `json:"proxy_backend" yaml:"proxy_backend"`
+	Created          string          `json:"created" yaml:"created"`
+	Tags             []string        `json:"tags" yaml:"tags"`
+}
+
+var listCSVColumns = []string{
+	"name", "status", "port", "directory", "template", "wordpress_version", "php_version",
+	"web_server", "db_engine", "db_version", "dev_hostname", "proxy_backend", "created", "tags",
+}
+
+func (e instanceListEntry) csvRecord() []string {
+	return []string{
+		e.Name, e.Status, strconv.Itoa(e.Port), e.Directory, e.Template, e.WordPressVersion, e.PHPVersion,
+		e.WebServer, e.DBEngine, e.DBVersion, e.DevHostName, e.ProxyBackend, e.Created, strings.Join(e.Tags, ";"),
+	}
+}
+
+// envPortKeyRe matches port keys such as WORDPRESS_PORT, MAILPIT_PORT_WEB or
+// TRAEFIK_HTTP_PORT.
+var envPortKeyRe = regexp.MustCompile(`^([A-Z0-9_]+?)_PORT(_[A-Z0-9]+)?$`)
+
+// instancePorts reads the host ports an instance's .env assigns.
+func instancePorts(instanceDir string) map[string]int {
+	ports := make(map[string]int)
+	data, err := os.ReadFile(filepath.Join(instanceDir, ".env"))
+	if err != nil {
+		return ports
+	}
+	for key, value := range parseEnvContent(string(data)) {
+		match := envPortKeyRe.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		if port, err := strconv.Atoi(value); err == nil && port > 0 {
+			ports[strings.ToLower(match[1]+match[2])] = port
+		}
+	}
+	return ports
+}
+
+// newInstanceListEntry combines the registry entry, the local meta file and .env. The
+// status is the one saved in the registry; applyStatus replaces it with a live check.
+func newInstanceListEntry(name string, meta InstanceMeta) instanceListEntry {
+	// The local meta file carries the versions detected by './manage install'.
+	if localMeta, errLocal := readInstanceMeta(meta.Directory); errLocal == nil {
+		syncDetectedVersions(&meta, localMeta)
+	}
+	status, services := meta.Status, meta.Services
+	if services == nil {
+		services = []ServiceStatus{}
+	}
+	ports := instancePorts(meta.Directory)
+	port := meta.WordPressPort
+	if port == 0 {
+		port = ports["wordpress"]
+	}
+	tags := meta.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return instanceListEntry{
+		Name:             name,
+		Directory:        meta.Directory,
+		Status:           status,
+		Services:         services,
+		Port:             port,
+		Ports:            ports,
+		Template:         meta.Template,
+		WordPressVersion: meta.WordPressVersion,
+		PHPVersion:       meta.PHPVersion,
+		WebServer:        instanceWebServer(meta),
+		DBEngine:         meta.DBEngine,
+		DBVersion:        meta.DBVersion,
+		DevHostName:      meta.DevHostName,
+		ProxyBackend:     meta.ProxyBackend,
+		Created:          meta.CreationDate,
+		Tags:             tags,
+	}
+}
+
+// applyStatus sets the entry's status from a checkStatuses result.
+func (e *instanceListEntry) applyStatus(result statusCheck) {
+	e.Status, e.Services = result.Status, result.Services
+	if e.Services == nil {
+		e.Services = []ServiceStatus{}
+	}
+}
+
+// listOptions are the 'wpod list' flags.
+type listOptions struct {
+	format       string
+	statuses     []string
+	template     string
+	tag          string
+	sortBy       string
+	reverse      bool
+	cached       bool
+	showServices bool
+}
+
+func (o listOptions) matches(e instanceListEntry) bool {
+	if len(o.statuses) > 0 {
+		found := false
+		for _, s := range o.statuses {
+			found = found || strings.EqualFold(s, e.Status)
+		}
+		if !found {
+			return false
+		}
+	}
+	if o.template != "" && !strings.EqualFold(o.template, e.Template) {
+		return false
+	}
+	if o.tag != "" {
+		for _, t := range e.Tags {
+			if strings.EqualFold(o.tag, t) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func sortInstanceList(entries []instanceListEntry, by string, reverse bool) error {
+	var less func(a, b instanceListEntry) bool
+	switch by {
+	case "", "name":
+		less = func(a, b instanceListEntry) bool { return a.Name < b.Name }
+	case "status":
+		less = func(a, b instanceListEntry) bool { return a.Status < b.Status }
+	case "created":
+		less = func(a, b instanceListEntry) bool { return a.Created < b.Created }
+	case "port":
+		less = func(a, b instanceListEntry) bool { return a.Port < b.Port }
+	case "template":
+		less = func(a, b instanceListEntry) bool { return a.Template < b.Template }
+	default:
+		return fmt.Errorf("cannot sort by '%s'; use name, status, created, port or template", by)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if less(a, b) == less(b, a) { // equal keys: fall back to the name
+			return a.Name < b.Name
+		}
+		if reverse {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+	return nil
+}
+
+// listInstances implements 'wpod list'.
+func listInstances(args []string) {
+	listFlags := flag.NewFlagSet("list", flag.ExitOnError)
+	var opts listOptions
+	var statusFilter string
+	listFlags.StringVar(&opts.format, "format", "table", "Output format: table, json, yaml, csv or a Go template such as '{{.Name}} {{.Port}}'")
+	listFlags.StringVar(&statusFilter, "status", "", "Only instances with this status (comma-separated: running,degraded,stopped,missing,unknown)")
+	listFlags.StringVar(&opts.template, "template", "", "Only instances created from this template")
+	listFlags.StringVar(&opts.tag, "tag", "", "Only instances with this tag")
+	listFlags.StringVar(&opts.sortBy, "sort", "name", "Sort by name, status, created, port or template")
+	listFlags.BoolVar(&opts.reverse, "reverse", false, "Reverse the sort order")
+	listFlags.BoolVar(&opts.cached, "cached", false, "Use the status saved by 'wpod update' instead of asking Docker")
+	listFlags.BoolVar(&opts.showServices, "services", false, "Show the state of each service (table format)")
+	listFlags.BoolVar(&opts.showServices, "s", false, "Show the state of each service (shorthand)")
+	_ = listFlags.Parse(args)
+	for _, s := range strings.Split(statusFilter, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			opts.statuses = append(opts.statuses, s)
+		}
+	}
+	opts.format = strings.TrimSpace(opts.format)
+	table := opts.format == "" || strings.EqualFold(opts.format, "table")
+
+	// Machine-readable formats keep stdout clean: problems go to stderr.
+	fail := func(title string, details ...string) {
+		if table {
+			printError(title, details...)
+			return
+		}
+		fmt.Fprintln(os.Stderr, "wpod list: "+title+": "+strings.Join(details, " "))
+		os.Exit(1)
+	}
+
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		fail("Failed to Read Manager Metadata", err.Error())
+		return
+	}
+
+	var statuses map[string]statusCheck
+	var timedOut []string
+	if !opts.cached {
+		statuses = checkStatuses(managerMeta, defaultStatusWorkers, defaultStatusTimeout)
+	}
+	entries := make([]instanceListEntry, 0, len(managerMeta))
+	for name, meta := range managerMeta {
+		entry := newInstanceListEntry(name, meta)
+		if result, ok := statuses[name]; ok {
+			entry.applyStatus(result)
+			if result.TimedOut {
+				timedOut = append(timedOut, name)
+			}
+		}
+		if opts.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := sortInstanceList(entries, strings.ToLower(opts.sortBy), opts.reverse); err != nil {
+		fail("Invalid Sort", err.Error())
+		return
+	}
+
+	if table {
+		printSectionHeader("List WordPress Instances")
+		if len(managerMeta) == 0 {
+			printWarning("No Instances Found", "No instances registered with the manager.")
+			printInfo("Tip:", "Use '"+commandStyle.Render("create")+"' to add a new instance.")
+			return
+		}
+		if len(entries) == 0 {
+			printInfo("No instances match the filters.")
+			return
+		}
+		renderInstanceTable(os.Stdout, entries, opts.showServices)
+		if len(timedOut) > 0 {
+			sort.Strings(timedOut)
+			printWarning("Docker Did Not Answer in Time", fmt.Sprintf("Status unknown for: %s.", strings.Join(timedOut, ", ")))
+		}
+		return
+	}
+	if len(timedOut) > 0 {
+		sort.Strings(timedOut)
+		fmt.Fprintln(os.Stderr, "wpod list: status check timed out for: "+strings.Join(timedOut, ", "))
+	}
+	if err := writeInstanceList(os.Stdout, entries, opts.format); err != nil {
+		fail("Invalid Format", err.Error())
+	}
+}
+
+// writeInstanceList renders entries as json, yaml, csv or through a Go template.
+func writeInstanceList(w io.Writer, entries []instanceListEntry, format string) error {
+	switch strings.ToLower(format) {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(entries); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write(listCSVColumns)
+		for _, e := range entries {
+			_ = cw.Write(e.csvRecord())
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	if !strings.Contains(format, "{{") {
+		return fmt.Errorf("'%s' is not a format; use table, json, yaml, csv or a Go template such as '{{.Name}} {{.Port}}'", format)
+	}
+	tmpl, err := template.New("list").Funcs(template.FuncMap{"join": strings.Join}).Parse(format)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := tmpl.Execute(w, e); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func renderInstanceTable(w io.Writer, entries []instanceListEntry, showServices bool) {
+	// Define column widths (adjust as needed)
+	nameWidth := 30
+	portWidth := 8
+	dateWidth := 20
+	wpVerWidth := 15
+	dbVerWidth := 18
+	statusWidth := 18
+	dirWidth := 50
+
+	header := lipgloss.JoinHorizontal(lipgloss.Top,
+		tableHeaderStyle.Width(nameWidth).Render("Instance Name"),
+		tableHeaderStyle.Width(portWidth).Render("Port"),
+		tableHeaderStyle.Width(dateWidth).Render("Created"),
+		tableHeaderStyle.Width(wpVerWidth).Render("WP Ver"),
+		tableHeaderStyle.Width(dbVerWidth).Render("Database"),
+		tableHeaderStyle.Width(dirWidth).Render("Directory"),
+		tableHeaderStyle.Width(statusWidth).Render("Status"),
+	)
+	rows := []string{header}
+	for _, e := range entries {
+		wpVer := e.WordPressVersion
+		if wpVer == "" {
+			wpVer = "N/A"
+		}
+		dbVer := describeDB(e.DBEngine, e.DBVersion)
+		if dbVer == "" {
+			dbVer = "N/A"
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top,
+			tableCellStyle.Width(nameWidth).Render(e.Name),
+			tableCellStyle.Width(portWidth).Render(strconv.Itoa(e.Port)),
+			tableCellStyle.Width(dateWidth).Render(e.Created),
+			tableCellStyle.Width(wpVerWidth).Render(wpVer),
+			tableCellStyle.Width(dbVerWidth).Render(dbVer),
+			tableCellStyle.Width(dirWidth).Render(shortenPath(e.Directory, dirWidth-3)),
+			tableCellStyle.Width(statusWidth).Render(renderStatus(e.Status)),
+		))
+		if showServices {
+			for _, line := range renderServiceStatuses(e.Services, "    ") {
+				rows = append(rows, tableCellStyle.Render(line))
+			}
+		}
+	}
+
+	fmt.Fprintln(w, tableBorderStyle.Render(lipgloss.JoinVertical(lipgloss.Left, rows...)))
+	if !showServices {
+		fmt.Fprintln(w, subtleStyle.Render("Use 'list --services' for the state of each service, '--format json' for scripts."))
+	}
+}
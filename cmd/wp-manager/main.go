@@ -26,8 +26,6 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"io/fs"
 	"math/big"
 	"net"
 	"os"
@@ -43,14 +41,14 @@ import (
 
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/regiellis/wp-manager-cli/internal/compose"
 )
 
-//go:embed all:templates/docker-default-wordpress
-var defaultWordpressTemplate embed.FS
+//go:embed all:templates
+var embeddedTemplates embed.FS
 var caddyfileTemplateContent string
 
 const (
-	embeddedTemplateRoot = "templates/docker-default-wordpress"
 	globalConfigFileName = ".wpod-config.json"
 	metaFileName         = ".wordpress-meta.json"
 	envTemplateFileName  = "env-template"
@@ -58,6 +56,8 @@ const (
 	defaultWordPressPort = 8080
 	defaultMailpitSMTP   = 1025
 	defaultMailpitWeb    = 8025
+	defaultDevDomain     = ".test" // reserved for testing (RFC 2606); .local belongs to mDNS
+	defaultBindAddress   = "127.0.0.1"
 )
 
 var jsonInput string
@@ -96,28 +96,56 @@ func saveThemePreferenceToConfig(light bool) {
 
 // --- Metadata Types ---
 type InstanceMeta struct {
-	Directory        string `json:"directory"`
-	CreationDate     string `json:"creation_date"`
-	WordPressVersion string `json:"wordpress_version"`
-	DBVersion        string `json:"db_version"`
-	WordPressPort    int    `json:"wordpress_port"`
-	Status           string `json:"status"`
+	Directory        string            `json:"directory"`
+	CreationDate     string            `json:"creation_date"`
+	WordPressVersion string            `json:"wordpress_version"`
+	DBVersion        string            `json:"db_version"`
+	DBEngine         string            `json:"db_engine,omitempty"`
+	WordPressPort    int               `json:"wordpress_port"`
+	Status           string            `json:"status"`
+	Services         []ServiceStatus   `json:"services,omitempty"`          // per-service state from the last status check
+	StatusCheckedAt  string            `json:"status_checked_at,omitempty"` // RFC 3339
+	Tags             []string          `json:"tags,omitempty"`
+	DevHostName      string            `json:"dev_hostname,omitempty"`
+	ProxyBackend     string            `json:"proxy_backend,omitempty"`
+	Template         string            `json:"template,omitempty"`
+	TemplateVars     map[string]string `json:"template_vars,omitempty"`
+	PHPVersion       string            `json:"php_version,omitempty"`
+	WebServer        string            `json:"web_server,omitempty"`
+	IdleStopMinutes  int               `json:"idle_stop_minutes,omitempty"` // 0: global policy, -1: never
 }
 
 // In cmd/wp-manager/main.go
 type InstanceCaddyConfigData struct {
 	InstanceName     string // Full instance directory name, e.g., www-myblog-wordpress
-	DevHostName      string // e.g., myblog.example.local
+	DevHostName      string // e.g., myblog.test
 	WordPressPort    int    // Host port WordPress is mapped to (for external access reference)
 	InstancePort     int    // Port inside the container (default 80)
 	CaddyHTTPPort    int    // NEW: for Caddyfile.template
 	InstanceNameBase string // e.g., myblog (for subdomains like adminer.myblog...)
-	DevDomainSuffix  string // e.g., .example.local (for subdomains)
+	DevDomainSuffix  string // e.g., .test (for subdomains)
 }
 
 type GlobalManagerConfig struct {
 	SitesBaseDirectory string `json:"sites_base_directory,omitempty"`
 	Theme              string `json:"theme,omitempty"`
+	DevDomainSuffix    string `json:"dev_domain_suffix,omitempty"`
+	HostsFile          string `json:"hosts_file,omitempty"`
+	BindAddress        string `json:"bind_address,omitempty"`
+	DNSListen          string `json:"dns_listen,omitempty"`
+	TemplatesDirectory string `json:"templates_directory,omitempty"`
+	// Reverse proxy backend for new instances: "caddy" (default) or "traefik"
+	ProxyBackend         string `json:"proxy_backend,omitempty"`
+	TraefikHTTPPort      int    `json:"traefik_http_port,omitempty"`
+	TraefikHTTPSPort     int    `json:"traefik_https_port,omitempty"`
+	TraefikDashboardPort int    `json:"traefik_dashboard_port,omitempty"`
+	// Defaults for new instances: PHP version of the wordpress image ("" = image default)
+	// and web server, "apache" (default) or "nginx-fpm"
+	PHPVersion string `json:"php_version,omitempty"`
+	WebServer  string `json:"web_server,omitempty"`
+	// Stop running instances after this many minutes without HTTP requests (0 = never);
+	// instances can override it with 'wpod idle set'
+	IdleStopMinutes int `json:"idle_stop_minutes,omitempty"`
 }
 
 // Represents the structure of the central manager metadata file
@@ -351,20 +379,63 @@ func readManagerMeta() (ManagerMeta, error) {
 
 // writeManagerMeta writes the central instance metadata file.
 func writeManagerMeta(meta ManagerMeta) error {
+	metaPath, unlock, err := lockManagerMeta()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeManagerMetaFile(metaPath, meta)
+}
+
+// updateManagerMeta re-reads the registry while holding its lock, lets change edit it and
+// writes it back if change reports a modification. Long-running commands use it so edits
+// other wpod processes made in the meantime are not overwritten.
+func updateManagerMeta(change func(ManagerMeta) bool) error {
+	metaPath, unlock, err := lockManagerMeta()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	meta, err := readManagerMeta()
+	if err != nil {
+		return err
+	}
+	if !change(meta) {
+		return nil
+	}
+	return writeManagerMetaFile(metaPath, meta)
+}
+
+// managerMetaLockWait is how long a writer waits for another wpod process to release the
+// registry lock.
+const managerMetaLockWait = 5 * time.Second
+
+// lockManagerMeta takes the registry lock and returns the registry path and the unlock func.
+func lockManagerMeta() (string, func(), error) {
 	metaPath, err := getManagerMetaPath()
 	if err != nil {
-		return fmt.Errorf("could not determine manager meta path: %w", err)
+		return "", nil, fmt.Errorf("could not determine manager meta path: %w", err)
 	}
 
 	// Add file locking for safety against concurrent writes
 	lockPath := metaPath + ".lock"
 	fileLock := NewFileLock(lockPath) // Assuming you have a simple file lock helper or use flock lib
-	err = fileLock.Lock()
+	deadline := time.Now().Add(managerMetaLockWait)
+	for {
+		err = fileLock.Lock()
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
 	if err != nil {
-		return fmt.Errorf("could not acquire lock on metadata file %s: %w", lockPath, err)
+		return "", nil, fmt.Errorf("could not acquire lock on metadata file %s: %w", lockPath, err)
 	}
-	defer fileLock.Unlock() // Ensure unlock happens
+	return metaPath, func() { fileLock.Unlock() }, nil
+}
 
+// writeManagerMetaFile writes the registry; the caller holds the lock.
+func writeManagerMetaFile(metaPath string, meta ManagerMeta) error {
 	data, err := json.MarshalIndent(meta, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal manager meta data: %w", err)
@@ -402,7 +473,7 @@ func writeManagerMeta(meta ManagerMeta) error {
 func handleConfigCommand(args []string) {
 	if len(args) == 0 {
 		printError("Config subcommand required.", "Usage: wpod config <get|set|show> <key> [value]")
-		printInfo("Available keys for config: sites_base_directory")
+		printInfo("Available keys for config: sites_base_directory, dev_domain_suffix, hosts_file, bind_address, dns_listen, templates_directory, proxy_backend, traefik_http_port, traefik_https_port, traefik_dashboard_port, php_version, web_server")
 		return
 	}
 	subcommand := strings.ToLower(args[0])
@@ -453,6 +524,29 @@ func configShow() {
 		printInfo(infoMsgStyle.Render("Sites Base Directory:"), commandStyle.Render(config.SitesBaseDirectory))
 	}
 
+	printInfo(infoMsgStyle.Render("Dev Domain Suffix:"), commandStyle.Render(configuredDevDomainSuffix(config)))
+	printInfo(infoMsgStyle.Render("Hosts File:"), commandStyle.Render(configuredHostsFile(config)))
+	printInfo(infoMsgStyle.Render("Bind Address:"), commandStyle.Render(configuredBindAddress(config)))
+	printInfo(infoMsgStyle.Render("DNS Listen Address:"), commandStyle.Render(configuredDNSListen(config)))
+	if templatesDir, err := configuredTemplatesDir(config); err == nil {
+		printInfo(infoMsgStyle.Render("User Templates Directory:"), commandStyle.Render(templatesDir))
+	}
+	printInfo(infoMsgStyle.Render("Proxy Backend:"), commandStyle.Render(configuredProxyBackend(config)))
+	if configuredProxyBackend(config) == proxyBackendTraefik {
+		httpPort, httpsPort, dashboardPort := configuredTraefikPorts(config)
+		printInfo(infoMsgStyle.Render("Traefik Ports:"), commandStyle.Render(fmt.Sprintf("http %d, https %d, dashboard %d", httpPort, httpsPort, dashboardPort)))
+	}
+	printInfo(infoMsgStyle.Render("Web Server:"), commandStyle.Render(configuredWebServer(config)))
+	phpVersion := configuredPHPVersion(config)
+	if phpVersion == "" {
+		phpVersion = "(image default)"
+	}
+	printInfo(infoMsgStyle.Render("PHP Version:"), commandStyle.Render(phpVersion))
+	idleStop := "(off)"
+	if config.IdleStopMinutes > 0 {
+		idleStop = fmt.Sprintf("%d minute(s)", config.IdleStopMinutes)
+	}
+	printInfo(infoMsgStyle.Render("Idle Auto-Stop:"), commandStyle.Render(idleStop))
 }
 
 func configGet(key string) {
@@ -469,6 +563,29 @@ func configGet(key string) {
 		} else {
 			fmt.Println(config.SitesBaseDirectory) // Raw output for scripting
 		}
+	case "dev_domain_suffix":
+		fmt.Println(configuredDevDomainSuffix(config))
+	case "hosts_file":
+		fmt.Println(configuredHostsFile(config))
+	case "bind_address":
+		fmt.Println(configuredBindAddress(config))
+	case "dns_listen":
+		fmt.Println(configuredDNSListen(config))
+	case "templates_directory":
+		if templatesDir, err := configuredTemplatesDir(config); err == nil {
+			fmt.Println(templatesDir)
+		}
+	case "proxy_backend":
+		fmt.Println(configuredProxyBackend(config))
+	case "traefik_http_port", "traefik_https_port", "traefik_dashboard_port":
+		httpPort, httpsPort, dashboardPort := configuredTraefikPorts(config)
+		fmt.Println(map[string]int{"traefik_http_port": httpPort, "traefik_https_port": httpsPort, "traefik_dashboard_port": dashboardPort}[key])
+	case "php_version":
+		fmt.Println(configuredPHPVersion(config))
+	case "web_server":
+		fmt.Println(configuredWebServer(config))
+	case "idle_stop_minutes":
+		fmt.Println(config.IdleStopMinutes)
 	default:
 		printError("Unknown configuration key.", fmt.Sprintf("Key '%s' not recognized.", key))
 	}
@@ -528,6 +645,126 @@ func configSet(key, value string) {
 		} else {
 			printInfo("Sites base directory is already set to this path. No changes made.")
 		}
+	case "dev_domain_suffix":
+		suffix := strings.TrimSpace(value)
+		if suffix != "" && !strings.HasPrefix(suffix, ".") {
+			suffix = "." + suffix
+		}
+		if strings.ContainsAny(suffix, "/\\:*?\"<>| ") {
+			printError("Invalid domain suffix.", fmt.Sprintf("'%s' contains invalid characters.", value))
+			return
+		}
+		changed = config.DevDomainSuffix != suffix
+		config.DevDomainSuffix = suffix
+		printSuccess("Dev domain suffix set to:", commandStyle.Render(configuredDevDomainSuffix(config)))
+	case "hosts_file":
+		hostsPath := strings.TrimSpace(value)
+		if hostsPath != "" {
+			absPath, errAbs := filepath.Abs(hostsPath)
+			if errAbs != nil {
+				printError("Invalid path value.", fmt.Sprintf("Could not determine absolute path for '%s': %v", value, errAbs))
+				return
+			}
+			hostsPath = absPath
+		}
+		changed = config.HostsFile != hostsPath
+		config.HostsFile = hostsPath
+		printSuccess("Hosts file set to:", commandStyle.Render(configuredHostsFile(config)))
+	case "bind_address":
+		addr := strings.TrimSpace(value)
+		if addr != "" && net.ParseIP(addr) == nil {
+			printError("Invalid bind address.", fmt.Sprintf("'%s' is not a valid IP address.", value))
+			return
+		}
+		changed = config.BindAddress != addr
+		config.BindAddress = addr
+		printSuccess("Bind address set to:", commandStyle.Render(configuredBindAddress(config)))
+	case "dns_listen":
+		listen := strings.TrimSpace(value)
+		if listen != "" {
+			if _, _, errSplit := net.SplitHostPort(listen); errSplit != nil {
+				printError("Invalid DNS listen address.", fmt.Sprintf("'%s' must be in host:port form, e.g. %s.", value, defaultDNSListen))
+				return
+			}
+		}
+		changed = config.DNSListen != listen
+		config.DNSListen = listen
+		printSuccess("DNS listen address set to:", commandStyle.Render(configuredDNSListen(config)))
+	case "templates_directory":
+		templatesDir := strings.TrimSpace(value)
+		if templatesDir != "" {
+			absPath, errAbs := filepath.Abs(templatesDir)
+			if errAbs != nil {
+				printError("Invalid path value.", fmt.Sprintf("Could not determine absolute path for '%s': %v", value, errAbs))
+				return
+			}
+			templatesDir = absPath
+		}
+		changed = config.TemplatesDirectory != templatesDir
+		config.TemplatesDirectory = templatesDir
+		resolved, _ := configuredTemplatesDir(config)
+		printSuccess("User templates directory set to:", commandStyle.Render(resolved))
+	case "proxy_backend":
+		backend := strings.ToLower(strings.TrimSpace(value))
+		if backend != "" && backend != proxyBackendCaddy && backend != proxyBackendTraefik {
+			printError("Invalid proxy backend.", fmt.Sprintf("'%s' must be '%s' or '%s'.", value, proxyBackendCaddy, proxyBackendTraefik))
+			return
+		}
+		changed = config.ProxyBackend != backend
+		config.ProxyBackend = backend
+		printSuccess("Proxy backend set to:", commandStyle.Render(configuredProxyBackend(config)))
+		if backend == proxyBackendTraefik {
+			printInfo("New instances will be routed through the shared Traefik container.", "Start it with "+commandStyle.Render("wpod proxy up")+"; move existing instances with "+commandStyle.Render("wpod proxy attach <name>")+".")
+		}
+	case "traefik_http_port", "traefik_https_port", "traefik_dashboard_port":
+		port, errPort := parseTraefikPort(value)
+		if errPort != nil {
+			printError("Invalid port.", errPort.Error())
+			return
+		}
+		target := map[string]*int{"traefik_http_port": &config.TraefikHTTPPort, "traefik_https_port": &config.TraefikHTTPSPort, "traefik_dashboard_port": &config.TraefikDashboardPort}[key]
+		changed = *target != port
+		*target = port
+		printSuccess(key+" set to:", commandStyle.Render(strconv.Itoa(port)), "Recreate the proxy to apply: "+commandStyle.Render("wpod proxy down && wpod proxy up"))
+	case "php_version":
+		phpVersion := strings.TrimSpace(value)
+		if errPHP := validatePHPVersion(phpVersion); errPHP != nil {
+			printError("Invalid PHP version.", errPHP.Error())
+			return
+		}
+		changed = config.PHPVersion != phpVersion
+		config.PHPVersion = phpVersion
+		if phpVersion == "" {
+			printSuccess("PHP version unset; new instances use the wordpress image default.")
+		} else {
+			printSuccess("PHP version for new instances set to:", commandStyle.Render(phpVersion))
+		}
+	case "web_server":
+		webServer, errWeb := normalizeWebServer(value)
+		if errWeb != nil {
+			printError("Invalid web server.", errWeb.Error())
+			return
+		}
+		changed = config.WebServer != webServer
+		config.WebServer = webServer
+		printSuccess("Web server for new instances set to:", commandStyle.Render(configuredWebServer(config)))
+	case "idle_stop_minutes":
+		minutes := 0
+		if v := strings.ToLower(strings.TrimSpace(value)); v != "off" && v != "" {
+			var errIdle error
+			if minutes, errIdle = parseIdleMinutes(value); errIdle != nil {
+				printError("Invalid idle policy.", errIdle.Error())
+				return
+			}
+		}
+		changed = config.IdleStopMinutes != minutes
+		config.IdleStopMinutes = minutes
+		if minutes == 0 {
+			printSuccess("Idle auto-stop turned off.")
+		} else {
+			printSuccess(fmt.Sprintf("Running instances are stopped after %d idle minute(s).", minutes),
+				"Run "+commandStyle.Render("wpod daemon")+" or "+commandStyle.Render("wpod idle --apply")+" to apply the policy.")
+		}
 	default:
 		printError("Unknown configuration key.", fmt.Sprintf("Key '%s' is not recognized for setting.", key))
 		return
@@ -645,13 +882,13 @@ func findAvailablePort(startPort, endPort int, usedPorts map[int]bool) (int, err
 
 // In cmd/wp-manager/main.go
 
-func createInstance() {
+func createInstance(assumeYes bool) {
 	printSectionHeader("Create New WordPress Instance")
 
 	// List templates with meta for selection
-	templates, err := listAvailableTemplatesWithMeta()
+	templates, err := loadTemplates()
 	if err != nil || len(templates) == 0 {
-		printError("No templates found", "Ensure at least one template with blueprint.json exists in templates/.")
+		printError("No templates found", "Ensure at least one template with blueprint.json exists in templates/ or the user template directory.")
 		return
 	}
 
@@ -661,6 +898,9 @@ func createInstance() {
 		options := make([]huh.Option[string], 0, len(templates))
 		for _, t := range templates {
 			desc := t.Name
+			if t.Source == templateSourceUser {
+				desc += " [user]"
+			}
 			if t.Description != "" {
 				desc += " — " + t.Description
 			}
@@ -682,6 +922,12 @@ func createInstance() {
 	}
 
 	printInfo("Using template:", selectedTemplate)
+	var selectedTemplateInfo templateInfo
+	for _, t := range templates {
+		if t.Dir == selectedTemplate {
+			selectedTemplateInfo = t
+		}
+	}
 
 	if _, err := exec.LookPath("docker"); err != nil {
 		printError("Docker Not Found", "Docker is required but not installed or not in PATH.")
@@ -949,7 +1195,10 @@ func createInstance() {
 	caddyHTTPSPort := 443
 	caddyPortsAvailable := isPortAvailable(80) && isPortAvailable(443)
 	caddyEnabled := false
-	if !caddyPortsAvailable {
+	proxyBackend := configuredProxyBackend(globalConfig)
+	if proxyBackend == proxyBackendTraefik {
+		printInfo("Proxy backend is Traefik.", "This instance will be routed through the shared '"+traefikContainerName+"' container instead of its own Caddy.")
+	} else if !caddyPortsAvailable {
 		printWarning("Ports 80 and/or 443 are already in use.", "A host-level web server (Caddy, Nginx, Apache, etc.) may be running.")
 		printInfo("Caddy container will NOT be enabled by default.", "You can use the provided Caddyfile template for your host server.")
 	} else {
@@ -1033,6 +1282,9 @@ func createInstance() {
 	}
 
 	WORDPRESS_VERSION := "latest"
+	webServer := configuredWebServer(globalConfig)
+	phpVersion := configuredPHPVersion(globalConfig)
+	dbEngine, dbVersion := defaultDBEngine, defaultDBVersion
 	if customizeSettings {
 		var wpVersionInput string
 		wpVersionPrompt := huh.NewInput().
@@ -1044,6 +1296,42 @@ func createInstance() {
 			WORDPRESS_VERSION = strings.TrimSpace(wpVersionInput)
 		}
 
+		phpOptions := []huh.Option[string]{huh.NewOption("Image default", "")}
+		for _, v := range supportedPHPVersions {
+			phpOptions = append(phpOptions, huh.NewOption("PHP "+v, v))
+		}
+		if phpVersion != "" && !containsString(supportedPHPVersions, phpVersion) {
+			phpOptions = append(phpOptions, huh.NewOption("PHP "+phpVersion, phpVersion))
+		}
+		stackForm := huh.NewForm(huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Web Server").
+				Description("Apache runs inside the wordpress container; nginx-fpm adds an nginx container in front of php-fpm.").
+				Options(huh.NewOption("Apache (mod_php)", webServerApache), huh.NewOption("nginx + php-fpm", webServerNginxFPM)).
+				Value(&webServer),
+			huh.NewSelect[string]().
+				Title("PHP Version").
+				Description("Selects the wordpress image tag.").
+				Options(phpOptions...).
+				Value(&phpVersion),
+		)).WithTheme(theme)
+		_ = stackForm.Run()
+
+		dbOptions := make([]huh.Option[string], 0, len(dbChoices))
+		for _, c := range dbChoices {
+			dbOptions = append(dbOptions, huh.NewOption(describeDB(c.Engine, c.Version), c.Engine+":"+c.Version))
+		}
+		dbSelection := dbEngine + ":" + dbVersion
+		dbForm := huh.NewSelect[string]().
+			Title("Database").
+			Description("Engine and version of the db service.").
+			Options(dbOptions...).
+			Value(&dbSelection)
+		if err := dbForm.WithTheme(theme).Run(); err == nil {
+			dbEngine, dbVersion, _ = strings.Cut(dbSelection, ":")
+		}
+		warnUnsupportedDBArch(dbEngine, dbVersion)
+
 		printInfo("Custom Configuration Required")
 		suggestedWPPort, errPortFind := findAvailablePort(11000, 19999, usedPorts)
 		if errPortFind != nil {
@@ -1173,7 +1461,9 @@ func createInstance() {
 		printInfo("Assigned Mailpit Web Port:", fmt.Sprintf("%d", mailpitWebPort))
 		printInfo("Assigned Adminer Web Port:", fmt.Sprintf("%d", adminerWebPort))
 
-		if !caddyPortsAvailable {
+		if proxyBackend == proxyBackendTraefik {
+			caddyEnabled = false
+		} else if !caddyPortsAvailable {
 			caddyEnabled = false
 			// Only print the manual Caddy info here, not the warning again
 			printInfo("To start Caddy manually later, run:", "docker compose up -d caddy")
@@ -1183,6 +1473,17 @@ func createInstance() {
 		}
 	}
 
+	blueprint, errBlueprint := readBlueprint(selectedTemplateInfo.FS)
+	if errBlueprint != nil {
+		printError("Template Blueprint Invalid", errBlueprint.Error(), "Check it with 'wpod template validate "+selectedTemplate+"'.")
+		return
+	}
+	templateVars, errVars := promptBlueprintVariables(blueprint)
+	if errVars != nil {
+		printError("Input cancelled.", errVars.Error())
+		return
+	}
+
 	printInfo("Setting up instance directory structure...", fmt.Sprintf("Target: %s", commandStyle.Render(fullInstanceName)))
 	if err := os.MkdirAll(fullInstanceName, 0755); err != nil {
 		printError("Directory Creation Failed", fmt.Sprintf("Failed to create %s: %v", fullInstanceName, err))
@@ -1195,29 +1496,7 @@ func createInstance() {
 	}
 
 	// Use the selectedTemplate variable for template file copying
-	templateRoot := filepath.Join("cmd/wp-manager/templates", selectedTemplate)
-	errCopy := fs.WalkDir(os.DirFS(templateRoot), ".", func(pathInTemplate string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return fmt.Errorf("walk err at %s: %w", pathInTemplate, err)
-		}
-		relativePath := pathInTemplate
-		if relativePath == "." {
-			return nil
-		}
-		targetPath := filepath.Join(fullInstanceName, relativePath)
-		if d.IsDir() {
-			return os.MkdirAll(targetPath, 0755)
-		}
-		srcFile, errRead := os.ReadFile(filepath.Join(templateRoot, pathInTemplate))
-		if errRead != nil {
-			return fmt.Errorf("read template %s: %w", pathInTemplate, errRead)
-		}
-		perm := fs.FileMode(0644)
-		if d.Name() == "manage" || d.Name() == "manage.exe" {
-			perm = 0755
-		}
-		return os.WriteFile(targetPath, srcFile, perm)
-	})
+	errCopy := copyTemplate(selectedTemplateInfo, fullInstanceName)
 	if errCopy != nil {
 		printError("Template Copy Failed", errCopy.Error())
 		os.RemoveAll(fullInstanceName)
@@ -1279,6 +1558,12 @@ func createInstance() {
 			}
 		}
 	}
+	newEnvContentStr, blueprintNotes := applyBlueprintToEnv(newEnvContentStr, blueprint, templateVars, usedPorts)
+	if len(blueprintNotes) > 0 {
+		printInfo("Applied template blueprint:", blueprintNotes...)
+	}
+	newEnvContentStr = applyStackToEnv(newEnvContentStr, WORDPRESS_VERSION, phpVersion, webServer)
+	newEnvContentStr = applyDBToEnv(newEnvContentStr, dbEngine, dbVersion)
 	if err := os.WriteFile(envFilePath, []byte(newEnvContentStr), 0644); err != nil {
 		printError("Failed to Write .env", fmt.Sprintf("Update .env failed: %v", err))
 		os.RemoveAll(fullInstanceName)
@@ -1289,14 +1574,14 @@ func createInstance() {
 	// --- Generate Instance-Specific Caddyfile ---
 	printInfo("Generating instance-specific Caddyfile...")
 
-	// Prompt for dev domain suffix (default: .example.local)
-	devDomainSuffix := ".example.local"
+	// Prompt for dev domain suffix (default: configured suffix or .test)
+	devDomainSuffix := configuredDevDomainSuffix(globalConfig)
 	var customDomainSuffix string
 	formDomain := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
 				Title("Dev Domain Suffix").
-				Description("Enter the dev domain suffix for this instance (e.g., .local, .test, .example.local).").
+				Description("Enter the dev domain suffix for this instance (e.g., .test, .localhost).").
 				Placeholder(devDomainSuffix).
 				Value(&customDomainSuffix),
 		),
@@ -1318,17 +1603,17 @@ func createInstance() {
 		InstanceNameBase: instanceNameBase, // for Caddyfile.template compatibility
 		DevDomainSuffix:  devDomainSuffix,  // for Caddyfile.template compatibility
 	}
-	caddyTemplatePathInEmbedFS := "templates/docker-default-wordpress/config/Caddyfile.template"
+	caddyTemplatePath := "config/Caddyfile.template"
 
-	templateContent, err := defaultWordpressTemplate.ReadFile(caddyTemplatePathInEmbedFS)
+	templateContent, err := readTemplateFile(selectedTemplateInfo, caddyTemplatePath)
 	if err != nil {
-		printWarning("Could not read embedded Caddyfile.template.", fmt.Sprintf("Path: %s, Error: %v", caddyTemplatePathInEmbedFS, err))
+		printWarning("Could not read Caddyfile.template.", fmt.Sprintf("Path: %s, Error: %v", caddyTemplatePath, err))
 		// Decide if this is fatal or if the instance can be created without it.
 		// For now, let's continue but warn.
 	} else {
 		tmpl, err := template.New("instanceCaddyfile").Parse(string(templateContent))
 		if err != nil {
-			printWarning("Failed to parse embedded Caddyfile.template.", err.Error())
+			printWarning("Failed to parse Caddyfile.template.", err.Error())
 		} else {
 			// Ensure the config directory exists in the new instance
 			instanceConfigDir := filepath.Join(fullInstanceName, "config")
@@ -1353,12 +1638,51 @@ func createInstance() {
 		}
 	}
 
+	renderCtx := newTemplateContext(fullInstanceName, selectedTemplate, devHostName, devDomainSuffix, proxyBackend, newEnvContentStr, templateVars)
+	renderedFiles, errRender := renderTemplateFiles(fullInstanceName, renderCtx)
+	if errRender != nil {
+		printError("Template Rendering Failed", errRender.Error(), "Check it with 'wpod template validate "+selectedTemplate+"'.")
+		os.RemoveAll(fullInstanceName)
+		return
+	}
+	if len(renderedFiles) > 0 {
+		printSuccess("Rendered template files:", renderedFiles...)
+	}
+
+	if err := configureInstanceWebServer(fullInstanceName, selectedTemplateInfo, webServer); err != nil {
+		printError("Failed to Configure the Web Server", err.Error())
+		os.RemoveAll(fullInstanceName)
+		return
+	}
+	if webServer == webServerNginxFPM {
+		printSuccess("nginx + php-fpm configured:", "nginx publishes the WordPress port; PHP runs in the wordpress container.")
+	}
+	if err := configureInstanceDatabase(fullInstanceName, dbEngine); err != nil {
+		printWarning("Could not adapt the db healthcheck to "+dbEngineLabel(dbEngine)+".", err.Error())
+	}
+
+	if proxyBackend == proxyBackendTraefik {
+		routed, errTraefik := configureInstanceForTraefik(fullInstanceName, instanceNameBase, devHostName, globalConfig)
+		if errTraefik != nil {
+			printWarning("Could not add Traefik routing to docker-compose.yml.", errTraefik.Error(), "Fix the compose file and run 'wpod proxy attach "+filepath.Base(fullInstanceName)+"'.")
+		} else {
+			printSuccess("Traefik routing added to docker-compose.yml:", routed...)
+		}
+	}
+
 	localMeta := InstanceMeta{
 		Directory:        fullInstanceName,
 		CreationDate:     time.Now().Format("2006-01-02 15:04:05"),
 		WordPressVersion: parseEnvValue([]byte(newEnvContentStr), "WORDPRESS_VERSION"),
-		DBVersion:        parseEnvValue([]byte(newEnvContentStr), "MYSQL_VERSION"),
+		DBVersion:        dbVersion,
+		DBEngine:         dbEngine,
 		WordPressPort:    wordpressPort, Status: "Stopped",
+		DevHostName:  devHostName,
+		ProxyBackend: proxyBackend,
+		Template:     selectedTemplate,
+		TemplateVars: templateVars,
+		PHPVersion:   phpVersion,
+		WebServer:    webServer,
 	}
 	if err := writeInstanceMeta(fullInstanceName, &localMeta); err != nil {
 		printError("Local Meta Write Failed", fmt.Sprintf("Write %s failed: %v", metaFileName, err))
@@ -1388,7 +1712,9 @@ func createInstance() {
 		fmt.Sprintf("Name: %s", commandStyle.Render(instanceNameBase)),
 		fmt.Sprintf("Directory: %s", commandStyle.Render(fullInstanceName)),
 		fmt.Sprintf("WordPress Port (on host): %d", wordpressPort),
-		fmt.Sprintf("Suggested Dev Hostname: %s (Add to hosts file: 127.0.0.1 %s)", commandStyle.Render(devHostName), devHostName),
+		fmt.Sprintf("Stack: %s", describeStack(webServer, phpVersion)),
+		fmt.Sprintf("Database: %s", describeDB(dbEngine, dbVersion)),
+		fmt.Sprintf("Suggested Dev Hostname: %s (Run '%s' to add it to your hosts file)", commandStyle.Render(devHostName), commandStyle.Render("wpod hosts sync")),
 		fmt.Sprintf("Mailpit Web UI: http://0.0.0.0:%d (SMTP on port %d)", mailpitWebPort, mailpitSMTPPort),
 		fmt.Sprintf("Adminer Web UI: http://0.0.0.0:%d", adminerWebPort),
 		"",
@@ -1398,7 +1724,11 @@ func createInstance() {
 		fmt.Sprintf("  Access via browser: %s (if hosts/Caddy configured) or http://0.0.0.0:%d", commandStyle.Render(devHostName), wordpressPort),
 	}
 	// Add Caddy manual start instructions if not enabled
-	if !caddyEnabled {
+	if proxyBackend == proxyBackendTraefik {
+		successDetails = append(successDetails, "", lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("Routed through the shared Traefik proxy:"))
+		successDetails = append(successDetails, fmt.Sprintf("  http://%s, http://adminer.%s, http://mail.%s", devHostName, devHostName, devHostName))
+		successDetails = append(successDetails, "  Start the proxy with: "+commandStyle.Render("wpod proxy up"))
+	} else if !caddyEnabled {
 		successDetails = append(successDetails, "", lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("Caddy was not enabled. To start Caddy manually (if ports become free):"))
 		successDetails = append(successDetails, "  docker compose up -d caddy")
 		successDetails = append(successDetails, "  See docs/caddy.md for more info on manual reverse proxy setup.")
@@ -1417,7 +1747,12 @@ func createInstance() {
 			"caddy_enabled":     caddyEnabled,
 			"caddy_http_port":   caddyHTTPPort,
 			"caddy_https_port":  caddyHTTPSPort,
+			"proxy_backend":     proxyBackend,
 			"wordpress_version": WORDPRESS_VERSION,
+			"php_version":       phpVersion,
+			"web_server":        webServer,
+			"db_engine":         dbEngine,
+			"db_version":        dbVersion,
 			"created":           time.Now().Format("2006-01-02 15:04:05"),
 		}
 		b, _ := json.MarshalIndent(output, "", "  ")
@@ -1425,78 +1760,31 @@ func createInstance() {
 		return
 	}
 
-	promptAndStartInstance(fullInstanceName)
+	if proxyBackend == proxyBackendTraefik {
+		if err := ensureTraefikRunning(globalConfig); err != nil {
+			printWarning("Shared Traefik proxy is not running.", err.Error(), "Start it later with 'wpod proxy up'.")
+		}
+	}
+
+	promptAndStartInstance(fullInstanceName, selectedTemplateInfo, assumeYes)
 
 	// --- Copy and Patch docker-compose.yml ---
 	dockerComposeInstancePath := filepath.Join(fullInstanceName, "docker-compose.yml")
 
-	dockerComposeContent, err := os.ReadFile(dockerComposeInstancePath)
+	composeFile, err := compose.Load(dockerComposeInstancePath)
 	if err != nil {
 		printError("Failed to read docker-compose.yml after copy", err.Error())
 		os.RemoveAll(fullInstanceName)
 		return
 	}
 	if !caddyEnabled {
-		// Patch docker-compose.yml: ensure caddy's profiles includes 'donotstart', but do not duplicate the key
-		lines := strings.Split(string(dockerComposeContent), "\n")
-		var newLines []string
-		inCaddy := false
-		for i := 0; i < len(lines); i++ {
-			line := lines[i]
-			trimmed := strings.TrimSpace(line)
-			if strings.HasPrefix(trimmed, "caddy:") && strings.HasPrefix(line, "  caddy:") {
-				inCaddy = true
-				newLines = append(newLines, line)
-				continue
-			}
-			if inCaddy {
-				if strings.HasPrefix(trimmed, "profiles:") {
-					// Check if 'donotstart' is already present in the list
-					profilesLine := line
-					var profilesBlock []string
-					profilesBlock = append(profilesBlock, profilesLine)
-					// Collect all indented lines under 'profiles:'
-					for j := i + 1; j < len(lines); j++ {
-						nextLine := lines[j]
-						if strings.HasPrefix(nextLine, "      - ") {
-							profilesBlock = append(profilesBlock, nextLine)
-						} else {
-							break
-						}
-					}
-					// Check if donotstart is present
-					donotstartPresent := false
-					for _, pl := range profilesBlock {
-						if strings.Contains(pl, "donotstart") {
-							donotstartPresent = true
-							break
-						}
-					}
-					if !donotstartPresent {
-						// Insert '      - donotstart' after 'profiles:'
-						newLines = append(newLines, profilesBlock[0])
-						newLines = append(newLines, "      - donotstart")
-						if len(profilesBlock) > 1 {
-							newLines = append(newLines, profilesBlock[1:]...)
-						}
-					} else {
-						newLines = append(newLines, profilesBlock...)
-					}
-					// Skip the lines we just processed
-					i += len(profilesBlock) - 1
-					continue
-				}
-				// End of caddy service block: next top-level service or outdent
-				if len(line) > 0 && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
-					inCaddy = false
-				}
+		// Keep caddy out of 'docker compose up' by putting it in a profile nobody activates.
+		if caddy := composeFile.Service("caddy"); caddy != nil && caddy.AddProfile("donotstart") {
+			if err := composeFile.Save(dockerComposeInstancePath); err != nil {
+				printError("Failed to patch docker-compose.yml to add Caddy profile", err.Error())
+				os.RemoveAll(fullInstanceName)
+				return
 			}
-			newLines = append(newLines, line)
-		}
-		if err := os.WriteFile(dockerComposeInstancePath, []byte(strings.Join(newLines, "\n")), 0644); err != nil {
-			printError("Failed to patch docker-compose.yml to add Caddy profile", err.Error())
-			os.RemoveAll(fullInstanceName)
-			return
 		}
 		printInfo("Caddy service set to 'donotstart' profile in docker-compose.yml for this instance.")
 	}
@@ -1665,7 +1953,19 @@ func deleteInstance() {
 	}
 } // End of deleteInstance
 
-func updateStatuses() {
+// Defaults for 'wpod update': how many instances are checked at once and how long one
+// docker call may take before the instance is reported as timed out.
+const (
+	defaultStatusWorkers = 8
+	defaultStatusTimeout = 20 * time.Second
+)
+
+func updateStatuses(args []string) {
+	updateFlags := flag.NewFlagSet("update", flag.ExitOnError)
+	workers := updateFlags.Int("workers", defaultStatusWorkers, "Number of instances checked at the same time")
+	timeout := updateFlags.Duration("timeout", defaultStatusTimeout, "Time limit for each instance's docker check")
+	_ = updateFlags.Parse(args)
+
 	printSectionHeader("Update Instance Statuses")
 
 	// Read manager meta
@@ -1679,190 +1979,123 @@ func updateStatuses() {
 		return
 	}
 
+	printInfo(fmt.Sprintf("Checking status for %d registered instance(s), up to %d at a time...", len(managerMeta), max(*workers, 1)))
+	results := checkStatuses(managerMeta, *workers, *timeout)
+	checkedAt := time.Now().Format(time.RFC3339)
+
+	// pendingUpdate is what this run learned about one instance.
+	type pendingUpdate struct {
+		meta          InstanceMeta
+		statusChanged bool
+	}
+	updates := make(map[string]pendingUpdate)
+	var timedOut []string
 	updatedCount := 0
-	somethingChanged := false
-	printInfo("Checking status for each registered instance...")
 
-	// Iterate through manager meta
-	for instanceName, meta := range managerMeta {
+	names := make([]string, 0, len(managerMeta))
+	for name := range managerMeta {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, instanceName := range names {
+		meta := managerMeta[instanceName]
 		instancePath := meta.Directory // Get path from manager meta
-		originalStatus := meta.Status
-		newStatus := "Unknown"
-
-		if _, statErr := os.Stat(instancePath); os.IsNotExist(statErr) {
-			newStatus = "Directory Missing"
-		} else {
-			// Run docker compose ps in the specific instance directory
-			cmd := exec.Command("docker", "compose", "ps", "--services", "--filter", "status=running")
-			cmd.Dir = instancePath
-			var out bytes.Buffer
-			cmd.Stdout = &out
-			// Ignore error, as non-zero exit often means no running services
-			_ = cmd.Run()
-
-			runningServices := out.String()
-			if strings.Contains(runningServices, "wordpress") ||
-				strings.Contains(runningServices, "wp") ||
-				strings.Contains(runningServices, "web") ||
-				strings.Contains(runningServices, "app") {
-				newStatus = "Running"
-			} else {
-				newStatus = "Stopped"
+		result := results[instanceName]
+		if result.TimedOut {
+			// Keep the last known status rather than recording a stalled docker as Unknown.
+			timedOut = append(timedOut, instanceName)
+			continue
+		}
+		if result.Err != nil {
+			printWarning(fmt.Sprintf("Could not check %s", instanceName), result.Err.Error())
+		}
+		update := pendingUpdate{meta: meta}
+		changed := false
+
+		// Pick up the versions './manage' detected in the running containers.
+		if localMeta, readErr := readInstanceMeta(instancePath); readErr == nil {
+			if syncDetectedVersions(&update.meta, localMeta) {
+				fmt.Printf("  %s Versions: WordPress %s, %s\n", boldStyle.Render(instanceName), update.meta.WordPressVersion, describeDB(update.meta.DBEngine, update.meta.DBVersion))
+				changed = true
 			}
 		}
 
-		// Update status in the managerMeta map if changed
-		if originalStatus != newStatus {
+		// Update status if changed
+		if meta.Status != result.Status || !sameServiceStatuses(meta.Services, result.Services) {
 			fmt.Printf("  %s Status: %s -> %s\n",
 				boldStyle.Render(instanceName),
-				subtleStyle.Render(originalStatus),
-				renderStatus(newStatus)) // Uses the existing helper
+				subtleStyle.Render(meta.Status),
+				renderStatus(result.Status)) // Uses the existing helper
+			if result.Status == statusDegraded {
+				for _, line := range renderServiceStatuses(result.Services, "      ") {
+					fmt.Println(line)
+				}
+			}
 
-			meta.Status = newStatus          // Update the meta struct (which is a copy)
-			managerMeta[instanceName] = meta // Put the updated copy back in the map
-			somethingChanged = true
+			update.meta.Status = result.Status
+			update.meta.Services = result.Services
+			update.meta.StatusCheckedAt = checkedAt
+			update.statusChanged = true
+			changed = true
 			updatedCount++
 
-			// --- Optional: Update the local .wordpress-meta.json file ---
-			// Check if dir exists before trying to write local meta
-			if _, statErr := os.Stat(instancePath); statErr == nil {
-				// Read local meta first to preserve other fields
-				localMeta, readErr := readInstanceMeta(instancePath)
-				if readErr != nil {
-					printWarning(fmt.Sprintf("Local Meta Read Error for %s", instanceName), fmt.Sprintf("Could not update local status: %v", readErr))
-				} else {
-					localMeta.Status = newStatus
-					if writeErr := writeInstanceMeta(instancePath, localMeta); writeErr != nil {
-						printWarning(fmt.Sprintf("Local Meta Write Error for %s", instanceName), fmt.Sprintf("Could not update local status: %v", writeErr))
-					}
-				}
+			if writeErr := writeLocalStatus(instancePath, result.Status, result.Services, checkedAt); writeErr != nil {
+				printWarning(fmt.Sprintf("Local Meta Error for %s", instanceName), fmt.Sprintf("Could not update local status: %v", writeErr))
 			}
-			// --- End Optional Local Update ---
 		}
-	} // End loop through instances
+		if changed {
+			updates[instanceName] = update
+		}
+	}
 
-	// Write the manager meta back to file *once* if anything changed
-	if somethingChanged {
-		if err := writeManagerMeta(managerMeta); err != nil {
-			printError("Failed to Write Updated Manager Metadata", err.Error())
-		} else {
-			printSuccess("Statuses Updated", fmt.Sprintf("%d instance(s) had their status refreshed in manager metadata.", updatedCount))
+	if len(timedOut) > 0 {
+		printWarning(fmt.Sprintf("%d Instance(s) Timed Out", len(timedOut)),
+			fmt.Sprintf("Docker did not answer within %s for: %s", *timeout, strings.Join(timedOut, ", ")),
+			"Their previous status was kept. Run 'wpod update' again or raise --timeout.")
+	}
+
+	// Write the manager meta back to file *once*, merged into the current registry under the
+	// lock so instances registered or edited meanwhile are kept.
+	if len(updates) == 0 {
+		printInfo("Statuses Up-to-Date", "All instance statuses are current.")
+		return
+	}
+	err = updateManagerMeta(func(current ManagerMeta) bool {
+		for name, update := range updates {
+			meta, ok := current[name]
+			if !ok {
+				continue // deleted while we were checking
+			}
+			syncDetectedVersions(&meta, &update.meta)
+			if update.statusChanged {
+				meta.Status = update.meta.Status
+				meta.Services = update.meta.Services
+				meta.StatusCheckedAt = update.meta.StatusCheckedAt
+			}
+			current[name] = meta
 		}
+		return true
+	})
+	if err != nil {
+		printError("Failed to Write Updated Manager Metadata", err.Error())
 	} else {
-		printInfo("Statuses Up-to-Date", "All instance statuses are current.")
+		printSuccess("Statuses Updated", fmt.Sprintf("%d instance(s) had their status refreshed in manager metadata.", updatedCount))
 	}
 }
 
 func renderStatus(status string) string {
 	switch status {
-	case "Running":
+	case statusRunning:
 		return statusRunningStyle.Render(status)
-	case "Stopped":
+	case statusStopped, statusDegraded:
 		return statusStoppedStyle.Render(status)
-	case "Directory Missing", "Unknown":
+	case statusMissing, "Directory Missing", statusUnknown:
 		return statusErrorStyle.Render(status)
 	default:
 		return status
 	}
 }
 
-func isWordPressContainerRunning(instanceDir string) bool {
-	metaPath := filepath.Join(instanceDir, ".wordpress-meta.json")
-	metaData, err := os.ReadFile(metaPath)
-	if err != nil {
-		return false
-	}
-	var meta InstanceMeta
-	if err := json.Unmarshal(metaData, &meta); err != nil {
-		return false
-	}
-	containerName := "wp-" + filepath.Base(instanceDir)
-	cmd := exec.Command("docker", "ps", "--filter", "name="+containerName, "--format", "{{.Names}}")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-	return strings.Contains(string(output), containerName)
-}
-
-func listInstances() {
-	printSectionHeader("List WordPress Instances")
-
-	// Read central manager metadata
-	managerMeta, err := readManagerMeta()
-	if err != nil {
-		printError("Failed to Read Manager Metadata", err.Error())
-		return
-	}
-
-	if len(managerMeta) == 0 {
-		printWarning("No Instances Found", "No instances registered with the manager.")
-		printInfo("Tip:", "Use '"+commandStyle.Render("create")+"' to add a new instance.")
-		return
-	}
-
-	// Define column widths (adjust as needed)
-	nameWidth := 30
-	portWidth := 8
-	dateWidth := 20
-	wpVerWidth := 15
-	dbVerWidth := 15
-	statusWidth := 18
-	dirWidth := 50
-
-	// Header row -
-	header := lipgloss.JoinHorizontal(lipgloss.Top,
-		tableHeaderStyle.Width(nameWidth).Render("Instance Name"),
-		tableHeaderStyle.Width(portWidth).Render("Port"),
-		tableHeaderStyle.Width(dateWidth).Render("Created"),
-		tableHeaderStyle.Width(wpVerWidth).Render("WP Ver"),
-		tableHeaderStyle.Width(dbVerWidth).Render("DB Ver"),
-		tableHeaderStyle.Width(dirWidth).Render("Directory"),
-		tableHeaderStyle.Width(statusWidth).Render("Status"),
-	)
-
-	var rows []string
-	rows = append(rows, header)
-
-	// Iterate through the manager metadata map
-	for instanceName, meta := range managerMeta {
-		var rowCells []string
-
-		wpVer := meta.WordPressVersion
-		if wpVer == "" {
-			wpVer = "N/A"
-		}
-		dbVer := meta.DBVersion
-		if dbVer == "" {
-			dbVer = "N/A"
-		}
-
-		displayDir := shortenPath(meta.Directory, dirWidth-3)
-
-		// --- Live status check ---
-		status := meta.Status
-		if _, err := os.Stat(meta.Directory); err == nil {
-			if isWordPressContainerRunning(meta.Directory) {
-				status = "Running"
-			} else {
-				status = "Stopped"
-			}
-		}
-
-		rowCells = append(rowCells, tableCellStyle.Width(nameWidth).Render(instanceName))
-		rowCells = append(rowCells, tableCellStyle.Width(portWidth).Render(strconv.Itoa(meta.WordPressPort)))
-		rowCells = append(rowCells, tableCellStyle.Width(dateWidth).Render(meta.CreationDate))
-		rowCells = append(rowCells, tableCellStyle.Width(wpVerWidth).Render(wpVer))
-		rowCells = append(rowCells, tableCellStyle.Width(dbVerWidth).Render(dbVer))
-		rowCells = append(rowCells, tableCellStyle.Width(dirWidth).Render(displayDir))
-		rowCells = append(rowCells, tableCellStyle.Width(statusWidth).Render(renderStatus(status)))
-
-		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, rowCells...))
-	}
-
-	fmt.Println(tableBorderStyle.Render(lipgloss.JoinVertical(lipgloss.Left, rows...)))
-}
-
 // Helper function to shorten paths for display (keep this)
 func shortenPath(path string, maxLen int) string {
 	if len(path) <= maxLen {
@@ -1881,144 +2114,6 @@ func shortenPath(path string, maxLen int) string {
 	return "..." + path[len(path)-(maxLen-3):]
 }
 
-// --- Enhanced Doctor Function ---
-
-func doctor() {
-	printSectionHeader("System Doctor: Environment Check")
-	var issuesFound = 0 // Reset or initialize counter
-
-	fmt.Println(lipgloss.NewStyle().Bold(true).Render("\n--- System ---"))
-	printInfo("Operating System", runtime.GOOS)
-	printInfo("Architecture", runtime.GOARCH)
-
-	fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- Docker Environment ---"))
-	dockerPath, dockerOk := checkExecutable("docker")
-	if !dockerOk {
-		printError("Docker Not Found", "The 'docker' command is required but not found in your system's PATH.")
-		issuesFound++
-		printInfo("Doctor Check Complete (stopped early due to missing Docker)")
-		return
-	}
-	printSuccess("Docker Found", fmt.Sprintf("Executable path: %s", dockerPath))
-	cmdDockerInfo := exec.Command("docker", "info")
-	cmdDockerInfo.Stdout = io.Discard
-	var stderrDockerInfo bytes.Buffer
-	cmdDockerInfo.Stderr = &stderrDockerInfo
-	if err := cmdDockerInfo.Run(); err != nil {
-		printError("Docker Daemon Not Responding", "Could not connect to the Docker daemon.", fmt.Sprintf("Error: %v", err), stderrDockerInfo.String())
-		issuesFound++
-	} else {
-		printSuccess("Docker Daemon Responding")
-	}
-
-	if dockerOk {
-		cmdComposeV2 := exec.Command("docker", "compose", "version")
-		var outV2 bytes.Buffer
-		errV2Run := cmdComposeV2.Run() // We only care about the error for existence check
-
-		cmdComposeV1 := exec.Command("docker-compose", "version")
-		var outV1 bytes.Buffer
-		errV1Run := cmdComposeV1.Run() // We only care about the error for existence check
-
-		if errV2Run == nil {
-			cmdComposeV2.Stdout = &outV2 // Re-run to get output if successful
-			_ = cmdComposeV2.Run()
-			versionLine := strings.SplitN(strings.TrimSpace(outV2.String()), "\n", 2)[0]
-			printSuccess("Docker Compose Found", fmt.Sprintf("'docker compose' (v2) is available. (%s)", versionLine))
-		} else if errV1Run == nil {
-			cmdComposeV1.Stdout = &outV1 // Re-run to get output
-			_ = cmdComposeV1.Run()
-			versionLine := strings.SplitN(strings.TrimSpace(outV1.String()), "\n", 2)[0]
-			printWarning("Docker Compose Found (v1)", fmt.Sprintf("'docker-compose' (v1) is available. (%s)", versionLine), "Consider upgrading to Docker Compose v2.")
-		} else {
-			printError("Docker Compose Not Found", "Neither 'docker compose' (v2) nor 'docker-compose' (v1) executed successfully.")
-			issuesFound++
-		}
-	}
-
-	fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- Project Template (Embedded) ---"))
-	templateHealthy := true
-	manageBinaryNameInTemplateForEmbedCheck := "manage" // Default for Linux/Darwin
-	// The doctor checks what *should have been embedded* based on the OS wpod was built for.
-	if runtime.GOOS == "windows" {
-		manageBinaryNameInTemplateForEmbedCheck = "manage.exe"
-	}
-
-	requiredFilesInEmbeddedTemplate := []string{
-		envTemplateFileName,
-		"docker-compose.yml",
-		manageBinaryNameInTemplateForEmbedCheck, // Check for OS-specific manage binary
-	}
-
-	for _, file := range requiredFilesInEmbeddedTemplate {
-		filePathInEmbed := filepath.Join(embeddedTemplateRoot, file)
-		if _, err := defaultWordpressTemplate.ReadFile(filePathInEmbed); err != nil {
-			printError(fmt.Sprintf("Embedded Template File Missing: %s", file),
-				fmt.Sprintf("File '%s' not found within the embedded template at '%s'.", file, filePathInEmbed),
-				"This is likely an issue with the build process (Makefile) or embed directives.",
-				fmt.Sprintf("Error: %v", err))
-			issuesFound++
-			templateHealthy = false
-		} else {
-			printSuccess(fmt.Sprintf("Embedded Template File Found: %s", file), fmt.Sprintf("'%s' exists in embedded template.", filePathInEmbed))
-		}
-	}
-	if templateHealthy {
-		printSuccess("Embedded Template Structure OK", "Essential files found in the embedded template.")
-	}
-
-	fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- Go Build Environment (for wpod itself) ---"))
-	goPath, goOk := checkExecutable("go")
-	if !goOk {
-		printWarning("Go Compiler Not Found", "The 'go' command was not found in your system's PATH.", "This is not critical for running pre-compiled wpod, but needed for development.")
-	} else {
-		printSuccess("Go Compiler Found", fmt.Sprintf("Executable path: %s", goPath))
-	}
-
-	fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- Filesystem Permissions ---"))
-	cwd, errCwd := os.Getwd()
-	if errCwd != nil {
-		printError("Cannot Check Permissions", "Failed to get current working directory.", errCwd.Error())
-		issuesFound++
-	} else {
-		tempFileName := ".wpod-doctor-write-test." + strconv.FormatInt(time.Now().UnixNano(), 10)
-		tempFilePath := filepath.Join(cwd, tempFileName)
-		if errWrite := os.WriteFile(tempFilePath, []byte("test"), 0600); errWrite != nil {
-			printError("Write Permission Denied (Current Directory)",
-				fmt.Sprintf("Cannot write files in '%s'.", cwd),
-				fmt.Sprintf("Error: %v", errWrite))
-			issuesFound++
-		} else {
-			printSuccess("Write Permission Granted (Current Directory)", fmt.Sprintf("Can write files in '%s'.", cwd))
-			_ = os.Remove(tempFilePath)
-		}
-	}
-
-	fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- Network Connectivity ---"))
-	hostsToCheck := []string{"hub.docker.com", "github.com", "raw.githubusercontent.com"}
-	networkOk := true
-	for _, host := range hostsToCheck {
-		if _, err := net.LookupHost(host); err != nil {
-			printWarning("Network Resolution Failed", fmt.Sprintf("Could not resolve '%s'.", host), fmt.Sprintf("Error: %v", err))
-			networkOk = false
-		}
-	}
-	if networkOk {
-		printSuccess("Network Resolution OK", "Able to resolve common external hostnames.")
-	}
-
-	fmt.Println(lipgloss.NewStyle().Bold(true).MarginTop(1).Render("\n--- Doctor Check Summary ---"))
-	if issuesFound == 0 {
-		printSuccess("All critical checks passed!", "Your environment seems ready.")
-	} else {
-		errorStr := "issues"
-		if issuesFound == 1 {
-			errorStr = "issue"
-		}
-		printError(fmt.Sprintf("Found %d critical %s.", issuesFound, errorStr), "Please review the errors above.")
-	}
-}
-
 // --- NEW: Metadata Management Commands ---
 
 // registerInstance prompts for an existing instance path and adds it to the manager.
@@ -2137,6 +2232,10 @@ func registerInstance() {
 	if localMeta.Status == "" {
 		localMeta.Status = "Unknown"
 	}
+	// Record the host name the instance answers on, so it doesn't follow later suffix changes.
+	if localMeta.DevHostName == "" {
+		localMeta.DevHostName = instanceDevHostName(instanceName, *localMeta)
+	}
 
 	// 4. Confirm Instance Name
 	inputName := huh.NewInput().
@@ -2344,10 +2443,21 @@ func locateInstance(instanceName string) {
 	fmt.Println(meta.Directory)
 }
 
+// lookupInstance finds a registered instance by its registry key or by the short name
+// used at creation ("myblog" for "www-myblog-wordpress").
+func lookupInstance(managerMeta ManagerMeta, name string) (string, InstanceMeta, bool) {
+	if meta, ok := managerMeta[name]; ok {
+		return name, meta, true
+	}
+	key := "www-" + name + "-wordpress"
+	meta, ok := managerMeta[key]
+	return key, meta, ok
+}
+
 // handleMetaCommand handles subcommands for 'wpod meta'.
 func handleMetaCommand(args []string) {
 	if len(args) < 1 {
-		printError("Meta Subcommand Required", "Usage: wpod meta [show|edit]")
+		printError("Meta Subcommand Required", "Usage: wpod meta [show|edit|tag|untag]")
 		return
 	}
 	subcommand := strings.ToLower(args[0])
@@ -2357,11 +2467,72 @@ func handleMetaCommand(args []string) {
 		metaShow(args[1:]) // Pass remaining args for flags like --json
 	case "edit":
 		metaEdit()
+	case "tag", "untag":
+		metaTag(args[1:], subcommand == "tag")
 	default:
-		printError("Unknown Meta Subcommand", fmt.Sprintf("Subcommand '%s' not recognized.", subcommand), "Valid subcommands are: show, edit")
+		printError("Unknown Meta Subcommand", fmt.Sprintf("Subcommand '%s' not recognized.", subcommand), "Valid subcommands are: show, edit, tag, untag")
 	}
 }
 
+// metaTag adds tags to (or removes them from) a registered instance. Tags are free-form
+// labels for 'wpod list --tag'.
+func metaTag(args []string, add bool) {
+	if len(args) < 2 {
+		printError("Instance and Tag Required", "Usage: wpod meta tag|untag <name> <tag>...")
+		return
+	}
+	var key string
+	var tags []string
+	err := updateManagerMeta(func(managerMeta ManagerMeta) bool {
+		k, meta, ok := lookupInstance(managerMeta, args[0])
+		if !ok {
+			return false
+		}
+		key = k
+		if add {
+			meta.Tags = normalizeTags(append(meta.Tags, args[1:]...))
+		} else {
+			drop := make(map[string]bool)
+			for _, t := range normalizeTags(args[1:]) {
+				drop[t] = true
+			}
+			var kept []string
+			for _, t := range meta.Tags {
+				if !drop[t] {
+					kept = append(kept, t)
+				}
+			}
+			meta.Tags = kept
+		}
+		tags = meta.Tags
+		managerMeta[k] = meta
+		return true
+	})
+	switch {
+	case err != nil:
+		printError("Failed to Update Manager Metadata", err.Error())
+		return
+	case key == "":
+		printError("Instance Not Found", fmt.Sprintf("'%s' is not registered.", args[0]))
+		return
+	}
+	printSuccess("Tags Updated", fmt.Sprintf("%s: %s", key, strings.Join(tags, ", ")))
+}
+
+// normalizeTags lowercases and trims tags, dropping empties and duplicates.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
 // metaShow displays the content of the manager metadata file.
 func metaShow(args []string) {
 	printSectionHeader("Show Manager Metadata")
@@ -2409,12 +2580,25 @@ func metaShow(args []string) {
 			fmt.Printf("  %s %s\n", styleKey.Render("Directory:"), styleValue.Render(meta.Directory))
 			fmt.Printf("  %s %s\n", styleKey.Render("Port:"), styleValue.Render(strconv.Itoa(meta.WordPressPort)))
 			fmt.Printf("  %s %s\n", styleKey.Render("Status:"), styleValue.Render(meta.Status))
+			for _, line := range renderServiceStatuses(meta.Services, "    ") {
+				fmt.Println(line)
+			}
 			fmt.Printf("  %s %s\n", styleKey.Render("Created:"), styleValue.Render(meta.CreationDate))
 			if meta.WordPressVersion != "" {
 				fmt.Printf("  %s %s\n", styleKey.Render("WP Ver:"), styleValue.Render(meta.WordPressVersion))
 			}
 			if meta.DBVersion != "" {
-				fmt.Printf("  %s %s\n", styleKey.Render("DB Ver:"), styleValue.Render(meta.DBVersion))
+				fmt.Printf("  %s %s\n", styleKey.Render("Database:"), styleValue.Render(describeDB(meta.DBEngine, meta.DBVersion)))
+			}
+			fmt.Printf("  %s %s\n", styleKey.Render("Stack:"), styleValue.Render(describeStack(instanceWebServer(meta), meta.PHPVersion)))
+			if len(meta.Tags) > 0 {
+				fmt.Printf("  %s %s\n", styleKey.Render("Tags:"), styleValue.Render(strings.Join(meta.Tags, ", ")))
+			}
+			switch {
+			case meta.IdleStopMinutes == idleNever:
+				fmt.Printf("  %s %s\n", styleKey.Render("Idle Stop:"), styleValue.Render("never"))
+			case meta.IdleStopMinutes > 0:
+				fmt.Printf("  %s %s\n", styleKey.Render("Idle Stop:"), styleValue.Render(fmt.Sprintf("after %d minute(s)", meta.IdleStopMinutes)))
 			}
 			fmt.Println() // Blank line between entries
 		}
@@ -2698,7 +2882,7 @@ func main() {
 	args := os.Args[2:] // Arguments after the action
 
 	// Print title for actual commands being run
-	if action != "help" && action != "-h" && action != "--help" {
+	if action != "help" && action != "-h" && action != "--help" && !wantsMachineOutput(action, args) {
 		fmt.Println(appTitleStyle.Render("WPOD // Wordpress Development & Management Tool"))
 	}
 
@@ -2709,6 +2893,7 @@ func main() {
 		createFlagSet.StringVar(&jsonInput, "json", "", "JSON string for non-interactive instance creation")
 		createFlagSet.StringVar(&jsonFile, "json-file", "", "Path to JSON file for non-interactive instance creation")
 		createFlagSet.BoolVar(&jsonOutput, "json-output", false, "Output instance details as JSON after creation")
+		createAssumeYes := createFlagSet.Bool("yes", false, "Run the template's post-create steps without asking")
 		_ = createFlagSet.Parse(args)
 		if jsonInput != "" || jsonFile != "" {
 			var data *InstanceCreateJSON
@@ -2725,7 +2910,7 @@ func main() {
 			createInstanceWithJSON(data)
 			return
 		}
-		createInstance()
+		createInstance(*createAssumeYes)
 	case "delete":
 		deleteFlagSet := flag.NewFlagSet("delete", flag.ExitOnError)
 		var deleteJSON string
@@ -2756,11 +2941,27 @@ func main() {
 		}
 		deleteInstance()
 	case "update":
-		updateStatuses()
+		updateStatuses(args)
 	case "list":
-		listInstances()
+		listInstances(args)
+	case "dash", "dashboard":
+		runDashboard()
+	case "stats":
+		statsCommand(args)
+	case "health":
+		healthCommand(args)
+	case "watch":
+		watchCommand(args)
+	case "idle":
+		handleIdleCommand(args)
+	case "daemon":
+		daemonCommand(args)
+	case "audit":
+		auditCommand(args)
+	case "serve":
+		serveCommand(args)
 	case "doctor":
-		doctor()
+		doctorCommand(args)
 	case "config":
 		handleConfigCommand(args)
 	case "register":
@@ -2781,6 +2982,14 @@ func main() {
 		locateInstance(name)
 	case "meta":
 		handleMetaCommand(args)
+	case "hosts":
+		handleHostsCommand(args)
+	case "dns":
+		handleDNSCommand(args)
+	case "proxy":
+		handleProxyCommand(args)
+	case "template", "templates":
+		handleTemplateCommand(args)
 	case "jump", "cd":
 		jumpCommand()
 		return
@@ -2794,17 +3003,46 @@ func main() {
 	}
 }
 
+// wantsMachineOutput reports whether a command prints data for scripts, in which case the
+// title banner is left out of stdout.
+func wantsMachineOutput(action string, args []string) bool {
+	for i, arg := range args {
+		switch {
+		case arg == "--json" || arg == "-json":
+			return true
+		case action == "list" && (arg == "--format" || arg == "-format") && i+1 < len(args):
+			return !strings.EqualFold(args[i+1], "table")
+		case action == "list" && (strings.HasPrefix(arg, "--format=") || strings.HasPrefix(arg, "-format=")):
+			_, format, _ := strings.Cut(arg, "=")
+			return !strings.EqualFold(format, "table")
+		}
+	}
+	return false
+}
+
 func printUsage() {
 	usage := lipgloss.JoinVertical(lipgloss.Left,
 		warningTitle.Render("Usage:"),
 		fmt.Sprintf("  %s %s", filepath.Base(os.Args[0]), commandStyle.Render("<command> [arguments...]")),
 		"",
 		warningTitle.Render("Available Commands:"),
-		fmt.Sprintf("  %s %s", commandStyle.Render("create"), subtleStyle.Render("- Interactively create a new WP instance")),
-		fmt.Sprintf("  %s %s", commandStyle.Render("list"), subtleStyle.Render("- List all registered WP instances")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("create [--yes]"), subtleStyle.Render("- Interactively create a new WP instance (--yes runs template steps without asking)")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("list [--services]"), subtleStyle.Render("- List all registered WP instances (with per-service state)")),
+		fmt.Sprintf("      %s", commandStyle.Render("[--format table|json|yaml|csv|'{{.Name}} {{.Port}}'] [--status s,...] [--template t] [--tag t]")),
+		fmt.Sprintf("      %s", commandStyle.Render("[--sort name|status|created|port|template] [--reverse] [--cached]")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("dash"), subtleStyle.Render("- Full-screen dashboard: live status, start/stop, open, logs, shell, delete")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("stats [name...]"), subtleStyle.Render("- CPU, memory and disk use per instance")),
+		fmt.Sprintf("      %s", commandStyle.Render("[--watch] [--interval 2s] [--json] [--sort memory|cpu|disk|name]")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("health <name> [--json] [--timeout 10s]"), subtleStyle.Render("- Probe the site, login page, database, WP-CLI, Mailpit and Adminer (exit 1 on failure)")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("delete"), subtleStyle.Render("- Interactively delete a WP instance (files & Docker)")),
-		fmt.Sprintf("  %s %s", commandStyle.Render("update"), subtleStyle.Render("- Check and update Docker status for all instances")),
-		fmt.Sprintf("  %s %s", commandStyle.Render("doctor"), subtleStyle.Render("- Check system environment and embedded template integrity")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("update [--workers 8] [--timeout 20s]"), subtleStyle.Render("- Check and update Docker status for all instances in parallel")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("watch [--timeline]"), subtleStyle.Render("- Keep registry status current from Docker events (runs until Ctrl+C)")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("idle [--apply] [--json]"), subtleStyle.Render("- Find (and stop) instances without HTTP requests for idle_stop_minutes")),
+		fmt.Sprintf("      %s", commandStyle.Render("set <name> <minutes|off|default>")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("daemon [--interval 1m]"), subtleStyle.Render("- Apply the idle policy in the background (runs until Ctrl+C)")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("audit [--limit 50] [--instance <name>] [--json]"), subtleStyle.Render("- Show what wpod did on its own, e.g. idle stops")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("serve [--listen 127.0.0.1:9323] [--metrics] [--interval 15s]"), subtleStyle.Render("- Serve /status.json and Prometheus /metrics for dashboards")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("doctor [--fix] [--yes] [--json]"), subtleStyle.Render("- Check the environment, templates and instances; --fix applies remediations")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("register"), subtleStyle.Render("- Register an existing WP instance directory")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("unregister <name>"), subtleStyle.Render("- Remove instance <name> from manager list (files untouched)")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("prune"), subtleStyle.Render("- Check for & remove registrations of missing instance directories")),
@@ -2813,6 +3051,23 @@ func printUsage() {
 		fmt.Sprintf("  %s %s", commandStyle.Render("meta <subcommand>"), subtleStyle.Render("- Manage the central metadata file")),
 		fmt.Sprintf("      %s", commandStyle.Render("show [--json]")),
 		fmt.Sprintf("      %s", commandStyle.Render("edit")),
+		fmt.Sprintf("      %s", commandStyle.Render("tag|untag <name> <tag>...")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("hosts <subcommand>"), subtleStyle.Render("- Manage dev host names in the hosts file")),
+		fmt.Sprintf("      %s", commandStyle.Render("sync [--dry-run] [--hosts-file <path>] [--bind <address>] [--yes]")),
+		fmt.Sprintf("      %s", commandStyle.Render("show [--hosts-file <path>]")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("dns <subcommand>"), subtleStyle.Render("- Resolve dev host names (wildcards included) with a local DNS responder")),
+		fmt.Sprintf("      %s", commandStyle.Render("serve [--listen <addr:port>] [--suffix <.suffix>] [--bind <address>]")),
+		fmt.Sprintf("      %s", commandStyle.Render("instructions [--listen <addr:port>] [--suffix <.suffix>]")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("template <subcommand>"), subtleStyle.Render("- Manage instance templates (built-in and user)")),
+		fmt.Sprintf("      %s", commandStyle.Render("list")),
+		fmt.Sprintf("      %s", commandStyle.Render("path")),
+		fmt.Sprintf("      %s", commandStyle.Render("validate <dir|name>")),
+		fmt.Sprintf("      %s", commandStyle.Render("create --from-instance <name> [--name <dir>] [--with-db] [--pin-versions] [--force]")),
+		fmt.Sprintf("      %s", commandStyle.Render("add <git-url|tarball|path> [--name <dir>] [--ref <branch|tag>] [--subdir <dir>] [--force]")),
+		fmt.Sprintf("      %s", commandStyle.Render("update [name...] [--ref <branch|tag>] [--force]")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("proxy <subcommand>"), subtleStyle.Render("- Manage the shared Traefik proxy (proxy_backend: traefik)")),
+		fmt.Sprintf("      %s", commandStyle.Render("up | down | status")),
+		fmt.Sprintf("      %s", commandStyle.Render("attach <name>")),
 		fmt.Sprintf("  %s %s", commandStyle.Render("config"), subtleStyle.Render("- Manage global configuration")),
 		fmt.Sprintf("      %s", commandStyle.Render("get <key>")),
 		fmt.Sprintf("      %s", commandStyle.Render("set <key> <value>")),
@@ -2821,11 +3076,23 @@ func printUsage() {
 		"",
 		warningTitle.Render("Configurable Keys:"),
 		fmt.Sprintf("  %s %s", commandStyle.Render("sites_base_directory"), subtleStyle.Render("- Default parent directory for new instances")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("dev_domain_suffix"), subtleStyle.Render("- Default dev domain suffix (e.g. .test)")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("hosts_file"), subtleStyle.Render("- Hosts file managed by 'hosts sync'")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("bind_address"), subtleStyle.Render("- Address dev host names resolve to (default 127.0.0.1)")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("dns_listen"), subtleStyle.Render("- UDP address for 'dns serve' (default 127.0.0.1:15353)")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("templates_directory"), subtleStyle.Render("- User template directory (default ~/.config/wpod/templates)")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("proxy_backend"), subtleStyle.Render("- Reverse proxy for new instances: caddy (default) or traefik")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("traefik_http_port, traefik_https_port, traefik_dashboard_port"), subtleStyle.Render("- Host ports of the shared Traefik container")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("php_version"), subtleStyle.Render("- PHP version of the wordpress image for new instances (e.g. 8.2; empty = image default)")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("web_server"), subtleStyle.Render("- Web server for new instances: apache (default) or nginx-fpm")),
+		fmt.Sprintf("  %s %s", commandStyle.Render("idle_stop_minutes"), subtleStyle.Render("- Stop instances after this many minutes without HTTP requests (off = never)")),
 	)
 	fmt.Println("\n" + infoBox.Render(usage))
 }
 
-func promptAndStartInstance(instanceDir string) {
+// promptAndStartInstance offers to start a new instance and then runs the template's
+// post-create steps, after confirmation for user templates unless assumeYes is set.
+func promptAndStartInstance(instanceDir string, tmpl templateInfo, assumeYes bool) {
 	var startNow bool
 	form := huh.NewForm(
 		huh.NewGroup(
@@ -2839,8 +3106,17 @@ func promptAndStartInstance(instanceDir string) {
 	).WithTheme(theme)
 	form.Run()
 
+	absDir, _ := filepath.Abs(instanceDir)
+	var postCreate []BlueprintStep
+	packages := 0
+	if data, err := os.ReadFile(filepath.Join(absDir, blueprintFileName)); err == nil {
+		if bp, errParse := parseBlueprint(data); errParse == nil {
+			postCreate = bp.PostCreate
+			packages = len(bp.Plugins) + len(bp.Themes)
+		}
+	}
+
 	if startNow {
-		absDir, _ := filepath.Abs(instanceDir)
 		if runtime.GOOS == "windows" {
 			printInfo("To start your instance, run the following in a new Command Prompt:")
 			fmt.Printf("cd %s && manage start\n", absDir)
@@ -2859,33 +3135,74 @@ func promptAndStartInstance(instanceDir string) {
 			cmd.Run()
 		}
 
-		// --- Install plugins and themes from blueprint.json if present ---
-		blueprintPath := filepath.Join(absDir, "blueprint.json")
-		if data, err := os.ReadFile(blueprintPath); err == nil {
-			var meta struct {
-				Plugins []string `json:"plugins"`
-				Themes  []string `json:"themes"`
-			}
-			if json.Unmarshal(data, &meta) == nil {
-				for _, plugin := range meta.Plugins {
-					printInfo("Installing plugin via WP-CLI:", plugin)
-					installCmd := exec.Command("docker", "compose", "exec", "-T", "wordpress", "wp", "plugin", "install", plugin, "--activate")
-					installCmd.Dir = absDir
-					installCmd.Stdout = os.Stdout
-					installCmd.Stderr = os.Stderr
-					_ = installCmd.Run()
-				}
-				for _, theme := range meta.Themes {
-					printInfo("Installing theme via WP-CLI:", theme)
-					installCmd := exec.Command("docker", "compose", "exec", "-T", "wordpress", "wp", "theme", "install", theme, "--activate")
-					installCmd.Dir = absDir
-					installCmd.Stdout = os.Stdout
-					installCmd.Stderr = os.Stderr
-					_ = installCmd.Run()
-				}
+		if packages > 0 {
+			printInfo(fmt.Sprintf("The template lists %d plugin(s)/theme(s).", packages),
+				"They are installed and activated by "+commandStyle.Render("./manage install")+" once WordPress is set up,",
+				"or at any later time with "+commandStyle.Render("./manage blueprint")+".")
+		}
+
+		if len(postCreate) > 0 {
+			runPostCreateSteps(absDir, postCreate, tmpl, assumeYes)
+		}
+	} else {
+		printPendingPostCreateSteps(postCreate)
+	}
+}
+
+// runPostCreateSteps runs the template's post-create steps once they are confirmed and,
+// when they use WP-CLI, once WordPress is installed. Steps that can't run yet are listed.
+func runPostCreateSteps(absDir string, steps []BlueprintStep, tmpl templateInfo, assumeYes bool) {
+	if !confirmPostCreateSteps(steps, tmpl, assumeYes) {
+		printInfo("Post-create steps skipped.")
+		printPendingPostCreateSteps(steps)
+		return
+	}
+	if stepsOfType(steps, "wp") && !wordPressInstalled(absDir) {
+		installNow := true
+		if !assumeYes {
+			form := huh.NewForm(
+				huh.NewGroup(
+					huh.NewConfirm().
+						Title("Install WordPress?").
+						Description("The template's WP-CLI steps need an installed site. Run './manage install' now?").
+						Affirmative("Yes, install").
+						Negative("No, later").
+						Value(&installNow),
+				),
+			).WithTheme(theme)
+			if err := form.Run(); err != nil {
+				installNow = false
 			}
 		}
+		if installNow {
+			cmd := exec.Command("./"+manageBinaryName(), "install")
+			cmd.Dir = absDir
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			_ = cmd.Run()
+		}
+		if !installNow || !waitForWordPressInstall(absDir, wordPressInstallWait) {
+			printWarning("WordPress is not installed yet.", "Run the post-create steps after './manage install'.")
+			printPendingPostCreateSteps(steps)
+			return
+		}
+	}
+	if err := runBlueprintSteps(absDir, steps); err != nil {
+		printWarning("Post-create step failed", err.Error(), "Remaining steps were skipped; run them manually from the instance directory.")
+	}
+}
+
+// printPendingPostCreateSteps lists blueprint steps that still need to run once the instance is up.
+func printPendingPostCreateSteps(steps []BlueprintStep) {
+	if len(steps) == 0 {
+		return
+	}
+	details := make([]string, 0, len(steps))
+	for _, step := range steps {
+		details = append(details, describeStep(step))
 	}
+	printInfo("Template post-create steps to run after the first start:", details...)
 }
 
 func viewGlobalConfig() {
@@ -2922,44 +3239,6 @@ func editGlobalConfig() {
 	}
 }
 
-// List available templates (folders in templates/ with blueprint.json)
-func listAvailableTemplatesWithMeta() ([]struct {
-	Dir         string
-	Name        string
-	Description string
-}, error) {
-	templatesDir := "cmd/wp-manager/templates"
-	dirs, err := os.ReadDir(templatesDir)
-	if err != nil {
-		return nil, err
-	}
-	var available []struct {
-		Dir         string
-		Name        string
-		Description string
-	}
-	for _, d := range dirs {
-		if d.IsDir() {
-			blueprintPath := filepath.Join(templatesDir, d.Name(), "blueprint.json")
-			data, err := os.ReadFile(blueprintPath)
-			if err == nil {
-				var meta struct {
-					Name        string `json:"name"`
-					Description string `json:"description"`
-				}
-				if json.Unmarshal(data, &meta) == nil {
-					available = append(available, struct {
-						Dir         string
-						Name        string
-						Description string
-					}{d.Name(), meta.Name, meta.Description})
-				}
-			}
-		}
-	}
-	return available, nil
-}
-
 // --- Instance Creation JSON Schema ---
 // InstanceCreateJSON defines all options for JSON-driven instance creation.
 type InstanceCreateJSON struct {
@@ -2982,6 +3261,13 @@ type InstanceCreateJSON struct {
 	CustomSalts       map[string]string `json:"custom_salts,omitempty"`   // Advanced: custom WP salts
 	ExtraEnv          map[string]string `json:"extra_env,omitempty"`      // Advanced: extra env vars
 	SkipCaddyfile     *bool             `json:"skip_caddyfile,omitempty"` // Advanced: skip Caddyfile
+	ProxyBackend      string            `json:"proxy_backend,omitempty"`  // caddy|traefik, defaults to global config
+	PHPVersion        string            `json:"php_version,omitempty"`    // e.g. 8.2, defaults to global config
+	WebServer         string            `json:"web_server,omitempty"`     // apache|nginx-fpm, defaults to global config
+	DBEngine          string            `json:"db_engine,omitempty"`      // mysql|mariadb, default mysql
+	DBVersion         string            `json:"db_version,omitempty"`     // e.g. 8.0, 5.7, 10.11, 11.4
+	Tags              []string          `json:"tags,omitempty"`           // Labels for 'wpod list --tag'
+	Variables         map[string]string `json:"variables,omitempty"`      // Blueprint template variables
 }
 
 func parseInstanceCreateJSON(input string) (*InstanceCreateJSON, error) {
@@ -3015,6 +3301,21 @@ func createInstanceWithJSON(data *InstanceCreateJSON) {
 		printError("Missing required field: template")
 		os.Exit(1)
 	}
+	selectedTemplate, err := findTemplate(data.Template)
+	if err != nil {
+		printError("Invalid template", err.Error())
+		os.Exit(1)
+	}
+	blueprint, err := readBlueprint(selectedTemplate.FS)
+	if err != nil {
+		printError("Invalid template blueprint", err.Error())
+		os.Exit(1)
+	}
+	templateVars, err := resolveBlueprintVariables(blueprint, data.Variables)
+	if err != nil {
+		printError("Invalid variables", err.Error())
+		os.Exit(1)
+	}
 	parentDir, err := sanitizeParentDirectory(data.ParentDirectory)
 	if err != nil {
 		printError("Invalid parent_directory", err.Error())
@@ -3050,9 +3351,53 @@ func createInstanceWithJSON(data *InstanceCreateJSON) {
 	caddyEnabled := sanitizeBool(data.CaddyEnabled, false)
 	caddyHTTPPort := sanitizeInt(&data.CaddyHTTPPort, 80)
 	caddyHTTPSPort := sanitizeInt(&data.CaddyHTTPSPort, 443)
-	devDomainSuffix := sanitizeString(&data.DevDomainSuffix, ".example.local")
+	globalConfig, _ := readGlobalManagerConfig()
+	devDomainSuffix := sanitizeString(&data.DevDomainSuffix, configuredDevDomainSuffix(globalConfig))
+	devHostName := instanceName + devDomainSuffix
+	if !strings.HasPrefix(devDomainSuffix, ".") {
+		devDomainSuffix = "." + devDomainSuffix
+	}
 	wordpressVersion := sanitizeString(&data.WordPressVersion, "latest")
 	skipCaddyfile := sanitizeBool(data.SkipCaddyfile, false)
+	proxyBackend := strings.ToLower(sanitizeString(&data.ProxyBackend, configuredProxyBackend(globalConfig)))
+	if proxyBackend != proxyBackendCaddy && proxyBackend != proxyBackendTraefik {
+		printError("Invalid proxy_backend", fmt.Sprintf("'%s' must be '%s' or '%s'.", proxyBackend, proxyBackendCaddy, proxyBackendTraefik))
+		os.Exit(1)
+	}
+	if proxyBackend == proxyBackendTraefik {
+		caddyEnabled = false
+	}
+	webServer, errWeb := normalizeWebServer(data.WebServer)
+	if errWeb != nil {
+		printError("Invalid web_server", errWeb.Error())
+		os.Exit(1)
+	}
+	if webServer == "" {
+		webServer = configuredWebServer(globalConfig)
+	}
+	phpVersion := sanitizeString(&data.PHPVersion, configuredPHPVersion(globalConfig))
+	if err := validatePHPVersion(phpVersion); err != nil {
+		printError("Invalid php_version", err.Error())
+		os.Exit(1)
+	}
+	dbEngine, errEngine := normalizeDBEngine(data.DBEngine)
+	if errEngine != nil {
+		printError("Invalid db_engine", errEngine.Error())
+		os.Exit(1)
+	}
+	if dbEngine == "" {
+		dbEngine = defaultDBEngine
+	}
+	defaultVersion := defaultDBVersion
+	if dbEngine == dbEngineMariaDB {
+		defaultVersion = defaultMariaDBVersion
+	}
+	dbVersion := sanitizeString(&data.DBVersion, defaultVersion)
+	if err := validateDBVersion(dbEngine, dbVersion); err != nil {
+		printError("Invalid db_version", err.Error())
+		os.Exit(1)
+	}
+	warnUnsupportedDBArch(dbEngine, dbVersion)
 	//customSalts := sanitizeCustomSalts(data.CustomSalts)
 	// If skipCaddyfile is true, do not generate a Caddyfile later
 	//extraEnv := sanitizeExtraEnv(data.ExtraEnv)
@@ -3064,29 +3409,7 @@ func createInstanceWithJSON(data *InstanceCreateJSON) {
 	}
 
 	// Copy template files
-	templateRoot := filepath.Join("cmd/wp-manager/templates", data.Template)
-	errCopy := fs.WalkDir(os.DirFS(templateRoot), ".", func(pathInTemplate string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return fmt.Errorf("walk err at %s: %w", pathInTemplate, err)
-		}
-		relativePath := pathInTemplate
-		if relativePath == "." {
-			return nil
-		}
-		targetPath := filepath.Join(fullInstanceName, relativePath)
-		if d.IsDir() {
-			return os.MkdirAll(targetPath, 0755)
-		}
-		srcFile, errRead := os.ReadFile(filepath.Join(templateRoot, pathInTemplate))
-		if errRead != nil {
-			return fmt.Errorf("read template %s: %w", pathInTemplate, errRead)
-		}
-		perm := fs.FileMode(0644)
-		if d.Name() == "manage" || d.Name() == "manage.exe" {
-			perm = 0755
-		}
-		return os.WriteFile(targetPath, srcFile, perm)
-	})
+	errCopy := copyTemplate(selectedTemplate, fullInstanceName)
 	if errCopy != nil {
 		printError("Template Copy Failed", errCopy.Error())
 		os.RemoveAll(fullInstanceName)
@@ -3158,6 +3481,17 @@ func createInstanceWithJSON(data *InstanceCreateJSON) {
 			newEnvContentStr = placeholderRe.ReplaceAllString(newEnvContentStr, fmt.Sprintf("%s=%s", key, value))
 		}
 	}
+	usedPorts := map[int]bool{mailpitSMTPPort: true, mailpitWebPort: true, adminerWebPort: true}
+	if existingMeta, errMeta := readManagerMeta(); errMeta == nil {
+		for _, meta := range existingMeta {
+			if meta.WordPressPort > 0 {
+				usedPorts[meta.WordPressPort] = true
+			}
+		}
+	}
+	newEnvContentStr, _ = applyBlueprintToEnv(newEnvContentStr, blueprint, templateVars, usedPorts)
+	newEnvContentStr = applyStackToEnv(newEnvContentStr, wordpressVersion, phpVersion, webServer)
+	newEnvContentStr = applyDBToEnv(newEnvContentStr, dbEngine, dbVersion)
 	if err := os.WriteFile(envFilePath, []byte(newEnvContentStr), 0644); err != nil {
 		printError("Failed to Write .env", fmt.Sprintf("Update .env failed: %v", err))
 		os.RemoveAll(fullInstanceName)
@@ -3170,14 +3504,13 @@ func createInstanceWithJSON(data *InstanceCreateJSON) {
 		// Fix: use local variables instead of data.WordPressPort
 		caddyData := InstanceCaddyConfigData{
 			InstanceName:     filepath.Base(fullInstanceName),
-			DevHostName:      data.InstanceName + devDomainSuffix,
+			DevHostName:      devHostName,
 			WordPressPort:    wordpressPort,
 			CaddyHTTPPort:    caddyHTTPPort,
-			InstanceNameBase: data.InstanceName,
+			InstanceNameBase: instanceName,
 			DevDomainSuffix:  devDomainSuffix,
 		}
-		caddyTemplatePathInEmbedFS := "templates/docker-default-wordpress/config/Caddyfile.template"
-		templateContent, err := defaultWordpressTemplate.ReadFile(caddyTemplatePathInEmbedFS)
+		templateContent, err := readTemplateFile(selectedTemplate, "config/Caddyfile.template")
 		if err == nil {
 			tmpl, err := template.New("instanceCaddyfile").Parse(string(templateContent))
 			if err == nil {
@@ -3195,14 +3528,48 @@ func createInstanceWithJSON(data *InstanceCreateJSON) {
 		}
 	}
 
+	renderCtx := newTemplateContext(fullInstanceName, selectedTemplate.Dir, devHostName, devDomainSuffix, proxyBackend, newEnvContentStr, templateVars)
+	if renderedFiles, err := renderTemplateFiles(fullInstanceName, renderCtx); err != nil {
+		printError("Template rendering failed", err.Error())
+		os.RemoveAll(fullInstanceName)
+		os.Exit(1)
+	} else if len(renderedFiles) > 0 {
+		printSuccess("Rendered template files:", renderedFiles...)
+	}
+
+	if err := configureInstanceWebServer(fullInstanceName, selectedTemplate, webServer); err != nil {
+		printError("Failed to configure the web server", err.Error())
+		os.RemoveAll(fullInstanceName)
+		os.Exit(1)
+	}
+	if err := configureInstanceDatabase(fullInstanceName, dbEngine); err != nil {
+		printWarning("Could not adapt the db healthcheck", err.Error())
+	}
+
+	if proxyBackend == proxyBackendTraefik {
+		if routed, err := configureInstanceForTraefik(fullInstanceName, instanceName, devHostName, globalConfig); err != nil {
+			printWarning("Could not add Traefik routing to docker-compose.yml.", err.Error())
+		} else {
+			printSuccess("Traefik routing added to docker-compose.yml:", routed...)
+		}
+	}
+
 	// Register instance meta
 	localMeta := InstanceMeta{
 		Directory:        fullInstanceName,
 		CreationDate:     time.Now().Format("2006-01-02 15:04:05"),
 		WordPressVersion: wordpressVersion,
-		DBVersion:        "", // Could be extended
+		DBVersion:        dbVersion, // replaced by the server version after './manage install'
+		DBEngine:         dbEngine,
 		WordPressPort:    wordpressPort,
 		Status:           "Stopped",
+		DevHostName:      devHostName,
+		ProxyBackend:     proxyBackend,
+		Template:         selectedTemplate.Dir,
+		TemplateVars:     templateVars,
+		PHPVersion:       phpVersion,
+		WebServer:        webServer,
+		Tags:             normalizeTags(data.Tags),
 	}
 	_ = writeInstanceMeta(fullInstanceName, &localMeta)
 
@@ -3218,21 +3585,26 @@ func createInstanceWithJSON(data *InstanceCreateJSON) {
 		fmt.Sprintf("Name: %s", commandStyle.Render(data.InstanceName)),
 		fmt.Sprintf("Directory: %s", commandStyle.Render(fullInstanceName)),
 		fmt.Sprintf("WordPress Port (on host): %d", wordpressPort),
-		fmt.Sprintf("Suggested Dev Hostname: %s (Add to hosts file: 127.0.0.1 %s)", commandStyle.Render(data.InstanceName+devDomainSuffix), data.InstanceName+devDomainSuffix),
+		fmt.Sprintf("Stack: %s", describeStack(webServer, phpVersion)),
+		fmt.Sprintf("Database: %s", describeDB(dbEngine, dbVersion)),
+		fmt.Sprintf("Suggested Dev Hostname: %s (Run '%s' to add it to your hosts file)", commandStyle.Render(devHostName), commandStyle.Render("wpod hosts sync")),
 		fmt.Sprintf("Mailpit Web UI: http://0.0.0.0:%d (SMTP on port %d)", mailpitWebPort, mailpitSMTPPort),
 		fmt.Sprintf("Adminer Web UI: http://0.0.0.0:%d", adminerWebPort),
 		"",
 		lipgloss.NewStyle().Foreground(lipgloss.Color("14")).Render("Next steps:"),
 		fmt.Sprintf("  cd %s", commandStyle.Render(fullInstanceName)),
 		fmt.Sprintf("  Run: %s", commandStyle.Render("./manage start")),
-		fmt.Sprintf("  Access via browser: %s (if hosts/Caddy configured) or http://0.0.0.0:%d", commandStyle.Render(data.InstanceName+devDomainSuffix), wordpressPort),
+		fmt.Sprintf("  Access via browser: %s (if hosts/Caddy configured) or http://0.0.0.0:%d", commandStyle.Render(devHostName), wordpressPort),
 	}
-	if !caddyEnabled {
+	if proxyBackend == proxyBackendTraefik {
+		successDetails = append(successDetails, "", "Routed through the shared Traefik proxy. Start it with: "+commandStyle.Render("wpod proxy up"))
+	} else if !caddyEnabled {
 		successDetails = append(successDetails, "", lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("Caddy was not enabled. To start Caddy manually (if ports become free):"))
 		successDetails = append(successDetails, "  docker compose up -d caddy")
 		successDetails = append(successDetails, "  See docs/caddy.md for more info on manual reverse proxy setup.")
 	}
 	printSuccess("🎉 Instance Created Successfully!", successDetails...)
+	printPendingPostCreateSteps(blueprint.PostCreate)
 
 	if jsonOutput {
 		// Fix: use local variables instead of data.WordPressPort
@@ -3241,14 +3613,21 @@ func createInstanceWithJSON(data *InstanceCreateJSON) {
 			"name":              data.InstanceName,
 			"directory":         fullInstanceName,
 			"wordpress_port":    wordpressPort,
-			"dev_hostname":      data.InstanceName + devDomainSuffix,
+			"dev_hostname":      devHostName,
 			"mailpit_web_port":  mailpitWebPort,
 			"mailpit_smtp_port": mailpitSMTPPort,
 			"adminer_web_port":  adminerWebPort,
 			"caddy_enabled":     caddyEnabled,
+			"proxy_backend":     proxyBackend,
+			"template":          selectedTemplate.Dir,
+			"template_vars":     templateVars,
 			"caddy_http_port":   caddyHTTPPort,
 			"caddy_https_port":  caddyHTTPSPort,
 			"wordpress_version": wordpressVersion,
+			"php_version":       phpVersion,
+			"web_server":        webServer,
+			"db_engine":         dbEngine,
+			"db_version":        dbVersion,
 			"created":           time.Now().Format("2006-01-02 15:04:05"),
 			"location":          fullInstanceName,
 			"meta": map[string]interface{}{
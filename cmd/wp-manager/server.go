@@ -17,3 +17,362 @@
  */
 
 package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultServeListen is where 'wpod serve' listens unless --listen says otherwise. It is
+// loopback only; use --listen 0.0.0.0:9323 to let other machines scrape it.
+const defaultServeListen = "127.0.0.1:9323"
+
+// serveInstance is one instance in /status.json: the 'wpod list --format json' fields plus
+// the resource usage of 'wpod stats --json'.
+type serveInstance struct {
+	instanceListEntry
+	Resources *serveResources `json:"resources,omitempty"`
+}
+
+// serveResources is instanceStats with the disk fields left out, rather than reported as
+// 0, when the server runs with --no-disk.
+type serveResources struct {
+	instanceStats
+	DirectoryBytes *int64            `json:"directory_bytes,omitempty"`
+	VolumeBytes    *int64            `json:"volume_bytes,omitempty"`
+	Volumes        *map[string]int64 `json:"volumes,omitempty"`
+}
+
+func newServeResources(stats *instanceStats, withDisk bool) *serveResources {
+	if stats == nil {
+		return nil
+	}
+	r := &serveResources{instanceStats: *stats}
+	if withDisk {
+		r.DirectoryBytes, r.VolumeBytes, r.Volumes = &stats.DirectoryBytes, &stats.VolumeBytes, &stats.Volumes
+	}
+	return r
+}
+
+// serveSnapshot is what one collection round found. Handlers only read snapshots, so a
+// scrape never waits on docker.
+type serveSnapshot struct {
+	GeneratedAt string          `json:"generated_at"` // RFC 3339
+	DurationMS  int64           `json:"duration_ms"`
+	Errors      []string        `json:"errors"`
+	Instances   []serveInstance `json:"instances"`
+
+	generated time.Time
+	withDisk  bool
+}
+
+// serveCollector refreshes the snapshot in the background.
+type serveCollector struct {
+	interval time.Duration
+	withDisk bool
+
+	mu       sync.RWMutex
+	snapshot *serveSnapshot
+
+	disk   map[string]instanceDisk
+	diskAt time.Time
+}
+
+func (c *serveCollector) current() *serveSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snapshot
+}
+
+// run collects once right away and then every interval until ctx is done.
+func (c *serveCollector) run(ctx context.Context) {
+	for {
+		snapshot := c.collect()
+		c.mu.Lock()
+		c.snapshot = snapshot
+		c.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.interval):
+		}
+	}
+}
+
+func (c *serveCollector) collect() *serveSnapshot {
+	start := time.Now()
+	snapshot := &serveSnapshot{generated: start, withDisk: c.withDisk, Errors: []string{}, Instances: []serveInstance{}}
+	defer func() {
+		snapshot.GeneratedAt = start.Format(time.RFC3339)
+		snapshot.DurationMS = time.Since(start).Milliseconds()
+	}()
+
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		snapshot.Errors = append(snapshot.Errors, err.Error())
+		return snapshot
+	}
+	statuses := checkStatuses(managerMeta, defaultStatusWorkers, defaultStatusTimeout)
+
+	if c.withDisk && time.Since(c.diskAt) >= statsDiskRefresh {
+		var warning string
+		c.disk, warning = collectInstanceDisk(managerMeta)
+		c.diskAt = time.Now()
+		if warning != "" {
+			snapshot.Errors = append(snapshot.Errors, warning)
+		}
+	}
+	resources := make(map[string]*instanceStats)
+	if stats, errStats := collectInstanceStats(managerMeta, c.disk); errStats != nil {
+		snapshot.Errors = append(snapshot.Errors, errStats.Error())
+	} else {
+		for i := range stats {
+			resources[stats[i].Name] = &stats[i]
+		}
+	}
+
+	names := make([]string, 0, len(managerMeta))
+	for name := range managerMeta {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entry := newInstanceListEntry(name, managerMeta[name])
+		result := statuses[name]
+		entry.applyStatus(result)
+		if result.Err != nil {
+			snapshot.Errors = append(snapshot.Errors, fmt.Sprintf("%s: %v", name, result.Err))
+		}
+		snapshot.Instances = append(snapshot.Instances, serveInstance{instanceListEntry: entry, Resources: newServeResources(resources[name], c.withDisk)})
+	}
+	return snapshot
+}
+
+// serveCommand implements 'wpod serve [--listen addr] [--metrics] [--interval d] [--no-disk]'.
+func serveCommand(args []string) {
+	serveFlags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := serveFlags.String("listen", defaultServeListen, "TCP address to listen on")
+	metrics := serveFlags.Bool("metrics", false, "Expose Prometheus metrics on /metrics")
+	interval := serveFlags.Duration("interval", 15*time.Second, "How often instance state is collected from docker")
+	noDisk := serveFlags.Bool("no-disk", false, "Skip directory and volume sizes (they are refreshed once a minute)")
+	_ = serveFlags.Parse(args)
+	if *interval < time.Second {
+		*interval = time.Second
+	}
+
+	collector := &serveCollector{interval: *interval, withDisk: !*noDisk}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status.json", collector.serveStatusJSON)
+	endpoints := []string{"/status.json"}
+	if *metrics {
+		mux.HandleFunc("/metrics", collector.serveMetrics)
+		endpoints = append(endpoints, "/metrics")
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "wpod\n\n%s\n", strings.Join(endpoints, "\n"))
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go collector.run(ctx)
+
+	server := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	printSectionHeader("wpod Status Server")
+	for _, endpoint := range endpoints {
+		printInfo("Serving:", commandStyle.Render("http://"+*listen+endpoint))
+	}
+	printInfo(fmt.Sprintf("Instance state is collected every %s.", *interval), "Press Ctrl+C to stop.")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		printError("Status Server Failed", err.Error())
+		os.Exit(1)
+	}
+	printSuccess("Status server stopped.")
+}
+
+// ready returns the current snapshot, or answers 503 while the first round still runs.
+func (c *serveCollector) ready(w http.ResponseWriter) *serveSnapshot {
+	snapshot := c.current()
+	if snapshot == nil {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "collecting instance state, try again shortly", http.StatusServiceUnavailable)
+	}
+	return snapshot
+}
+
+func (c *serveCollector) serveStatusJSON(w http.ResponseWriter, r *http.Request) {
+	snapshot := c.ready(w)
+	if snapshot == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(snapshot)
+}
+
+func (c *serveCollector) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	snapshot := c.ready(w)
+	if snapshot == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, snapshot)
+}
+
+// Statuses and health values exported as state sets, so every series exists at 0 or 1.
+var (
+	metricStatuses = []string{statusRunning, statusDegraded, statusStopped, statusMissing, statusUnknown}
+	metricHealths  = []string{"healthy", "unhealthy", "starting"}
+)
+
+// writeMetrics renders a snapshot in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, s *serveSnapshot) {
+	m := &metricWriter{w: w}
+
+	m.family("wpod_instances", "gauge", "Registered instances.")
+	m.sample("wpod_instances", nil, float64(len(s.Instances)))
+
+	m.family("wpod_instance_up", "gauge", "1 if every service of the instance runs and none is unhealthy.")
+	for _, in := range s.Instances {
+		m.sample("wpod_instance_up", instanceLabels(in), boolMetric(in.Status == statusRunning))
+	}
+
+	m.family("wpod_instance_status", "gauge", "Instance status as a state set: 1 for the current status.")
+	for _, in := range s.Instances {
+		for _, status := range metricStatuses {
+			m.sample("wpod_instance_status", []string{"instance", in.Name, "status", strings.ToLower(status)}, boolMetric(in.Status == status))
+		}
+	}
+
+	m.family("wpod_container_up", "gauge", "1 if the compose service's container is running.")
+	for _, in := range s.Instances {
+		for _, svc := range in.Services {
+			m.sample("wpod_container_up", []string{"instance", in.Name, "service", svc.Service}, boolMetric(svc.State == "running"))
+		}
+	}
+
+	m.family("wpod_container_health", "gauge", "Healthcheck state of services that define one, as a state set.")
+	for _, in := range s.Instances {
+		for _, svc := range in.Services {
+			if svc.Health == "" {
+				continue
+			}
+			for _, health := range metricHealths {
+				m.sample("wpod_container_health", []string{"instance", in.Name, "service", svc.Service, "health", health}, boolMetric(svc.Health == health))
+			}
+		}
+	}
+
+	m.family("wpod_instance_port", "gauge", "Host ports assigned in the instance's .env.")
+	for _, in := range s.Instances {
+		names := make([]string, 0, len(in.Ports))
+		for name := range in.Ports {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m.sample("wpod_instance_port", []string{"instance", in.Name, "port", name}, float64(in.Ports[name]))
+		}
+	}
+
+	// Resource gauges exist only for instances 'docker stats' reported on. Each family is
+	// written in one block, as the exposition format requires.
+	resources := []struct {
+		name, help string
+		value      func(*instanceStats) float64
+	}{
+		{"wpod_instance_containers_running", "Running containers of the instance.", func(r *instanceStats) float64 { return float64(r.Containers) }},
+		{"wpod_instance_cpu_percent", "CPU use summed over the instance's containers; 100 is one core.", func(r *instanceStats) float64 { return r.CPUPercent }},
+		{"wpod_instance_memory_bytes", "Memory use summed over the instance's containers.", func(r *instanceStats) float64 { return float64(r.MemoryBytes) }},
+	}
+	for _, res := range resources {
+		m.family(res.name, "gauge", res.help)
+		for _, in := range s.Instances {
+			if in.Resources != nil {
+				m.sample(res.name, []string{"instance", in.Name}, res.value(&in.Resources.instanceStats))
+			}
+		}
+	}
+	if s.withDisk {
+		m.family("wpod_instance_disk_bytes", "gauge", "On-disk size of the instance directory and its named volumes.")
+		for _, in := range s.Instances {
+			if in.Resources != nil {
+				m.sample("wpod_instance_disk_bytes", []string{"instance", in.Name, "kind", "directory"}, float64(in.Resources.instanceStats.DirectoryBytes))
+				m.sample("wpod_instance_disk_bytes", []string{"instance", in.Name, "kind", "volumes"}, float64(in.Resources.instanceStats.VolumeBytes))
+			}
+		}
+	}
+
+	m.family("wpod_collect_timestamp_seconds", "gauge", "When the data was collected from docker.")
+	m.sample("wpod_collect_timestamp_seconds", nil, float64(s.generated.Unix()))
+	m.family("wpod_collect_duration_seconds", "gauge", "How long the last collection took.")
+	m.sample("wpod_collect_duration_seconds", nil, float64(s.DurationMS)/1000)
+	m.family("wpod_collect_errors", "gauge", "Problems during the last collection; details in /status.json.")
+	m.sample("wpod_collect_errors", nil, float64(len(s.Errors)))
+}
+
+func instanceLabels(in serveInstance) []string {
+	return []string{"instance", in.Name, "template", in.Template, "web_server", in.WebServer}
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricWriter writes exposition-format lines; labels are name/value pairs.
+type metricWriter struct{ w io.Writer }
+
+func (m *metricWriter) family(name, kind, help string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (m *metricWriter) sample(name string, labels []string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i] + `="` + escapeLabelValue(labels[i+1]) + `"`)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	b.WriteByte('\n')
+	_, _ = io.WriteString(m.w, b.String())
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string { return labelValueEscaper.Replace(v) }
@@ -0,0 +1,218 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/regiellis/wp-manager-cli/internal/compose"
+)
+
+const (
+	webServerApache   = "apache"
+	webServerNginxFPM = "nginx-fpm"
+
+	nginxImage       = "nginx:stable-alpine"
+	nginxConfigFile  = "config/nginx.conf"
+	containerDocRoot = "/var/www/html"
+)
+
+// supportedPHPVersions are offered by the create prompt; any X.Y the official wordpress
+// image publishes is accepted.
+var supportedPHPVersions = []string{"8.1", "8.2", "8.3", "8.4"}
+
+var phpVersionRe = regexp.MustCompile(`^\d+\.\d+$`)
+
+// normalizeWebServer accepts the documented values plus the obvious shorthands. An empty
+// value stays empty so callers can fall back to the global default.
+func normalizeWebServer(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return "", nil
+	case webServerApache, "apache2":
+		return webServerApache, nil
+	case webServerNginxFPM, "nginx", "fpm", "nginx+fpm":
+		return webServerNginxFPM, nil
+	}
+	return "", fmt.Errorf("'%s' must be '%s' or '%s'", value, webServerApache, webServerNginxFPM)
+}
+
+// validatePHPVersion checks a PHP_VERSION value; empty means "the image default".
+func validatePHPVersion(value string) error {
+	if value == "" || phpVersionRe.MatchString(value) {
+		return nil
+	}
+	return fmt.Errorf("'%s' is not a PHP version such as %s", value, strings.Join(supportedPHPVersions, ", "))
+}
+
+func configuredWebServer(config GlobalManagerConfig) string {
+	if config.WebServer == webServerNginxFPM {
+		return webServerNginxFPM
+	}
+	return webServerApache
+}
+
+// configuredPHPVersion returns the default PHP version for new instances; empty means the
+// PHP version the wordpress image ships by default.
+func configuredPHPVersion(config GlobalManagerConfig) string {
+	return config.PHPVersion
+}
+
+// instanceWebServer returns the stack an instance was created with; instances that predate
+// the option run Apache.
+func instanceWebServer(meta InstanceMeta) string {
+	if meta.WebServer == webServerNginxFPM {
+		return webServerNginxFPM
+	}
+	return webServerApache
+}
+
+// describeStack renders e.g. "apache, PHP 8.2" for listings.
+func describeStack(webServer, phpVersion string) string {
+	if phpVersion == "" {
+		phpVersion = "default"
+	}
+	return fmt.Sprintf("%s, PHP %s", webServer, phpVersion)
+}
+
+// wordpressImageTag builds the official wordpress image tag for a WordPress version, PHP
+// version and web server, e.g. "6.6-php8.2-fpm", "php8.3-apache" or "latest".
+func wordpressImageTag(wordpressVersion, phpVersion, webServer string) string {
+	var parts []string
+	if wordpressVersion != "" && wordpressVersion != "latest" {
+		parts = append(parts, wordpressVersion)
+	}
+	if phpVersion != "" {
+		parts = append(parts, "php"+phpVersion)
+	}
+	if webServer == webServerNginxFPM {
+		parts = append(parts, "fpm")
+	} else if len(parts) > 0 {
+		parts = append(parts, "apache")
+	}
+	if len(parts) == 0 {
+		return "latest"
+	}
+	return strings.Join(parts, "-")
+}
+
+// applyStackToEnv writes PHP_VERSION, WEB_SERVER and WORDPRESS_IMAGE_TAG into .env
+// content.
+func applyStackToEnv(content, wordpressVersion, phpVersion, webServer string) string {
+	values := [][2]string{
+		{"PHP_VERSION", phpVersion},
+		{"WEB_SERVER", webServer},
+		{"WORDPRESS_IMAGE_TAG", wordpressImageTag(wordpressVersion, phpVersion, webServer)},
+	}
+	for _, kv := range values {
+		content = setOrAppendEnvValue(content, kv[0], kv[1])
+	}
+	return content
+}
+
+// applyNginxFPMToCompose turns the wordpress service into a php-fpm backend and adds an
+// nginx service that publishes the WordPress port instead. It is a no-op if the compose
+// file already has an nginx service.
+func applyNginxFPMToCompose(content string) (string, error) {
+	file, err := compose.Parse([]byte(content))
+	if err != nil {
+		return content, err
+	}
+	if file.HasService("nginx") {
+		return content, nil
+	}
+	wordpress := file.Service("wordpress")
+	if wordpress == nil {
+		return content, fmt.Errorf("no wordpress service found in docker-compose.yml")
+	}
+
+	// nginx needs the document root (read-only) to serve static files.
+	var volumes []string
+	for _, item := range wordpress.Volumes() {
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || !strings.HasPrefix(parts[1], containerDocRoot) {
+			continue
+		}
+		if len(parts) == 2 {
+			item += ":ro"
+		}
+		volumes = append(volumes, item)
+	}
+	volumes = append(volumes, fmt.Sprintf("./%s:/etc/nginx/conf.d/default.conf:ro", nginxConfigFile))
+
+	nginx := file.AddService("nginx")
+	nginx.Set("image", nginxImage)
+	nginx.Set("container_name", "wordpress_nginx_${WORDPRESS_CONTAINER_NAME}")
+	nginx.Set("restart", "unless-stopped")
+	nginx.SetList("volumes", volumes, false)
+	// Move the published port from wordpress (php-fpm speaks FastCGI, not HTTP) to nginx.
+	if nginx.CopyFrom(wordpress, "ports") {
+		wordpress.Delete("ports")
+	}
+	nginx.CopyFrom(wordpress, "networks")
+	nginx.AddToList("depends_on", "wordpress")
+
+	out, err := file.Bytes()
+	if err != nil {
+		return content, err
+	}
+	return string(out), nil
+}
+
+// configureInstanceWebServer adapts a freshly copied instance to its web server. Apache is
+// what the templates ship, so only nginx-fpm needs changes: the nginx config (taken from
+// the default template if the instance's template has none) and the compose services.
+func configureInstanceWebServer(instanceDir string, t templateInfo, webServer string) error {
+	if webServer != webServerNginxFPM {
+		return nil
+	}
+	confPath := filepath.Join(instanceDir, filepath.FromSlash(nginxConfigFile))
+	if _, err := os.Stat(confPath); err != nil {
+		data, errRead := readTemplateFile(t, nginxConfigFile)
+		if errRead != nil {
+			return fmt.Errorf("read %s: %w", nginxConfigFile, errRead)
+		}
+		if err := os.MkdirAll(filepath.Dir(confPath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(confPath, data, 0644); err != nil {
+			return fmt.Errorf("write %s: %w", nginxConfigFile, err)
+		}
+	}
+
+	composePath := filepath.Join(instanceDir, "docker-compose.yml")
+	content, err := os.ReadFile(composePath)
+	if err != nil {
+		return fmt.Errorf("read docker-compose.yml: %w", err)
+	}
+	patched, err := applyNginxFPMToCompose(string(content))
+	if err != nil {
+		return err
+	}
+	if patched != string(content) {
+		if err := os.WriteFile(composePath, []byte(patched), 0644); err != nil {
+			return fmt.Errorf("write docker-compose.yml: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,514 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// statsDiskRefresh limits how often --watch walks instance directories and asks Docker
+// for volume sizes; both are slow compared to 'docker stats'.
+const statsDiskRefresh = time.Minute
+
+// instanceStats is one instance in 'wpod stats' output. The JSON field names are
+// documented in docs/cli.md.
+type instanceStats struct {
+	Name           string           `json:"name"`
+	Directory      string           `json:"directory"`
+	Containers     int              `json:"containers"`  // running containers
+	CPUPercent     float64          `json:"cpu_percent"` // summed over containers; 100 is one full core
+	MemoryBytes    int64            `json:"memory_bytes"`
+	MemoryPercent  float64          `json:"memory_percent"` // of the memory available to Docker
+	DirectoryBytes int64            `json:"directory_bytes"`
+	VolumeBytes    int64            `json:"volume_bytes"`
+	Volumes        map[string]int64 `json:"volumes"` // named volume -> bytes
+}
+
+func (s instanceStats) diskBytes() int64 { return s.DirectoryBytes + s.VolumeBytes }
+
+// statsSample is one --watch --json line.
+type statsSample struct {
+	Timestamp string          `json:"timestamp"`
+	Instances []instanceStats `json:"instances"`
+}
+
+// instanceDisk is the cached on-disk part of instanceStats.
+type instanceDisk struct {
+	directory int64
+	volumes   map[string]int64
+}
+
+// dockerStatsEntry is the part of a 'docker stats --format json' record wpod uses.
+type dockerStatsEntry struct {
+	ID       string `json:"ID"`
+	CPUPerc  string `json:"CPUPerc"`
+	MemUsage string `json:"MemUsage"`
+	MemPerc  string `json:"MemPerc"`
+}
+
+// dockerVolumeUsage is one volume in 'docker system df -v'.
+type dockerVolumeUsage struct {
+	Name   string `json:"Name"`
+	Labels string `json:"Labels"`
+	Size   string `json:"Size"`
+}
+
+// statsCommand implements 'wpod stats [name...] [--json] [--watch] [--interval d] [--sort key]'.
+func statsCommand(args []string) {
+	statsFlags := flag.NewFlagSet("stats", flag.ExitOnError)
+	jsonOut := statsFlags.Bool("json", false, "Print JSON (one line per sample with --watch)")
+	watch := statsFlags.Bool("watch", false, "Refresh until interrupted")
+	interval := statsFlags.Duration("interval", 2*time.Second, "Refresh interval for --watch")
+	sortBy := statsFlags.String("sort", "memory", "Sort by memory, cpu, disk or name")
+	_ = statsFlags.Parse(reorderFlags(args, "interval", "sort"))
+
+	fail := func(title string, details ...string) {
+		if *jsonOut {
+			fmt.Fprintln(os.Stderr, "wpod stats: "+title+": "+strings.Join(details, " "))
+		} else {
+			printError(title, details...)
+		}
+		os.Exit(1)
+	}
+
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		fail("Failed to Read Manager Metadata", err.Error())
+	}
+	selected := make(map[string]InstanceMeta)
+	for _, name := range statsFlags.Args() {
+		key, meta, ok := lookupInstance(managerMeta, name)
+		if !ok {
+			fail("Instance Not Found", fmt.Sprintf("No registered instance named '%s'.", name))
+		}
+		selected[key] = meta
+	}
+	if statsFlags.NArg() == 0 {
+		selected = managerMeta
+	}
+	if len(selected) == 0 {
+		fail("No Instances Found", "No instances registered with the manager.")
+	}
+	if *interval < 500*time.Millisecond {
+		*interval = 500 * time.Millisecond
+	}
+
+	disk := make(map[string]instanceDisk)
+	var diskAt time.Time
+	for {
+		if time.Since(diskAt) >= statsDiskRefresh {
+			var warning string
+			disk, warning = collectInstanceDisk(selected)
+			diskAt = time.Now()
+			if warning != "" && !*jsonOut {
+				printWarning("Volume Sizes Unavailable", warning)
+			}
+		}
+		stats, err := collectInstanceStats(selected, disk)
+		if err != nil && !*watch {
+			fail("Failed to Read Docker Stats", err.Error())
+		}
+		if err != nil {
+			// A container removed between 'docker ps' and 'docker stats' fails the whole
+			// sample; the next tick sees the new container list.
+			if *jsonOut {
+				fmt.Fprintln(os.Stderr, "wpod stats: Failed to Read Docker Stats: "+err.Error())
+			} else {
+				printWarning("Failed to Read Docker Stats", err.Error(), fmt.Sprintf("Retrying in %s.", *interval))
+			}
+			time.Sleep(*interval)
+			continue
+		}
+		if err := sortInstanceStats(stats, strings.ToLower(*sortBy)); err != nil {
+			fail("Invalid Sort", err.Error())
+		}
+
+		switch {
+		case *jsonOut && *watch:
+			line, _ := json.Marshal(statsSample{Timestamp: time.Now().Format(time.RFC3339), Instances: stats})
+			fmt.Println(string(line))
+		case *jsonOut:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(stats)
+		default:
+			if *watch {
+				fmt.Print("\033[H\033[2J") // clear the screen between samples
+				fmt.Println(subtleStyle.Render(fmt.Sprintf("wpod stats, every %s (Ctrl+C to stop)  %s", *interval, time.Now().Format("15:04:05"))))
+			}
+			renderInstanceStats(os.Stdout, stats)
+		}
+		if !*watch {
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// reorderFlags moves flags in front of instance names so 'wpod stats mysite --json' works
+// like 'wpod stats --json mysite'. valueFlags names the flags that take the next argument.
+func reorderFlags(args []string, valueFlags ...string) []string {
+	var flags, names []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			names = append(names, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		if slices.Contains(valueFlags, strings.TrimLeft(arg, "-")) && i+1 < len(args) {
+			flags = append(flags, args[i+1])
+			i++
+		}
+	}
+	return append(flags, names...)
+}
+
+// collectInstanceStats samples 'docker stats' once for every running container of the
+// selected instances and sums the numbers per instance.
+func collectInstanceStats(instances map[string]InstanceMeta, disk map[string]instanceDisk) ([]instanceStats, error) {
+	owners, err := composeContainerOwners(instances)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*instanceStats, len(instances))
+	stats := make([]instanceStats, 0, len(instances))
+	for name, meta := range instances {
+		d := disk[name]
+		volumes := d.volumes
+		if volumes == nil {
+			volumes = map[string]int64{}
+		}
+		var volumeBytes int64
+		for _, size := range volumes {
+			volumeBytes += size
+		}
+		stats = append(stats, instanceStats{Name: name, Directory: meta.Directory, DirectoryBytes: d.directory, VolumeBytes: volumeBytes, Volumes: volumes})
+	}
+	for i := range stats {
+		byName[stats[i].Name] = &stats[i]
+	}
+	if len(owners) == 0 {
+		return stats, nil
+	}
+
+	ids := make([]string, 0, len(owners))
+	for id := range owners {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	out, err := dockerStdout(append([]string{"stats", "--no-stream", "--format", "{{json .}}"}, ids...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var entry dockerStatsEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("parse docker stats output: %w", err)
+		}
+		s := byName[owners[shortContainerID(entry.ID)]]
+		if s == nil {
+			continue
+		}
+		s.Containers++
+		s.CPUPercent += parsePercent(entry.CPUPerc)
+		s.MemoryPercent += parsePercent(entry.MemPerc)
+		used, _, _ := strings.Cut(entry.MemUsage, "/")
+		if n, ok := parseDockerSize(used); ok {
+			s.MemoryBytes += n
+		}
+	}
+	// Summing percentages adds float noise; two decimals is what docker reports anyway.
+	for i := range stats {
+		stats[i].CPUPercent = math.Round(stats[i].CPUPercent*100) / 100
+		stats[i].MemoryPercent = math.Round(stats[i].MemoryPercent*100) / 100
+	}
+	return stats, nil
+}
+
+// composeContainerOwners maps the short ID of every running compose container to the
+// instance it belongs to, matched by the compose working directory label.
+func composeContainerOwners(instances map[string]InstanceMeta) (map[string]string, error) {
+	dirs := make(map[string]string, len(instances))
+	for name, meta := range instances {
+		dirs[canonicalDir(meta.Directory)] = name
+	}
+	out, err := dockerStdout("ps", "--filter", "label=com.docker.compose.project",
+		"--format", `{{.ID}}	{{.Label "com.docker.compose.project.working_dir"}}`)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		id, dir, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		if name, found := dirs[canonicalDir(dir)]; found {
+			owners[shortContainerID(id)] = name
+		}
+	}
+	return owners, nil
+}
+
+// collectInstanceDisk measures each instance directory and its named volumes. Volume sizes
+// come from 'docker system df -v'; when that fails the warning says why.
+func collectInstanceDisk(instances map[string]InstanceMeta) (map[string]instanceDisk, string) {
+	disk := make(map[string]instanceDisk, len(instances))
+	projects := make(map[string]string, len(instances))
+	for name, meta := range instances {
+		disk[name] = instanceDisk{directory: directorySize(meta.Directory), volumes: map[string]int64{}}
+		projects[instanceProjectName(meta.Directory)] = name
+	}
+
+	out, err := dockerStdout("system", "df", "-v", "--format", "{{json .Volumes}}")
+	if err != nil {
+		return disk, err.Error()
+	}
+	var volumes []dockerVolumeUsage
+	if err := json.Unmarshal(bytes.TrimSpace(out), &volumes); err != nil {
+		return disk, fmt.Sprintf("parse docker system df output: %v", err)
+	}
+	for _, v := range volumes {
+		name, ok := projects[volumeLabel(v.Labels, "com.docker.compose.project")]
+		if !ok {
+			continue
+		}
+		if size, ok := parseDockerSize(v.Size); ok {
+			disk[name].volumes[v.Name] = size
+		}
+	}
+	return disk, ""
+}
+
+// instanceProjectName mirrors docker compose's default project name: COMPOSE_PROJECT_NAME
+// from .env, otherwise the lowercased directory name.
+func instanceProjectName(instanceDir string) string {
+	if data, err := os.ReadFile(filepath.Join(instanceDir, ".env")); err == nil {
+		if name := parseEnvContent(string(data))["COMPOSE_PROJECT_NAME"]; name != "" {
+			return name
+		}
+	}
+	name := strings.ToLower(filepath.Base(instanceDir))
+	return projectNameInvalidRe.ReplaceAllString(name, "")
+}
+
+// projectNameInvalidRe matches the characters docker compose drops from project names.
+var projectNameInvalidRe = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// volumeLabel picks one value out of docker's "k=v,k=v" label list.
+func volumeLabel(labels, key string) string {
+	for _, pair := range strings.Split(labels, ",") {
+		if k, v, ok := strings.Cut(pair, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// directorySize sums the size of the regular files below dir. Files it may not read (a
+// database directory owned by the container user, say) are skipped.
+func directorySize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, errInfo := d.Info(); errInfo == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+func canonicalDir(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return filepath.Clean(dir)
+}
+
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// dockerStdout runs docker and returns stdout, folding stderr into the error.
+func dockerStdout(args ...string) ([]byte, error) {
+	cmd := exec.Command("docker", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func parsePercent(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	return v
+}
+
+// dockerSizeUnits covers both unit styles docker prints: binary (MiB) in 'docker stats'
+// and decimal (MB) in 'docker system df'.
+var dockerSizeUnits = map[string]float64{
+	"b":  1,
+	"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+}
+
+var dockerSizeRe = regexp.MustCompile(`^([0-9.]+)\s*([a-zA-Z]*)$`)
+
+// parseDockerSize reads sizes such as "123.4MiB", "1.2GB" or "0B".
+func parseDockerSize(s string) (int64, bool) {
+	match := dockerSizeRe.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	unit := strings.ToLower(match[2])
+	if unit == "" {
+		unit = "b"
+	}
+	factor, ok := dockerSizeUnits[unit]
+	if !ok {
+		return 0, false
+	}
+	return int64(value * factor), true
+}
+
+// formatByteSize renders bytes with binary units, e.g. "512.0 MiB".
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func sortInstanceStats(stats []instanceStats, by string) error {
+	var less func(a, b instanceStats) bool
+	switch by {
+	case "memory", "mem":
+		less = func(a, b instanceStats) bool { return a.MemoryBytes > b.MemoryBytes }
+	case "cpu":
+		less = func(a, b instanceStats) bool { return a.CPUPercent > b.CPUPercent }
+	case "disk":
+		less = func(a, b instanceStats) bool { return a.diskBytes() > b.diskBytes() }
+	case "name":
+		less = func(a, b instanceStats) bool { return a.Name < b.Name }
+	default:
+		return fmt.Errorf("unknown sort key '%s' (use memory, cpu, disk or name)", by)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if less(stats[i], stats[j]) != less(stats[j], stats[i]) {
+			return less(stats[i], stats[j])
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return nil
+}
+
+func renderInstanceStats(w io.Writer, stats []instanceStats) {
+	nameWidth := 30
+	numWidth := 12
+
+	header := lipgloss.JoinHorizontal(lipgloss.Top,
+		tableHeaderStyle.Width(nameWidth).Render("Instance Name"),
+		tableHeaderStyle.Width(numWidth).Render("Containers"),
+		tableHeaderStyle.Width(numWidth).Render("CPU %"),
+		tableHeaderStyle.Width(numWidth).Render("Memory"),
+		tableHeaderStyle.Width(numWidth).Render("Mem %"),
+		tableHeaderStyle.Width(numWidth).Render("Directory"),
+		tableHeaderStyle.Width(numWidth).Render("Volumes"),
+		tableHeaderStyle.Width(numWidth).Render("Disk Total"),
+	)
+	rows := []string{header}
+	var total instanceStats
+	for _, s := range stats {
+		rows = append(rows, statsRow(nameWidth, numWidth, tableCellStyle, s.Name, s))
+		total.Containers += s.Containers
+		total.CPUPercent += s.CPUPercent
+		total.MemoryBytes += s.MemoryBytes
+		total.MemoryPercent += s.MemoryPercent
+		total.DirectoryBytes += s.DirectoryBytes
+		total.VolumeBytes += s.VolumeBytes
+	}
+	if len(stats) > 1 {
+		rows = append(rows, statsRow(nameWidth, numWidth, tableHeaderStyle, "Total", total))
+	}
+	fmt.Fprintln(w, tableBorderStyle.Render(lipgloss.JoinVertical(lipgloss.Left, rows...)))
+}
+
+func statsRow(nameWidth, numWidth int, style lipgloss.Style, name string, s instanceStats) string {
+	memory, cpu, memPct := "-", "-", "-"
+	if s.Containers > 0 {
+		memory = formatByteSize(s.MemoryBytes)
+		cpu = fmt.Sprintf("%.1f", s.CPUPercent)
+		memPct = fmt.Sprintf("%.1f", s.MemoryPercent)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		style.Width(nameWidth).Render(name),
+		style.Width(numWidth).Render(strconv.Itoa(s.Containers)),
+		style.Width(numWidth).Render(cpu),
+		style.Width(numWidth).Render(memory),
+		style.Width(numWidth).Render(memPct),
+		style.Width(numWidth).Render(formatByteSize(s.DirectoryBytes)),
+		style.Width(numWidth).Render(formatByteSize(s.VolumeBytes)),
+		style.Width(numWidth).Render(formatByteSize(s.diskBytes())),
+	)
+}
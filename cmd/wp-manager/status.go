@@ -0,0 +1,284 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/regiellis/wp-manager-cli/internal/compose"
+)
+
+// Overall instance states, derived from the state of each compose service.
+const (
+	statusRunning  = "Running"  // every expected service is up and none is unhealthy
+	statusDegraded = "Degraded" // something runs, but a service is down, restarting or unhealthy
+	statusStopped  = "Stopped"  // no service is running
+	statusMissing  = "Missing"  // the instance directory or its compose file is gone
+	statusUnknown  = "Unknown"  // docker could not be asked
+
+	// serviceStateMissing marks an expected service that has no container at all.
+	serviceStateMissing = "missing"
+)
+
+// ServiceStatus is the state of one compose service as reported by 'docker compose ps'.
+type ServiceStatus struct {
+	Service  string `json:"service" yaml:"service"`
+	State    string `json:"state" yaml:"state"`                       // running, exited, restarting, created, paused, dead or missing
+	Health   string `json:"health,omitempty" yaml:"health,omitempty"` // healthy, unhealthy or starting; empty without a healthcheck
+	ExitCode int    `json:"exit_code,omitempty" yaml:"exit_code,omitempty"`
+}
+
+// composePSEntry is the part of a 'docker compose ps --format json' record wpod uses.
+type composePSEntry struct {
+	Service  string `json:"Service"`
+	State    string `json:"State"`
+	Health   string `json:"Health"`
+	ExitCode int    `json:"ExitCode"`
+}
+
+// parseComposePS reads 'docker compose ps --format json' output. Compose before 2.21
+// prints one JSON array, later versions one object per line.
+func parseComposePS(out []byte) ([]composePSEntry, error) {
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, nil
+	}
+	var entries []composePSEntry
+	if out[0] == '[' {
+		if err := json.Unmarshal(out, &entries); err != nil {
+			return nil, fmt.Errorf("parse docker compose ps output: %w", err)
+		}
+		return entries, nil
+	}
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var entry composePSEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("parse docker compose ps output: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// expectedServices lists the services 'docker compose up' starts: those without a profile.
+func expectedServices(composePath string) []string {
+	file, err := compose.Load(composePath)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, name := range file.Services() {
+		if len(file.Service(name).Profiles()) == 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// instanceStatus asks docker compose for the state of every service of an instance and
+// derives the overall status. Expected services without a container are reported as
+// missing; services from inactive profiles only show up once they have a container.
+func instanceStatus(instanceDir string) (string, []ServiceStatus, error) {
+	return instanceStatusContext(context.Background(), instanceDir)
+}
+
+// instanceStatusContext is instanceStatus with a context that bounds the docker call.
+func instanceStatusContext(ctx context.Context, instanceDir string) (string, []ServiceStatus, error) {
+	composePath := filepath.Join(instanceDir, "docker-compose.yml")
+	if _, err := os.Stat(composePath); err != nil {
+		return statusMissing, nil, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "docker", "compose", "ps", "--all", "--format", "json")
+	cmd.Dir = instanceDir
+	// docker runs compose as a plugin process that survives the kill; don't wait on its pipes.
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return statusUnknown, nil, fmt.Errorf("docker compose ps: %w", ctx.Err())
+	}
+	if err != nil {
+		return statusUnknown, nil, fmt.Errorf("docker compose ps: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	entries, err := parseComposePS(out)
+	if err != nil {
+		return statusUnknown, nil, err
+	}
+
+	byService := make(map[string]ServiceStatus, len(entries))
+	for _, e := range entries {
+		byService[e.Service] = ServiceStatus{Service: e.Service, State: strings.ToLower(e.State), Health: strings.ToLower(e.Health), ExitCode: e.ExitCode}
+	}
+	var services []ServiceStatus
+	seen := make(map[string]bool)
+	for _, name := range expectedServices(composePath) {
+		s, ok := byService[name]
+		if !ok {
+			s = ServiceStatus{Service: name, State: serviceStateMissing}
+		}
+		services = append(services, s)
+		seen[name] = true
+	}
+	for _, e := range entries {
+		if !seen[e.Service] {
+			services = append(services, byService[e.Service])
+			seen[e.Service] = true
+		}
+	}
+	return deriveStatus(services), services, nil
+}
+
+// statusCheck is the outcome of one instance's check in checkStatuses.
+type statusCheck struct {
+	Status   string
+	Services []ServiceStatus
+	Err      error
+	TimedOut bool
+}
+
+// checkStatuses runs instanceStatus for many instances, at most workers at a time and each
+// bounded by timeout, so one stalled docker call can't hold up the others.
+func checkStatuses(instances ManagerMeta, workers int, timeout time.Duration) map[string]statusCheck {
+	type job struct{ name, dir string }
+	jobs := make(chan job)
+	results := make(map[string]statusCheck, len(instances))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range max(min(workers, len(instances)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				status, services, err := instanceStatusContext(ctx, j.dir)
+				result := statusCheck{Status: status, Services: services, Err: err, TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded)}
+				cancel()
+				mu.Lock()
+				results[j.name] = result
+				mu.Unlock()
+			}
+		}()
+	}
+	for name, meta := range instances {
+		jobs <- job{name, meta.Directory}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// writeLocalStatus records a status in the instance's own meta file, keeping its other
+// fields. A missing instance directory is not an error.
+func writeLocalStatus(instanceDir, status string, services []ServiceStatus, checkedAt string) error {
+	if _, err := os.Stat(instanceDir); err != nil {
+		return nil
+	}
+	localMeta, err := readInstanceMeta(instanceDir)
+	if err != nil {
+		return err
+	}
+	localMeta.Status = status
+	localMeta.Services = services
+	localMeta.StatusCheckedAt = checkedAt
+	return writeInstanceMeta(instanceDir, localMeta)
+}
+
+// deriveStatus folds per-service states into Running, Degraded or Stopped.
+func deriveStatus(services []ServiceStatus) string {
+	running, problems := 0, 0
+	for _, s := range services {
+		if s.State == "running" {
+			running++
+		}
+		if !s.ok() {
+			problems++
+		}
+	}
+	switch {
+	case running == 0:
+		return statusStopped
+	case problems > 0:
+		return statusDegraded
+	default:
+		return statusRunning
+	}
+}
+
+// ok reports whether the service is up and not failing its healthcheck. A healthcheck
+// that is still starting counts as up.
+func (s ServiceStatus) ok() bool {
+	return s.State == "running" && s.Health != "unhealthy"
+}
+
+// describe renders e.g. "running (healthy)", "exited (1)" or "missing".
+func (s ServiceStatus) describe() string {
+	switch {
+	case s.State == "running" && s.Health != "":
+		return fmt.Sprintf("running (%s)", s.Health)
+	case s.State == "exited" || s.State == "dead":
+		return fmt.Sprintf("%s (%d)", s.State, s.ExitCode)
+	default:
+		return s.State
+	}
+}
+
+// renderServiceStatuses formats per-service detail lines for listings.
+func renderServiceStatuses(services []ServiceStatus, indent string) []string {
+	lines := make([]string, 0, len(services))
+	for _, s := range services {
+		state := statusRunningStyle.Render(s.describe())
+		if !s.ok() {
+			state = statusErrorStyle.Render(s.describe())
+			if (s.State == "exited" && s.ExitCode == 0) || s.State == "created" {
+				state = statusStoppedStyle.Render(s.describe())
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s%-14s %s", indent, s.Service, state))
+	}
+	return lines
+}
+
+// sameServiceStatuses reports whether two service lists describe the same state.
+func sameServiceStatuses(a, b []ServiceStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
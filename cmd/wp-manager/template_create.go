@@ -0,0 +1,409 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const seedFileName = "db/seed.sql"
+
+// templateCreateSkip lists instance paths (slash separated) that never go into a template:
+// runtime state, credentials, WordPress core, media and the manage binary.
+var templateCreateSkip = []string{
+	".env", ".env-template", metaFileName, blueprintFileName, "manage", "manage.exe",
+	".git", "backups", "node_modules", "wordpress", "config/Caddyfile",
+	"wp-content/uploads", "wp-content/cache", "wp-content/upgrade",
+}
+
+// instanceSpecificEnvKeys are filled by 'wpod create' for every new instance, so their
+// values in the source instance are dropped from the generated env-template.
+var instanceSpecificEnvKeys = []string{
+	"WORDPRESS_VERSION", "WORDPRESS_CONTAINER_NAME", "WORDPRESS_PORT", "WORDPRESS_URL", "PRODUCTION_URL",
+	"MYSQL_USER", "MYSQL_DATABASE", "WORDPRESS_DB_HOST", "WORDPRESS_DB_USER", "WORDPRESS_DB_NAME",
+	"MAILPIT_PORT_SMTP", "MAILPIT_PORT_WEB", "ADMINER_PORT", "CADDY_HTTP_PORT", "CADDY_HTTPS_PORT",
+	"TRAEFIK_HTTP_PORT", "TRAEFIK_HTTPS_PORT", "TRAEFIK_DASHBOARD_PORT",
+	"PHP_VERSION", "WEB_SERVER", "WORDPRESS_IMAGE_TAG", "DB_ENGINE", "DB_VERSION", "DB_IMAGE",
+}
+
+var (
+	secretEnvKeyRe = regexp.MustCompile(`PASSWORD|PASSWD|SECRET|TOKEN|_KEY$|_SALT$|^AUTH`)
+	envLineRe      = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+	tablePrefixRe  = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// templateCreate implements 'wpod template create --from-instance <name>'.
+func templateCreate(args []string) {
+	createFlags := flag.NewFlagSet("template create", flag.ExitOnError)
+	fromInstance := createFlags.String("from-instance", "", "Registered instance to turn into a template")
+	dirName := createFlags.String("name", "", "Template directory name (default: the instance name)")
+	title := createFlags.String("title", "", "Template display name for blueprint.json")
+	description := createFlags.String("description", "", "Template description for blueprint.json")
+	withDB := createFlags.Bool("with-db", false, "Include a sanitized database dump as "+seedFileName)
+	pinVersions := createFlags.Bool("pin-versions", false, "Pin plugin and theme versions in blueprint.json")
+	force := createFlags.Bool("force", false, "Replace an existing user template with the same name")
+	_ = createFlags.Parse(args)
+
+	printSectionHeader("Create Template From Instance")
+	if *fromInstance == "" {
+		printError("Instance Required", "Usage: wpod template create --from-instance <name> [--name <dir>] [--with-db] [--pin-versions] [--force]")
+		os.Exit(1)
+	}
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		printError("Failed to Read Manager Metadata", err.Error())
+		os.Exit(1)
+	}
+	instanceKey, meta, ok := lookupInstance(managerMeta, *fromInstance)
+	if !ok {
+		printError("Not Found", fmt.Sprintf("Instance '%s' is not registered.", *fromInstance))
+		os.Exit(1)
+	}
+	instanceDir := meta.Directory
+
+	name := *dirName
+	if name == "" {
+		name = instanceNameBase(instanceKey)
+	}
+	if _, errSan := sanitizeInstanceName(name); errSan != nil || strings.ContainsAny(name, `/\`) {
+		printError("Invalid Template Name", fmt.Sprintf("'%s' cannot be used as a directory name.", name))
+		os.Exit(1)
+	}
+	config, _ := readGlobalManagerConfig()
+	templatesDir, err := configuredTemplatesDir(config)
+	if err != nil {
+		printError("Could not determine template directory", err.Error())
+		os.Exit(1)
+	}
+	targetDir := filepath.Join(templatesDir, name)
+	if _, err := os.Stat(targetDir); err == nil {
+		if !*force {
+			printError("Template Exists", fmt.Sprintf("%s already exists.", targetDir), "Use --force to replace it or --name to pick another name.")
+			os.Exit(1)
+		}
+		if err := os.RemoveAll(targetDir); err != nil {
+			printError("Could not remove existing template", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	envContent, err := os.ReadFile(filepath.Join(instanceDir, ".env"))
+	if err != nil {
+		printError("Could not read the instance .env", err.Error())
+		os.Exit(1)
+	}
+	bp, err := readBlueprint(os.DirFS(instanceDir))
+	if err != nil && meta.Template != "" {
+		if t, errFind := findTemplate(meta.Template); errFind == nil {
+			bp, err = readBlueprint(t.FS)
+		}
+	}
+	if err != nil {
+		bp = &Blueprint{Folders: []string{"wp-content", "db", "config"}}
+	}
+
+	printInfo("Copying instance files...", fmt.Sprintf("%s -> %s", shortenPath(instanceDir, 60), shortenPath(targetDir, 60)))
+	copied, err := copyInstanceForTemplate(instanceDir, targetDir)
+	if err != nil {
+		printError("Copy Failed", err.Error())
+		os.RemoveAll(targetDir)
+		os.Exit(1)
+	}
+	printSuccess(fmt.Sprintf("Copied %d file(s)", copied), "Skipped: "+strings.Join(templateCreateSkip, ", "))
+
+	envTemplate, stripped := instanceEnvToTemplate(string(envContent), bp)
+	if err := os.WriteFile(filepath.Join(targetDir, envTemplateFileName), []byte(envTemplate), 0644); err != nil {
+		printError("Could not write env-template", err.Error())
+		os.RemoveAll(targetDir)
+		os.Exit(1)
+	}
+	printSuccess("env-template written", fmt.Sprintf("Cleared %d secret or instance-specific value(s).", len(stripped)))
+
+	bp.Schema = blueprintSchemaVersion
+	bp.Name = *title
+	if bp.Name == "" {
+		bp.Name = instanceNameBase(instanceKey) + " starter"
+	}
+	bp.Description = *description
+	if bp.Description == "" {
+		bp.Description = "Created from instance " + instanceKey + "."
+	}
+	plugins, themes, errPackages := instanceActivePackages(instanceDir, *pinVersions)
+	if errPackages != nil {
+		printWarning("Could not read active plugins and themes", errPackages.Error(), "Start the instance to capture them; the source blueprint's lists were kept.")
+	} else {
+		bp.Plugins, bp.Themes = plugins, themes
+		printSuccess("Captured active plugins and themes", fmt.Sprintf("%d plugin(s), %d theme(s)", len(plugins), len(themes)))
+	}
+
+	if *withDB {
+		prefix := parseEnvValue(envContent, "WORDPRESS_TABLE_PREFIX")
+		if prefix == "" {
+			prefix = "wp_"
+		}
+		seed, errSeed := exportSanitizedSeed(instanceDir, prefix)
+		if errSeed == nil {
+			errSeed = os.MkdirAll(filepath.Join(targetDir, "db"), 0755)
+		}
+		if errSeed == nil {
+			errSeed = os.WriteFile(filepath.Join(targetDir, filepath.FromSlash(seedFileName)), seed, 0644)
+		}
+		if errSeed != nil {
+			printWarning("Seed database not included", errSeed.Error())
+		} else {
+			printSuccess("Sanitized seed database written to "+seedFileName,
+				"User passwords, e-mail addresses, sessions and transients were removed.",
+				"Import it in a new instance with "+commandStyle.Render("./manage db")+" → Import.")
+		}
+	}
+
+	data, err := json.MarshalIndent(bp, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(targetDir, blueprintFileName), append(data, '\n'), 0644)
+	}
+	if err != nil {
+		printError("Could not write blueprint.json", err.Error())
+		os.RemoveAll(targetDir)
+		os.Exit(1)
+	}
+
+	for _, issue := range validateTemplate(os.DirFS(targetDir)) {
+		printWarning("Template check: " + issue.Message)
+	}
+	if instanceWebServer(meta) == webServerNginxFPM {
+		printWarning("The instance runs nginx + php-fpm.", "Its docker-compose.yml has the nginx service and no published port on wordpress; restore the Apache layout in the template so 'wpod create' can choose the web server.")
+	}
+	if meta.ProxyBackend == proxyBackendTraefik {
+		printWarning("The instance is routed through Traefik.", "Its docker-compose.yml carries Traefik labels for "+instanceKey+"; remove them from the template so new instances get their own routes.")
+	}
+	printSuccess("Template Created", fmt.Sprintf("Name: %s", commandStyle.Render(name)), fmt.Sprintf("Location: %s", targetDir),
+		"Use it with "+commandStyle.Render("wpod create")+" or "+commandStyle.Render(fmt.Sprintf(`wpod --json '{"template": "%s", ...}'`, name)))
+}
+
+// templateCreateSkipped reports whether a relative instance path stays out of the template.
+// Files below db/ are skipped as well since they are usually database dumps.
+func templateCreateSkipped(rel string) bool {
+	if strings.HasPrefix(rel, "db/") && rel != "db/.gitkeep" {
+		return true
+	}
+	for _, skip := range templateCreateSkip {
+		if rel == skip || strings.HasPrefix(rel, skip+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func copyInstanceForTemplate(srcDir, targetDir string) (int, error) {
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return 0, err
+	}
+	copied := 0
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if templateCreateSkipped(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(targetDir, filepath.FromSlash(rel))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil // sockets, symlinks into the container, etc.
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		copied++
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+	return copied, err
+}
+
+// instanceEnvToTemplate turns an instance .env back into an env-template. Secrets and
+// values wpod fills at create time are cleared, other values stay as template defaults.
+// Cleared secrets are declared in the blueprint's env list with "secret": true.
+func instanceEnvToTemplate(content string, bp *Blueprint) (string, []string) {
+	declaredSecret := make(map[string]bool)
+	for _, e := range bp.Env {
+		declaredSecret[e.Key] = e.Secret
+	}
+	var stripped []string
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		match := envLineRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		key := match[1]
+		secret := declaredSecret[key] || secretEnvKeyRe.MatchString(key)
+		if !secret && !containsString(instanceSpecificEnvKeys, key) {
+			continue
+		}
+		if strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), key+"=")) != "" {
+			stripped = append(stripped, key)
+		}
+		lines[i] = key + "="
+		if secret && !declaredSecret[key] {
+			markEnvKeySecret(bp, key)
+		}
+	}
+	return strings.Join(lines, "\n"), stripped
+}
+
+func markEnvKeySecret(bp *Blueprint, key string) {
+	for i := range bp.Env {
+		if bp.Env[i].Key == key {
+			bp.Env[i].Secret = true
+			return
+		}
+	}
+	bp.Env = append(bp.Env, BlueprintEnvKey{Key: key, Secret: true})
+}
+
+// instanceActivePackages asks WP-CLI in the running instance for its active plugins and
+// themes, the same query 'manage' uses for getActivePluginsAsJSON. A parent theme is
+// listed before its child with activation turned off.
+func instanceActivePackages(instanceDir string, pinVersions bool) ([]BlueprintPackage, []BlueprintPackage, error) {
+	type wpItem struct {
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		Version string `json:"version"`
+	}
+	list := func(kind string, statusFilter string) ([]wpItem, error) {
+		cmd := exec.Command("docker", "compose", "exec", "-T", "--user", "www-data", "wordpress", "wp", kind, "list", "--status="+statusFilter, "--format=json")
+		cmd.Dir = instanceDir
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, errors.New(msg)
+			}
+			return nil, err
+		}
+		var items []wpItem
+		if err := json.Unmarshal(bytes.TrimSpace(out), &items); err != nil {
+			return nil, fmt.Errorf("unexpected WP-CLI output: %w", err)
+		}
+		return items, nil
+	}
+	toPackage := func(item wpItem) BlueprintPackage {
+		p := BlueprintPackage{Slug: item.Name}
+		if pinVersions {
+			p.Version = item.Version
+		}
+		return p
+	}
+
+	activePlugins, err := list("plugin", "active")
+	if err != nil {
+		return nil, nil, err
+	}
+	plugins := make([]BlueprintPackage, 0, len(activePlugins))
+	for _, item := range activePlugins {
+		plugins = append(plugins, toPackage(item))
+	}
+
+	var themes []BlueprintPackage
+	for _, status := range []string{"parent", "active"} {
+		items, err := list("theme", status)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, item := range items {
+			p := toPackage(item)
+			if status == "parent" {
+				off := false
+				p.Activate = &off
+			}
+			themes = append(themes, p)
+		}
+	}
+	return plugins, themes, nil
+}
+
+// seedSanitizeSQL scrubs personal data and credentials from a copy of the database.
+// Users keep their accounts but need a password reset after import.
+func seedSanitizeSQL(prefix string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("UPDATE `%susers` SET user_pass = '', user_activation_key = '', user_email = CONCAT('user', ID, '@example.invalid')", prefix),
+		fmt.Sprintf("DELETE FROM `%susermeta` WHERE meta_key = 'session_tokens'", prefix),
+		fmt.Sprintf("UPDATE `%soptions` SET option_value = 'admin@example.invalid' WHERE option_name IN ('admin_email', 'new_admin_email')", prefix),
+		fmt.Sprintf("DELETE FROM `%soptions` WHERE option_name LIKE '\\_transient\\_%%' OR option_name LIKE '\\_site\\_transient\\_%%'", prefix),
+		fmt.Sprintf("UPDATE `%scomments` SET comment_author_email = '', comment_author_IP = ''", prefix),
+	}, "; ") + ";"
+}
+
+// seedExportScript copies the instance database into a scratch schema inside the db
+// container, sanitizes the copy and dumps it to stdout, so the live data is never touched.
+const seedExportScript = `set -e
+export MYSQL_PWD="$MYSQL_ROOT_PASSWORD"
+CLI=$(command -v mariadb || command -v mysql)
+DUMP=$(command -v mariadb-dump || command -v mysqldump)
+trap '"$CLI" -uroot -e "DROP DATABASE IF EXISTS wpod_seed"' EXIT
+"$CLI" -uroot -e "DROP DATABASE IF EXISTS wpod_seed; CREATE DATABASE wpod_seed"
+"$DUMP" -uroot --single-transaction "$MYSQL_DATABASE" | "$CLI" -uroot wpod_seed
+"$CLI" -uroot wpod_seed -e "$WPOD_SANITIZE_SQL"
+"$DUMP" -uroot --single-transaction --skip-dump-date wpod_seed
+`
+
+func exportSanitizedSeed(instanceDir, tablePrefix string) ([]byte, error) {
+	if !tablePrefixRe.MatchString(tablePrefix) {
+		return nil, fmt.Errorf("table prefix '%s' is not supported", tablePrefix)
+	}
+	printInfo("Exporting a sanitized copy of the database...")
+	cmd := exec.Command("docker", "compose", "exec", "-T", "-e", "WPOD_SANITIZE_SQL="+seedSanitizeSQL(tablePrefix), "db", "sh", "-c", seedExportScript)
+	cmd.Dir = instanceDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("%w (is the instance running?)", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,206 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const renderedTemplateSuffix = ".tmpl"
+
+// TemplateContext is the data every *.tmpl file of a template is rendered with. The
+// field names are part of the template format documented in docs/templates.md, so only
+// add to them.
+type TemplateContext struct {
+	Name             string            // short instance name, e.g. "myblog"
+	InstanceName     string            // instance directory name, e.g. "www-myblog-wordpress"
+	Directory        string            // absolute instance directory
+	Template         string            // template directory name
+	DevHostName      string            // e.g. "myblog.test"
+	DevDomainSuffix  string            // e.g. ".test"
+	ProxyBackend     string            // caddy or traefik
+	WordPressVersion string            // WORDPRESS_VERSION from .env
+	PHPVersion       string            // PHP_VERSION from .env; empty for the image default
+	WebServer        string            // apache or nginx-fpm
+	DBEngine         string            // DB_ENGINE from .env: mysql or mariadb
+	DBVersion        string            // DB_VERSION from .env, e.g. "8.0"
+	Ports            TemplatePorts     // host ports from .env
+	Vars             map[string]string // blueprint variable answers
+	Env              map[string]string // the instance .env after wpod filled it in
+}
+
+// TemplatePorts are the host ports wpod assigned to the instance; 0 when unset.
+type TemplatePorts struct {
+	WordPress   int
+	Adminer     int
+	MailpitSMTP int
+	MailpitWeb  int
+	CaddyHTTP   int
+	CaddyHTTPS  int
+}
+
+// parseEnvContent reads KEY=value lines; comments and blank lines are skipped.
+func parseEnvContent(content string) map[string]string {
+	env := make(map[string]string)
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		match := envLineRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		_, value, _ := strings.Cut(line, "=")
+		env[match[1]] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return env
+}
+
+// newTemplateContext builds the render context for an instance from its final .env.
+func newTemplateContext(instanceDir, templateName, devHostName, devDomainSuffix, proxyBackend, envContent string, vars map[string]string) TemplateContext {
+	env := parseEnvContent(envContent)
+	port := func(key string) int {
+		p, _ := strconv.Atoi(env[key])
+		return p
+	}
+	absDir, err := filepath.Abs(instanceDir)
+	if err != nil {
+		absDir = instanceDir
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	webServer := env["WEB_SERVER"]
+	if webServer == "" {
+		webServer = webServerApache
+	}
+	return TemplateContext{
+		Name:             instanceNameBase(instanceDir),
+		InstanceName:     filepath.Base(instanceDir),
+		Directory:        absDir,
+		Template:         templateName,
+		DevHostName:      devHostName,
+		DevDomainSuffix:  devDomainSuffix,
+		ProxyBackend:     proxyBackend,
+		WordPressVersion: env["WORDPRESS_VERSION"],
+		PHPVersion:       env["PHP_VERSION"],
+		WebServer:        webServer,
+		DBEngine:         env["DB_ENGINE"],
+		DBVersion:        env["DB_VERSION"],
+		Ports: TemplatePorts{
+			WordPress:   port("WORDPRESS_PORT"),
+			Adminer:     port("ADMINER_PORT"),
+			MailpitSMTP: port("MAILPIT_PORT_SMTP"),
+			MailpitWeb:  port("MAILPIT_PORT_WEB"),
+			CaddyHTTP:   port("CADDY_HTTP_PORT"),
+			CaddyHTTPS:  port("CADDY_HTTPS_PORT"),
+		},
+		Vars: vars,
+		Env:  env,
+	}
+}
+
+// templateFuncs are available in *.tmpl files in addition to the text/template built-ins.
+var templateFuncs = template.FuncMap{
+	"default": func(def, value interface{}) interface{} {
+		if value == nil {
+			return def
+		}
+		if s, ok := value.(string); ok && s == "" {
+			return def
+		}
+		return value
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"quote": strconv.Quote,
+	"truthy": func(s string) bool {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "1", "true", "yes", "on":
+			return true
+		}
+		return false
+	},
+}
+
+func parseFileTemplate(name string, content []byte) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(string(content))
+}
+
+// renderTemplateFiles renders every *.tmpl file below instanceDir to the same path without
+// the suffix and removes the .tmpl file. It returns the rendered paths (relative).
+func renderTemplateFiles(instanceDir string, ctx TemplateContext) ([]string, error) {
+	var rendered []string
+	err := filepath.WalkDir(instanceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), renderedTemplateSuffix) {
+			return nil
+		}
+		rel, _ := filepath.Rel(instanceDir, path)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tmpl, err := parseFileTemplate(filepath.ToSlash(rel), content)
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, ctx); err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		target := strings.TrimSuffix(path, renderedTemplateSuffix)
+		if err := os.WriteFile(target, out.Bytes(), info.Mode().Perm()); err != nil {
+			return err
+		}
+		rendered = append(rendered, strings.TrimSuffix(filepath.ToSlash(rel), renderedTemplateSuffix))
+		return os.Remove(path)
+	})
+	if err != nil {
+		return rendered, fmt.Errorf("rendering template files: %w", err)
+	}
+	return rendered, nil
+}
+
+// sampleTemplateContext is used by 'template validate' to execute *.tmpl files.
+func sampleTemplateContext(bp *Blueprint) TemplateContext {
+	vars := make(map[string]string)
+	for _, v := range bp.Variables {
+		vars[v.Name] = v.normalizeValue(v.defaultValue())
+	}
+	return TemplateContext{
+		Name: "example", InstanceName: "www-example-wordpress", Directory: "/srv/www-example-wordpress",
+		Template: "example", DevHostName: "example.test", DevDomainSuffix: ".test", ProxyBackend: proxyBackendCaddy,
+		WordPressVersion: "latest",
+		Ports:            TemplatePorts{WordPress: 11000, Adminer: 8081, MailpitSMTP: 10000, MailpitWeb: 8000, CaddyHTTP: 80, CaddyHTTPS: 443},
+		Vars:             vars,
+		Env:              map[string]string{},
+	}
+}
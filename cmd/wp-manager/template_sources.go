@@ -0,0 +1,617 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	templateLockFileName = "templates.lock.json"
+
+	templateSourceGit     = "git"
+	templateSourceTarball = "tarball"
+	templateSourcePath    = "path"
+)
+
+// templateLock records where each fetched user template came from. It lives next to the
+// templates in the user template directory.
+type templateLock struct {
+	Templates map[string]templateLockEntry `json:"templates"`
+}
+
+type templateLockEntry struct {
+	Source      string `json:"source"`             // as given to 'template add'
+	Kind        string `json:"kind"`               // git, tarball or path
+	Ref         string `json:"ref,omitempty"`      // git branch or tag asked for
+	Subdir      string `json:"subdir,omitempty"`   // template directory inside the source
+	Revision    string `json:"revision,omitempty"` // git commit of the installed copy
+	Checksum    string `json:"checksum"`           // sha256 over the installed files
+	InstalledAt string `json:"installed_at"`
+}
+
+func readTemplateLock(templatesDir string) (*templateLock, error) {
+	lock := &templateLock{Templates: make(map[string]templateLockEntry)}
+	data, err := os.ReadFile(filepath.Join(templatesDir, templateLockFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", templateLockFileName, err)
+	}
+	if lock.Templates == nil {
+		lock.Templates = make(map[string]templateLockEntry)
+	}
+	return lock, nil
+}
+
+func writeTemplateLock(templatesDir string, lock *templateLock) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(templatesDir, templateLockFileName), append(data, '\n'), 0644)
+}
+
+// detectTemplateSourceKind tells git repositories, tarballs and plain directories apart.
+func detectTemplateSourceKind(source string) (string, error) {
+	lower := strings.ToLower(source)
+	isTarball := strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") || strings.HasSuffix(lower, ".tar")
+	switch {
+	case strings.HasPrefix(lower, "git+"), strings.HasPrefix(lower, "git@"), strings.HasPrefix(lower, "git://"),
+		strings.HasPrefix(lower, "ssh://"), strings.HasSuffix(lower, ".git"):
+		return templateSourceGit, nil
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		if isTarball {
+			return templateSourceTarball, nil
+		}
+		return templateSourceGit, nil
+	case strings.HasPrefix(lower, "file://"):
+		if isTarball {
+			return templateSourceTarball, nil
+		}
+		return templateSourceGit, nil
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", fmt.Errorf("'%s' is not a git URL, tarball or existing path", source)
+	}
+	if !info.IsDir() {
+		if isTarball {
+			return templateSourceTarball, nil
+		}
+		return "", fmt.Errorf("'%s' is a file but not a .tar, .tar.gz or .tgz archive", source)
+	}
+	return templateSourcePath, nil
+}
+
+// fetchTemplateSource copies a source into a temporary directory and returns that
+// directory (to be removed by the caller) and, for git, the checked out revision.
+func fetchTemplateSource(entry templateLockEntry) (string, string, error) {
+	tmp, err := os.MkdirTemp("", "wpod-template-")
+	if err != nil {
+		return "", "", err
+	}
+	fail := func(err error) (string, string, error) {
+		os.RemoveAll(tmp)
+		return "", "", err
+	}
+
+	revision := ""
+	switch entry.Kind {
+	case templateSourceGit:
+		if _, ok := checkExecutable("git"); !ok {
+			return fail(errors.New("git is not installed"))
+		}
+		// A source or ref starting with '-' would be read by git as an option.
+		repo := strings.TrimPrefix(entry.Source, "git+")
+		if strings.HasPrefix(repo, "-") {
+			return fail(fmt.Errorf("git source '%s' must not start with '-'", entry.Source))
+		}
+		if strings.HasPrefix(entry.Ref, "-") {
+			return fail(fmt.Errorf("ref '%s' must not start with '-'", entry.Ref))
+		}
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if entry.Ref != "" {
+			args = append(args, "--branch", entry.Ref)
+		}
+		args = append(args, "--", repo, tmp)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			return fail(fmt.Errorf("git clone failed: %s", strings.TrimSpace(string(out))))
+		}
+		out, err := exec.Command("git", "-C", tmp, "rev-parse", "HEAD").Output()
+		if err != nil {
+			return fail(fmt.Errorf("git rev-parse failed: %w", err))
+		}
+		revision = strings.TrimSpace(string(out))
+	case templateSourceTarball:
+		if err := extractTemplateTarball(entry.Source, tmp); err != nil {
+			return fail(err)
+		}
+	case templateSourcePath:
+		if err := copyDirTree(entry.Source, tmp); err != nil {
+			return fail(err)
+		}
+	default:
+		return fail(fmt.Errorf("unknown source kind '%s'", entry.Kind))
+	}
+	return tmp, revision, nil
+}
+
+func openTarballSource(source string) (io.ReadCloser, error) {
+	lower := strings.ToLower(source)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		client := &http.Client{Timeout: 2 * time.Minute}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("download failed: %s", resp.Status)
+		}
+		return resp.Body, nil
+	}
+	if strings.HasPrefix(lower, "file://") {
+		u, err := url.Parse(source)
+		if err != nil {
+			return nil, err
+		}
+		source = u.Path
+	}
+	return os.Open(source)
+}
+
+// extractTemplateTarball unpacks a (gzipped) tarball into dest. A single top-level
+// directory, as produced by GitHub release archives, is stripped.
+func extractTemplateTarball(source, dest string) error {
+	rc, err := openTarballSource(source)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = bytes.NewReader(data)
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	type file struct {
+		name string
+		mode fs.FileMode
+		data []byte
+	}
+	var files []file
+	var dirs []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading tarball: %w", err)
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name == "." || strings.HasPrefix(name, "pax_global_header") {
+			continue
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("tarball entry '%s' escapes the target directory", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			dirs = append(dirs, name)
+		case tar.TypeReg:
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			files = append(files, file{name, fs.FileMode(hdr.Mode).Perm(), content})
+		}
+		// Links and devices are ignored; templates are plain files.
+	}
+
+	strip := ""
+	if len(files) > 0 {
+		top, _, _ := strings.Cut(files[0].name, "/")
+		strip = top + "/"
+		for _, f := range files {
+			if !strings.HasPrefix(f.name, strip) {
+				strip = ""
+				break
+			}
+		}
+	}
+	for _, d := range dirs {
+		if rel := strings.TrimPrefix(d+"/", strip); rel != "" {
+			if err := os.MkdirAll(filepath.Join(dest, filepath.FromSlash(rel)), 0755); err != nil {
+				return err
+			}
+		}
+	}
+	for _, f := range files {
+		target := filepath.Join(dest, filepath.FromSlash(strings.TrimPrefix(f.name, strip)))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		mode := f.mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.WriteFile(target, f.data, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyDirTree copies regular files and directories from src to dst, leaving out .git.
+func copyDirTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+// templateChecksum hashes relative paths and contents of every file in dir, in order.
+func templateChecksum(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	h := sha256.New()
+	for _, rel := range files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", rel, len(data))
+		h.Write(data)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// installTemplateFromSource fetches entry, validates it and installs it as name in
+// templatesDir, replacing any previous copy. It returns the completed lock entry.
+func installTemplateFromSource(templatesDir, name string, entry templateLockEntry) (templateLockEntry, error) {
+	tmp, revision, err := fetchTemplateSource(entry)
+	if err != nil {
+		return entry, err
+	}
+	defer os.RemoveAll(tmp)
+
+	root := tmp
+	if entry.Subdir != "" {
+		root = filepath.Join(tmp, filepath.FromSlash(path.Clean(entry.Subdir)))
+		if rel, err := filepath.Rel(tmp, root); err != nil || strings.HasPrefix(rel, "..") {
+			return entry, fmt.Errorf("subdir '%s' is outside the source", entry.Subdir)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, blueprintFileName)); err != nil {
+		return entry, fmt.Errorf("no %s at the template root (use --subdir for templates in a sub-directory)", blueprintFileName)
+	}
+	var problems []string
+	for _, issue := range validateTemplate(os.DirFS(root)) {
+		if issue.isError() {
+			problems = append(problems, issue.Message)
+		}
+	}
+	if len(problems) > 0 {
+		return entry, fmt.Errorf("template has errors: %s", strings.Join(problems, "; "))
+	}
+
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		return entry, err
+	}
+	staging := filepath.Join(templatesDir, "."+name+".new")
+	os.RemoveAll(staging)
+	if err := copyDirTree(root, staging); err != nil {
+		os.RemoveAll(staging)
+		return entry, err
+	}
+	checksum, err := templateChecksum(staging)
+	if err != nil {
+		os.RemoveAll(staging)
+		return entry, err
+	}
+	target := filepath.Join(templatesDir, name)
+	if err := os.RemoveAll(target); err != nil {
+		os.RemoveAll(staging)
+		return entry, err
+	}
+	if err := os.Rename(staging, target); err != nil {
+		os.RemoveAll(staging)
+		return entry, err
+	}
+
+	entry.Revision = revision
+	entry.Checksum = checksum
+	entry.InstalledAt = time.Now().Format(time.RFC3339)
+	return entry, nil
+}
+
+// defaultTemplateSourceName derives a template directory name from a source.
+func defaultTemplateSourceName(source, subdir string) string {
+	if subdir != "" {
+		return path.Base(path.Clean(filepath.ToSlash(subdir)))
+	}
+	base := strings.TrimSuffix(filepath.ToSlash(source), "/")
+	if i := strings.LastIndexAny(base, "/:"); i >= 0 {
+		base = base[i+1:]
+	}
+	for _, suffix := range []string{".git", ".tar.gz", ".tgz", ".tar"} {
+		base = strings.TrimSuffix(base, suffix)
+	}
+	return base
+}
+
+// templateAdd implements 'wpod template add <git-url|tarball|path>'.
+func templateAdd(args []string) {
+	addFlags := flag.NewFlagSet("template add", flag.ExitOnError)
+	name := addFlags.String("name", "", "Template directory name (default: derived from the source)")
+	ref := addFlags.String("ref", "", "Git branch or tag to check out")
+	subdir := addFlags.String("subdir", "", "Directory inside the source that holds the template")
+	force := addFlags.Bool("force", false, "Replace an existing template with the same name")
+	source, rest := "", args
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		source, rest = rest[0], rest[1:]
+	}
+	_ = addFlags.Parse(rest)
+	if source == "" && addFlags.NArg() > 0 {
+		source = addFlags.Arg(0)
+	}
+
+	printSectionHeader("Add Template")
+	if source == "" {
+		printError("Source Required", "Usage: wpod template add <git-url|tarball|path> [--name <dir>] [--ref <branch|tag>] [--subdir <dir>] [--force]")
+		os.Exit(1)
+	}
+	kind, err := detectTemplateSourceKind(source)
+	if err != nil {
+		printError("Unsupported Source", err.Error())
+		os.Exit(1)
+	}
+	if kind == templateSourcePath || (kind == templateSourceTarball && !strings.Contains(source, "://")) {
+		if abs, errAbs := filepath.Abs(source); errAbs == nil {
+			source = abs
+		}
+	}
+	if *name == "" {
+		*name = defaultTemplateSourceName(source, *subdir)
+	}
+	if _, errSan := sanitizeInstanceName(*name); errSan != nil {
+		printError("Invalid Template Name", fmt.Sprintf("'%s' cannot be used as a directory name; pass --name.", *name))
+		os.Exit(1)
+	}
+
+	config, _ := readGlobalManagerConfig()
+	templatesDir, err := configuredTemplatesDir(config)
+	if err != nil {
+		printError("Could not determine template directory", err.Error())
+		os.Exit(1)
+	}
+	lock, err := readTemplateLock(templatesDir)
+	if err != nil {
+		printError("Could not read the template lock file", err.Error())
+		os.Exit(1)
+	}
+	if _, err := os.Stat(filepath.Join(templatesDir, *name)); err == nil && !*force {
+		printError("Template Exists", fmt.Sprintf("A user template named '%s' already exists.", *name), "Use 'wpod template update "+*name+"' or pass --force to replace it.")
+		os.Exit(1)
+	}
+
+	printInfo(fmt.Sprintf("Fetching %s source...", kind), source)
+	entry, err := installTemplateFromSource(templatesDir, *name, templateLockEntry{Source: source, Kind: kind, Ref: *ref, Subdir: *subdir})
+	if err != nil {
+		printError("Template Not Added", err.Error())
+		os.Exit(1)
+	}
+	lock.Templates[*name] = entry
+	if err := writeTemplateLock(templatesDir, lock); err != nil {
+		printWarning("Template installed, but the lock file could not be written", err.Error())
+	}
+	details := []string{fmt.Sprintf("Name: %s", commandStyle.Render(*name)), "Location: " + filepath.Join(templatesDir, *name)}
+	if entry.Revision != "" {
+		details = append(details, "Revision: "+entry.Revision)
+	}
+	details = append(details, "Checksum: "+entry.Checksum)
+	printSuccess("Template Added", details...)
+}
+
+// templateUpdate implements 'wpod template update [name...]'. Templates whose files were
+// edited since they were fetched are left alone unless --force is given.
+func templateUpdate(args []string) {
+	updateFlags := flag.NewFlagSet("template update", flag.ExitOnError)
+	force := updateFlags.Bool("force", false, "Overwrite local changes to fetched templates")
+	ref := updateFlags.String("ref", "", "Switch to another git branch or tag (single template only)")
+	var names []string
+	for len(args) > 0 {
+		_ = updateFlags.Parse(args)
+		args = updateFlags.Args()
+		if len(args) > 0 {
+			names = append(names, args[0])
+			args = args[1:]
+		}
+	}
+
+	printSectionHeader("Update Templates")
+	config, _ := readGlobalManagerConfig()
+	templatesDir, err := configuredTemplatesDir(config)
+	if err != nil {
+		printError("Could not determine template directory", err.Error())
+		os.Exit(1)
+	}
+	lock, err := readTemplateLock(templatesDir)
+	if err != nil {
+		printError("Could not read the template lock file", err.Error())
+		os.Exit(1)
+	}
+	if len(names) == 0 {
+		for name := range lock.Templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		printInfo("No fetched templates to update.", "Add one with "+commandStyle.Render("wpod template add <git-url|tarball|path>")+".")
+		return
+	}
+	if *ref != "" && len(names) != 1 {
+		printError("--ref needs exactly one template name.")
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, name := range names {
+		entry, ok := lock.Templates[name]
+		if !ok {
+			printError(fmt.Sprintf("%s: not in %s", name, templateLockFileName), "Only templates added with 'wpod template add' can be updated.")
+			failed++
+			continue
+		}
+		if *ref != "" {
+			entry.Ref = *ref
+		}
+		previous := entry
+		updated, err := updateFetchedTemplate(templatesDir, name, entry, *force)
+		if errors.Is(err, errTemplateLocalChanges) {
+			printWarning(fmt.Sprintf("%s: local changes, skipped", name), "The installed files differ from the fetched copy. Re-run with --force to discard them.")
+			failed++
+			continue
+		} else if err != nil {
+			printError(fmt.Sprintf("%s: update failed", name), err.Error())
+			failed++
+			continue
+		}
+		lock.Templates[name] = updated
+		switch {
+		case updated.Checksum == previous.Checksum:
+			printSuccess(fmt.Sprintf("%s: up to date", name), describeTemplateRevision(updated))
+		case previous.Revision != "" && updated.Revision != previous.Revision:
+			printSuccess(fmt.Sprintf("%s: updated", name), fmt.Sprintf("%s -> %s", shortRevision(previous.Revision), shortRevision(updated.Revision)))
+		default:
+			printSuccess(fmt.Sprintf("%s: updated", name), describeTemplateRevision(updated))
+		}
+	}
+	if err := writeTemplateLock(templatesDir, lock); err != nil {
+		printError("Could not write the template lock file", err.Error())
+		os.Exit(1)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// errTemplateLocalChanges reports a fetched template whose files were edited after install.
+var errTemplateLocalChanges = errors.New("the installed files differ from the fetched copy")
+
+// updateFetchedTemplate re-fetches the template installed as name from its lock entry.
+// Without force, a template with local edits is left alone and errTemplateLocalChanges
+// is returned.
+func updateFetchedTemplate(templatesDir, name string, entry templateLockEntry, force bool) (templateLockEntry, error) {
+	if current, err := templateChecksum(filepath.Join(templatesDir, name)); err == nil && current != entry.Checksum && !force {
+		return entry, errTemplateLocalChanges
+	}
+	return installTemplateFromSource(templatesDir, name, entry)
+}
+
+func shortRevision(rev string) string {
+	if len(rev) > 12 {
+		return rev[:12]
+	}
+	return rev
+}
+
+func describeTemplateRevision(e templateLockEntry) string {
+	if e.Revision != "" {
+		return fmt.Sprintf("%s @ %s", e.Source, shortRevision(e.Revision))
+	}
+	return fmt.Sprintf("%s (%s)", e.Source, e.Checksum)
+}
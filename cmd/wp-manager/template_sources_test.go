@@ -0,0 +1,219 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bundledTemplate is copied into the test sources so they pass template validation.
+const bundledTemplate = "templates/docker-default-wordpress"
+
+// runGit runs git in dir with a fixed identity so commits work without a git config.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=wpod", "-c", "user.email=wpod@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newTemplateRepo creates a git repository holding a copy of the bundled template.
+func newTemplateRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "starter")
+	if err := copyDirTree(bundledTemplate, repo); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "init", "--quiet", "--initial-branch=main")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "initial")
+	return repo
+}
+
+func TestTemplateAddAndUpdateFromGit(t *testing.T) {
+	repo := newTemplateRepo(t)
+	source := "file://" + filepath.ToSlash(repo)
+	kind, err := detectTemplateSourceKind(source)
+	if err != nil || kind != templateSourceGit {
+		t.Fatalf("kind = %q, %v; want %q", kind, err, templateSourceGit)
+	}
+	templatesDir := t.TempDir()
+
+	// add
+	entry, err := installTemplateFromSource(templatesDir, "starter", templateLockEntry{Source: source, Kind: kind})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := runGit(t, repo, "rev-parse", "HEAD")
+	if entry.Revision != first {
+		t.Errorf("revision = %q, want %q", entry.Revision, first)
+	}
+	if !strings.HasPrefix(entry.Checksum, "sha256:") {
+		t.Errorf("checksum = %q", entry.Checksum)
+	}
+	lock := &templateLock{Templates: map[string]templateLockEntry{"starter": entry}}
+	if err := writeTemplateLock(templatesDir, lock); err != nil {
+		t.Fatal(err)
+	}
+	lock, err = readTemplateLock(templatesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry = lock.Templates["starter"]
+	if entry.Source != source || entry.Kind != templateSourceGit || entry.Revision != first {
+		t.Errorf("lock entry = %+v", entry)
+	}
+
+	// update with a changed revision
+	if err := os.WriteFile(filepath.Join(repo, "NOTES.md"), []byte("second revision\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "second")
+	second := runGit(t, repo, "rev-parse", "HEAD")
+	updated, err := updateFetchedTemplate(templatesDir, "starter", entry, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Revision != second || updated.Checksum == entry.Checksum {
+		t.Errorf("after update: revision %q checksum %q; want revision %q and a new checksum", updated.Revision, updated.Checksum, second)
+	}
+	if _, err := os.Stat(filepath.Join(templatesDir, "starter", "NOTES.md")); err != nil {
+		t.Errorf("updated file not installed: %v", err)
+	}
+
+	// local edits are kept without --force
+	edited := filepath.Join(templatesDir, "starter", "NOTES.md")
+	if err := os.WriteFile(edited, []byte("local edit\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := updateFetchedTemplate(templatesDir, "starter", updated, false); !errors.Is(err, errTemplateLocalChanges) {
+		t.Fatalf("err = %v, want errTemplateLocalChanges", err)
+	}
+	if data, _ := os.ReadFile(edited); string(data) != "local edit\n" {
+		t.Errorf("local edit was overwritten: %q", data)
+	}
+
+	// and discarded with it
+	if _, err := updateFetchedTemplate(templatesDir, "starter", updated, true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(edited); string(data) != "second revision\n" {
+		t.Errorf("--force kept the local edit: %q", data)
+	}
+}
+
+func TestTemplateSourceRejectsOptionLikeGitArgs(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, entry := range []templateLockEntry{
+		{Source: "--upload-pack=touch /tmp/pwned", Kind: templateSourceGit},
+		{Source: "file:///tmp/repo.git", Kind: templateSourceGit, Ref: "--upload-pack=x"},
+	} {
+		if _, _, err := fetchTemplateSource(entry); err == nil || !strings.Contains(err.Error(), "must not start with '-'") {
+			t.Errorf("%+v: err = %v", entry, err)
+		}
+	}
+}
+
+type tarEntry struct {
+	name string
+	body string
+	dir  bool
+}
+
+// writeTarball writes entries to a gzipped tarball in a temp directory.
+func writeTarball(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "template.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if e.dir {
+			hdr = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractTemplateTarball(t *testing.T) {
+	tarball := writeTarball(t, []tarEntry{
+		{name: "starter-1.0/", dir: true},
+		{name: "starter-1.0/blueprint.json", body: "{}"},
+		{name: "starter-1.0/config/php.ini", body: "memory_limit=256M"},
+	})
+	dest := t.TempDir()
+	if err := extractTemplateTarball("file://"+filepath.ToSlash(tarball), dest); err != nil {
+		t.Fatal(err)
+	}
+	// The single top-level directory is stripped.
+	for _, name := range []string{"blueprint.json", "config/php.ini"} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s not extracted: %v", name, err)
+		}
+	}
+}
+
+func TestExtractTemplateTarballRejectsEscapes(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "starter/../../evil.txt", "/etc/evil.txt"} {
+		t.Run(name, func(t *testing.T) {
+			tarball := writeTarball(t, []tarEntry{{name: "starter/blueprint.json", body: "{}"}, {name: name, body: "x"}})
+			dest := filepath.Join(t.TempDir(), "dest")
+			if err := extractTemplateTarball(tarball, dest); err == nil || !strings.Contains(err.Error(), "escapes") {
+				t.Fatalf("err = %v, want an escape error", err)
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "evil.txt")); err == nil {
+				t.Error("entry was written outside the target directory")
+			}
+		})
+	}
+}
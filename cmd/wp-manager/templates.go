@@ -0,0 +1,303 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	templateSourceBuiltin = "built-in"
+	templateSourceUser    = "user"
+
+	userTemplatesDirName = "templates"
+	blueprintFileName    = "blueprint.json"
+	defaultTemplateName  = "docker-default-wordpress"
+)
+
+// templateInfo describes one template available to 'wpod create'. Templates are looked up
+// by Dir; a user template with the same Dir as a built-in one replaces it.
+type templateInfo struct {
+	Dir         string // template id, the directory name
+	Name        string
+	Description string
+	Source      string // templateSourceBuiltin or templateSourceUser
+	Location    string // filesystem path, or "embedded" for built-in templates
+	Overrides   bool   // user template shadowing a built-in template of the same Dir
+	FS          fs.FS  // rooted at the template directory
+}
+
+// builtinTemplatesFS returns the embedded templates directory as its own root.
+func builtinTemplatesFS() fs.FS {
+	sub, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		// Only possible if the embed directive changes; fail loudly in that case.
+		panic(err)
+	}
+	return sub
+}
+
+// configuredTemplatesDir returns the user template directory: the templates_directory
+// config key, or <config dir>/templates.
+func configuredTemplatesDir(config GlobalManagerConfig) (string, error) {
+	if config.TemplatesDirectory != "" {
+		return config.TemplatesDirectory, nil
+	}
+	storageDir, err := getConfigStorageDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(storageDir, userTemplatesDirName), nil
+}
+
+// readTemplateBlueprintMeta reads the name and description from a template's blueprint.json.
+func readTemplateBlueprintMeta(templateFS fs.FS) (name, description string, err error) {
+	data, err := fs.ReadFile(templateFS, blueprintFileName)
+	if err != nil {
+		return "", "", err
+	}
+	var meta struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", "", fmt.Errorf("invalid %s: %w", blueprintFileName, err)
+	}
+	return meta.Name, meta.Description, nil
+}
+
+// scanTemplates lists the template directories (those with a blueprint.json) in root.
+func scanTemplates(root fs.FS, source, location string) []templateInfo {
+	entries, err := fs.ReadDir(root, ".")
+	if err != nil {
+		return nil
+	}
+	var found []templateInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		sub, err := fs.Sub(root, entry.Name())
+		if err != nil {
+			continue
+		}
+		name, description, err := readTemplateBlueprintMeta(sub)
+		if err != nil {
+			continue
+		}
+		if name == "" {
+			name = entry.Name()
+		}
+		info := templateInfo{Dir: entry.Name(), Name: name, Description: description, Source: source, FS: sub, Location: "embedded"}
+		if location != "" {
+			info.Location = filepath.Join(location, entry.Name())
+		}
+		found = append(found, info)
+	}
+	return found
+}
+
+// loadTemplates returns built-in templates layered with user templates, sorted by Dir.
+func loadTemplates() ([]templateInfo, error) {
+	byDir := make(map[string]templateInfo)
+	for _, t := range scanTemplates(builtinTemplatesFS(), templateSourceBuiltin, "") {
+		byDir[t.Dir] = t
+	}
+
+	config, _ := readGlobalManagerConfig()
+	userDir, err := configuredTemplatesDir(config)
+	if err == nil {
+		if info, statErr := os.Stat(userDir); statErr == nil && info.IsDir() {
+			for _, t := range scanTemplates(os.DirFS(userDir), templateSourceUser, userDir) {
+				_, t.Overrides = byDir[t.Dir]
+				byDir[t.Dir] = t
+			}
+		}
+	}
+
+	templates := make([]templateInfo, 0, len(byDir))
+	for _, t := range byDir {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		// Keep the default template first so it stays the default choice.
+		if (templates[i].Dir == defaultTemplateName) != (templates[j].Dir == defaultTemplateName) {
+			return templates[i].Dir == defaultTemplateName
+		}
+		return templates[i].Dir < templates[j].Dir
+	})
+	if len(templates) == 0 {
+		return nil, errors.New("no templates found")
+	}
+	return templates, nil
+}
+
+// findTemplate looks up a template by its directory name.
+func findTemplate(dir string) (templateInfo, error) {
+	templates, err := loadTemplates()
+	if err != nil {
+		return templateInfo{}, err
+	}
+	for _, t := range templates {
+		if t.Dir == dir {
+			return t, nil
+		}
+	}
+	return templateInfo{}, fmt.Errorf("template '%s' not found (see 'wpod template list')", dir)
+}
+
+func manageBinaryName() string {
+	if runtime.GOOS == "windows" {
+		return "manage.exe"
+	}
+	return "manage"
+}
+
+// copyTemplate writes every file of a template into targetDir. Templates without their own
+// manage binary (user templates usually) get the one embedded with the default template.
+func copyTemplate(t templateInfo, targetDir string) error {
+	manageCopied := false
+	err := fs.WalkDir(t.FS, ".", func(pathInTemplate string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk err at %s: %w", pathInTemplate, err)
+		}
+		if pathInTemplate == "." {
+			return nil
+		}
+		targetPath := filepath.Join(targetDir, filepath.FromSlash(pathInTemplate))
+		if d.IsDir() {
+			return os.MkdirAll(targetPath, 0755)
+		}
+		srcFile, errRead := fs.ReadFile(t.FS, pathInTemplate)
+		if errRead != nil {
+			return fmt.Errorf("read template %s: %w", pathInTemplate, errRead)
+		}
+		perm := fs.FileMode(0644)
+		if d.Name() == "manage" || d.Name() == "manage.exe" {
+			perm = 0755
+			manageCopied = true
+		}
+		return os.WriteFile(targetPath, srcFile, perm)
+	})
+	if err != nil || manageCopied {
+		return err
+	}
+	manageName := manageBinaryName()
+	if data, errRead := fs.ReadFile(builtinTemplatesFS(), path.Join(defaultTemplateName, manageName)); errRead == nil {
+		return os.WriteFile(filepath.Join(targetDir, manageName), data, 0755)
+	}
+	return nil
+}
+
+// readTemplateFile reads a file from the template, falling back to the built-in default
+// template so user templates can omit shared files such as the Caddyfile template.
+func readTemplateFile(t templateInfo, name string) ([]byte, error) {
+	data, err := fs.ReadFile(t.FS, name)
+	if err == nil {
+		return data, nil
+	}
+	return fs.ReadFile(builtinTemplatesFS(), path.Join(defaultTemplateName, name))
+}
+
+// handleTemplateCommand handles subcommands for 'wpod template'.
+func handleTemplateCommand(args []string) {
+	if len(args) < 1 {
+		printError("Template Subcommand Required", "Usage: wpod template <list|path|validate <dir>|create --from-instance <name>|add <source>|update [name]>")
+		return
+	}
+	subcommand := strings.ToLower(args[0])
+	switch subcommand {
+	case "list", "ls":
+		templateList()
+	case "add":
+		templateAdd(args[1:])
+	case "update", "upgrade":
+		templateUpdate(args[1:])
+	case "create", "new":
+		templateCreate(args[1:])
+	case "validate", "lint":
+		templateValidate(args[1:])
+	case "path", "dir":
+		config, _ := readGlobalManagerConfig()
+		dir, err := configuredTemplatesDir(config)
+		if err != nil {
+			printError("Could not determine template directory", err.Error())
+			return
+		}
+		fmt.Println(dir) // Raw output for scripting
+	default:
+		printError("Unknown Template Subcommand", fmt.Sprintf("Subcommand '%s' not recognized.", subcommand), "Valid subcommands are: list, path, validate, create, add, update")
+	}
+}
+
+func templateList() {
+	printSectionHeader("Available Templates")
+	templates, err := loadTemplates()
+	if err != nil {
+		printError("No Templates Found", err.Error())
+		return
+	}
+
+	dirWidth, nameWidth, sourceWidth, locationWidth := 30, 45, 22, 50
+	header := lipgloss.JoinHorizontal(lipgloss.Top,
+		tableHeaderStyle.Width(dirWidth).Render("Template"),
+		tableHeaderStyle.Width(nameWidth).Render("Name"),
+		tableHeaderStyle.Width(sourceWidth).Render("Source"),
+		tableHeaderStyle.Width(locationWidth).Render("Location"),
+	)
+	rows := []string{header}
+	config, _ := readGlobalManagerConfig()
+	lock := &templateLock{}
+	if dir, err := configuredTemplatesDir(config); err == nil {
+		if l, errLock := readTemplateLock(dir); errLock == nil {
+			lock = l
+		}
+	}
+	for _, t := range templates {
+		source := t.Source
+		if entry, ok := lock.Templates[t.Dir]; ok && t.Source == templateSourceUser {
+			source += ", " + entry.Kind
+		}
+		if t.Overrides {
+			source += " (overrides)"
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top,
+			tableCellStyle.Width(dirWidth).Render(t.Dir),
+			tableCellStyle.Width(nameWidth).Render(t.Name),
+			tableCellStyle.Width(sourceWidth).Render(source),
+			tableCellStyle.Width(locationWidth).Render(shortenPath(t.Location, locationWidth-3)),
+		))
+	}
+	fmt.Println(tableBorderStyle.Render(lipgloss.JoinVertical(lipgloss.Left, rows...)))
+
+	if dir, err := configuredTemplatesDir(config); err == nil {
+		printInfo("User templates are read from:", commandStyle.Render(dir), "A user template with the same directory name as a built-in one replaces it.")
+	}
+}
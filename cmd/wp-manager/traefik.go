@@ -0,0 +1,456 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/regiellis/wp-manager-cli/internal/compose"
+)
+
+const (
+	proxyBackendCaddy   = "caddy"
+	proxyBackendTraefik = "traefik"
+
+	traefikNetworkName       = "wpod-proxy"
+	traefikContainerName     = "wpod-traefik"
+	traefikImage             = "traefik:v3.1"
+	defaultTraefikHTTPPort   = 80
+	defaultTraefikHTTPSPort  = 443
+	defaultTraefikDashboard  = 8088
+	traefikManagedLabelKey   = "traefik.enable"
+	traefikDockerSocketMount = "/var/run/docker.sock:/var/run/docker.sock:ro"
+)
+
+// traefikRoute describes one compose service exposed through the shared Traefik container.
+type traefikRoute struct {
+	Service string // compose service name
+	Host    string // host name matched by the router
+	Port    int    // port inside the container
+}
+
+func configuredProxyBackend(config GlobalManagerConfig) string {
+	if config.ProxyBackend == proxyBackendTraefik {
+		return proxyBackendTraefik
+	}
+	return proxyBackendCaddy
+}
+
+func configuredTraefikPorts(config GlobalManagerConfig) (httpPort, httpsPort, dashboardPort int) {
+	httpPort, httpsPort, dashboardPort = defaultTraefikHTTPPort, defaultTraefikHTTPSPort, defaultTraefikDashboard
+	if config.TraefikHTTPPort > 0 {
+		httpPort = config.TraefikHTTPPort
+	}
+	if config.TraefikHTTPSPort > 0 {
+		httpsPort = config.TraefikHTTPSPort
+	}
+	if config.TraefikDashboardPort > 0 {
+		dashboardPort = config.TraefikDashboardPort
+	}
+	return
+}
+
+// instanceProxyBackend returns the backend an instance was created with; instances that
+// predate the option are Caddy instances.
+func instanceProxyBackend(meta InstanceMeta) string {
+	if meta.ProxyBackend == proxyBackendTraefik {
+		return proxyBackendTraefik
+	}
+	return proxyBackendCaddy
+}
+
+var traefikRouterNameRe = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// traefikRouterName derives a router/service name that is unique per instance.
+func traefikRouterName(instanceNameBase, service string) string {
+	name := traefikRouterNameRe.ReplaceAllString(strings.ToLower(instanceNameBase), "-")
+	name = "wpod-" + strings.Trim(name, "-")
+	if service != "wordpress" {
+		name += "-" + service
+	}
+	return name
+}
+
+// traefikRoutesFor lists the routes for an instance's standard services.
+func traefikRoutesFor(devHostName string) []traefikRoute {
+	return []traefikRoute{
+		{Service: "wordpress", Host: devHostName, Port: 80},
+		{Service: "adminer", Host: "adminer." + devHostName, Port: 8080},
+		{Service: "mailpit", Host: "mail." + devHostName, Port: 8025},
+	}
+}
+
+// traefikLabels returns the compose labels for one route: an HTTP router, an HTTPS router
+// with TLS and a load-balancer service pointing at the container port.
+func traefikLabels(instanceNameBase string, route traefikRoute) []string {
+	name := traefikRouterName(instanceNameBase, route.Service)
+	rule := fmt.Sprintf("Host(`%s`)", route.Host)
+	return []string{
+		"traefik.enable=true",
+		"traefik.docker.network=" + traefikNetworkName,
+		fmt.Sprintf("traefik.http.routers.%s.rule=%s", name, rule),
+		fmt.Sprintf("traefik.http.routers.%s.entrypoints=web", name),
+		fmt.Sprintf("traefik.http.routers.%s.service=%s", name, name),
+		fmt.Sprintf("traefik.http.routers.%s-secure.rule=%s", name, rule),
+		fmt.Sprintf("traefik.http.routers.%s-secure.entrypoints=websecure", name),
+		fmt.Sprintf("traefik.http.routers.%s-secure.tls=true", name),
+		fmt.Sprintf("traefik.http.routers.%s-secure.service=%s", name, name),
+		fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port=%d", name, route.Port),
+	}
+}
+
+// --- docker-compose.yml patching ---
+
+// addTraefikToService adds the labels and the proxy network to one service.
+func addTraefikToService(file *compose.File, service string, labels []string) bool {
+	svc := file.Service(service)
+	if svc == nil {
+		return false
+	}
+	// Join the shared proxy network next to the instance network.
+	if len(svc.Networks()) == 0 {
+		svc.AddToList("networks", "default")
+	}
+	svc.AddToList("networks", traefikNetworkName)
+	svc.AddLabels(labels...)
+	return true
+}
+
+// applyTraefikToCompose adds Traefik routing for an instance to its compose content. It is
+// a no-op if the file already carries Traefik labels.
+func applyTraefikToCompose(content, instanceNameBase, devHostName string) (string, []string, error) {
+	if strings.Contains(content, traefikManagedLabelKey) {
+		return content, nil, nil
+	}
+	file, err := compose.Parse([]byte(content))
+	if err != nil {
+		return content, nil, err
+	}
+
+	// On the nginx-fpm stack the site is served by nginx, not the wordpress container.
+	hasNginx := file.HasService("nginx")
+
+	var routed []string
+	for _, route := range traefikRoutesFor(devHostName) {
+		service := route.Service
+		if service == "wordpress" && hasNginx {
+			service = "nginx"
+		}
+		if addTraefikToService(file, service, traefikLabels(instanceNameBase, route)) {
+			routed = append(routed, route.Host)
+		}
+	}
+	if len(routed) == 0 {
+		return content, nil, fmt.Errorf("no wordpress, adminer or mailpit service found in docker-compose.yml")
+	}
+
+	// Declare the proxy network as external at the top level.
+	file.AddExternalNetwork(traefikNetworkName)
+
+	out, err := file.Bytes()
+	if err != nil {
+		return content, nil, err
+	}
+	return string(out), routed, nil
+}
+
+// configureInstanceForTraefik patches an instance's compose file and .env for the Traefik
+// backend and returns the host names that will be routed.
+func configureInstanceForTraefik(instanceDir, instanceNameBase, devHostName string, config GlobalManagerConfig) ([]string, error) {
+	composePath := filepath.Join(instanceDir, "docker-compose.yml")
+	content, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, fmt.Errorf("read docker-compose.yml: %w", err)
+	}
+	patched, routed, err := applyTraefikToCompose(string(content), instanceNameBase, devHostName)
+	if err != nil {
+		return nil, err
+	}
+	if patched != string(content) {
+		if err := os.WriteFile(composePath, []byte(patched), 0644); err != nil {
+			return nil, fmt.Errorf("write docker-compose.yml: %w", err)
+		}
+	}
+
+	// Record the shared proxy ports in .env so they are visible with './manage ports'.
+	envPath := filepath.Join(instanceDir, ".env")
+	if envContent, err := os.ReadFile(envPath); err == nil {
+		httpPort, httpsPort, dashboardPort := configuredTraefikPorts(config)
+		updated := string(envContent)
+		for key, value := range map[string]int{
+			"TRAEFIK_HTTP_PORT":      httpPort,
+			"TRAEFIK_HTTPS_PORT":     httpsPort,
+			"TRAEFIK_DASHBOARD_PORT": dashboardPort,
+		} {
+			re := regexp.MustCompile(fmt.Sprintf(`(?m)^%s=.*$`, regexp.QuoteMeta(key)))
+			if re.MatchString(updated) {
+				updated = re.ReplaceAllString(updated, fmt.Sprintf("%s=%d", key, value))
+			}
+		}
+		if updated != string(envContent) {
+			if err := os.WriteFile(envPath, []byte(updated), 0644); err != nil {
+				return routed, fmt.Errorf("write .env: %w", err)
+			}
+		}
+	}
+	return routed, nil
+}
+
+// --- Shared Traefik container ---
+
+func ensureTraefikNetwork() error {
+	if _, err := dockerStdout("network", "inspect", traefikNetworkName); err == nil {
+		return nil
+	}
+	if _, err := dockerStdout("network", "create", traefikNetworkName); err != nil {
+		return fmt.Errorf("creating network %s: %w", traefikNetworkName, err)
+	}
+	printSuccess("Created Docker network:", commandStyle.Render(traefikNetworkName))
+	return nil
+}
+
+// traefikContainerState returns "running", "exited", etc., or "" if the container does not exist.
+func traefikContainerState() string {
+	out, err := dockerStdout("inspect", "-f", "{{.State.Status}}", traefikContainerName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func traefikRunArgs(config GlobalManagerConfig) []string {
+	httpPort, httpsPort, dashboardPort := configuredTraefikPorts(config)
+	bind := configuredBindAddress(config)
+	return []string{
+		"run", "-d",
+		"--name", traefikContainerName,
+		"--restart", "unless-stopped",
+		"--network", traefikNetworkName,
+		"-p", fmt.Sprintf("%s:%d:80", bind, httpPort),
+		"-p", fmt.Sprintf("%s:%d:443", bind, httpsPort),
+		"-p", fmt.Sprintf("%s:%d:8080", bind, dashboardPort),
+		"-v", traefikDockerSocketMount,
+		"--label", "wpod.managed=true",
+		traefikImage,
+		"--providers.docker=true",
+		"--providers.docker.exposedbydefault=false",
+		"--providers.docker.network=" + traefikNetworkName,
+		"--entrypoints.web.address=:80",
+		"--entrypoints.websecure.address=:443",
+		"--api.dashboard=true",
+		"--api.insecure=true",
+	}
+}
+
+// ensureTraefikRunning creates the proxy network and starts the shared Traefik container.
+func ensureTraefikRunning(config GlobalManagerConfig) error {
+	if err := ensureTraefikNetwork(); err != nil {
+		return err
+	}
+	switch traefikContainerState() {
+	case "running":
+		return nil
+	case "":
+		if _, err := dockerStdout(traefikRunArgs(config)...); err != nil {
+			return fmt.Errorf("%s: %w", traefikContainerName, err)
+		}
+	default:
+		if _, err := dockerStdout("start", traefikContainerName); err != nil {
+			return fmt.Errorf("%s: %w", traefikContainerName, err)
+		}
+	}
+	return nil
+}
+
+// handleProxyCommand handles subcommands for 'wpod proxy'.
+func handleProxyCommand(args []string) {
+	if len(args) < 1 {
+		printError("Proxy Subcommand Required", "Usage: wpod proxy <up|down|status|attach <name>>")
+		return
+	}
+	subcommand := strings.ToLower(args[0])
+	switch subcommand {
+	case "up", "start":
+		proxyUp()
+	case "down", "stop":
+		proxyDown()
+	case "status":
+		proxyStatus()
+	case "attach":
+		name := ""
+		if len(args) > 1 {
+			name = args[1]
+		}
+		proxyAttach(name)
+	default:
+		printError("Unknown Proxy Subcommand", fmt.Sprintf("Subcommand '%s' not recognized.", subcommand), "Valid subcommands are: up, down, status, attach")
+	}
+}
+
+func proxyUp() {
+	printSectionHeader("Start Shared Traefik Proxy")
+	config, _ := readGlobalManagerConfig()
+	if err := ensureTraefikRunning(config); err != nil {
+		printError("Failed to Start Traefik", err.Error())
+		printInfo("If ports are already taken, change them with:", commandStyle.Render("wpod config set traefik_http_port <port>"))
+		return
+	}
+	httpPort, httpsPort, dashboardPort := configuredTraefikPorts(config)
+	printSuccess("Traefik is running.",
+		fmt.Sprintf("HTTP: %d  HTTPS: %d", httpPort, httpsPort),
+		fmt.Sprintf("Dashboard: http://%s:%d/dashboard/", configuredBindAddress(config), dashboardPort),
+	)
+}
+
+func proxyDown() {
+	printSectionHeader("Stop Shared Traefik Proxy")
+	if traefikContainerState() == "" {
+		printInfo("Traefik container is not present.")
+		return
+	}
+	if _, err := dockerStdout("rm", "-f", traefikContainerName); err != nil {
+		printError("Failed to Remove Traefik Container", err.Error())
+		return
+	}
+	printSuccess("Traefik container removed.", fmt.Sprintf("The '%s' network was kept so instances can still start.", traefikNetworkName))
+}
+
+func proxyStatus() {
+	printSectionHeader("Shared Traefik Proxy")
+	config, _ := readGlobalManagerConfig()
+	state := traefikContainerState()
+	if state == "" {
+		state = "not created"
+	}
+	printInfo(infoMsgStyle.Render("Proxy Backend:"), commandStyle.Render(configuredProxyBackend(config)))
+	printInfo(infoMsgStyle.Render("Container:"), commandStyle.Render(traefikContainerName+" ("+state+")"))
+
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		printError("Failed to Read Manager Metadata", err.Error())
+		return
+	}
+	var routed []string
+	for name, meta := range managerMeta {
+		if instanceProxyBackend(meta) != proxyBackendTraefik {
+			continue
+		}
+		dev := instanceDevHostName(name, meta)
+		routed = append(routed, fmt.Sprintf("%s -> http://%s", name, dev))
+	}
+	if len(routed) == 0 {
+		printInfo("No instances are routed through Traefik.", "Use "+commandStyle.Render("wpod proxy attach <name>")+" to move an instance over.")
+		return
+	}
+	sort.Strings(routed)
+	printInfo("Routed instances:", routed...)
+}
+
+// proxyAttach switches an existing instance to the Traefik backend.
+func proxyAttach(instanceName string) {
+	printSectionHeader("Route Instance Through Traefik")
+	if err := backfillDevHostNames(); err != nil {
+		printWarning("Could not record dev host names in the registry.", err.Error())
+	}
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		printError("Failed to Read Manager Metadata", err.Error())
+		return
+	}
+	if instanceName == "" {
+		var names []string
+		for name := range managerMeta {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			printWarning("No Instances Found", "No instances registered.")
+			return
+		}
+		sort.Strings(names)
+		options := make([]huh.Option[string], len(names))
+		for i, name := range names {
+			options[i] = huh.NewOption(name, name)
+		}
+		if err := huh.NewForm(huh.NewGroup(
+			huh.NewSelect[string]().Title("Select Instance").Options(options...).Value(&instanceName),
+		)).WithTheme(theme).Run(); err != nil {
+			printInfo("Cancelled.")
+			return
+		}
+	}
+	meta, ok := managerMeta[instanceName]
+	if !ok {
+		printError("Instance Not Found", fmt.Sprintf("Instance '%s' is not registered.", instanceName))
+		return
+	}
+	config, _ := readGlobalManagerConfig()
+	devHost := instanceDevHostName(instanceName, meta)
+	routed, err := configureInstanceForTraefik(meta.Directory, instanceNameBase(instanceName), devHost, config)
+	if err != nil {
+		printError("Failed to Configure Instance", err.Error())
+		return
+	}
+
+	err = updateManagerMeta(func(current ManagerMeta) bool {
+		latest, ok := current[instanceName]
+		if ok {
+			latest.ProxyBackend = proxyBackendTraefik
+			current[instanceName] = latest
+		}
+		return ok
+	})
+	if err != nil {
+		printError("Failed to Update Manager Metadata", err.Error())
+		return
+	}
+	if localMeta, err := readInstanceMeta(meta.Directory); err == nil {
+		localMeta.ProxyBackend = proxyBackendTraefik
+		_ = writeInstanceMeta(meta.Directory, localMeta)
+	}
+
+	details := []string{}
+	for _, host := range routed {
+		details = append(details, "http://"+host)
+	}
+	if len(routed) == 0 {
+		details = append(details, "docker-compose.yml already had Traefik labels.")
+	}
+	details = append(details, "", "Restart the instance to apply: "+commandStyle.Render("./manage restart"))
+	printSuccess("Instance routed through Traefik.", details...)
+}
+
+// parseTraefikPort validates a port value for the traefik_*_port config keys.
+func parseTraefikPort(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("'%s' is not a valid port", value)
+	}
+	return port, nil
+}
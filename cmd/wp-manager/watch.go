@@ -0,0 +1,297 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// watchSettleDelay lets a burst of events ('docker compose up' starting four
+	// containers) settle before the instance status is checked once.
+	watchSettleDelay = time.Second
+	// watchReconnectDelay is the pause before 'docker events' is restarted after it exits.
+	watchReconnectDelay = 5 * time.Second
+)
+
+// watchedActions are the container events that can change an instance's status.
+var watchedActions = map[string]bool{
+	"start": true, "restart": true, "stop": true, "die": true, "kill": true, "oom": true,
+	"pause": true, "unpause": true, "destroy": true, "health_status": true,
+}
+
+// dockerEvent is the part of a 'docker events --format json' record wpod uses.
+type dockerEvent struct {
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	TimeNano int64 `json:"timeNano"`
+}
+
+func (e dockerEvent) time() time.Time {
+	if e.TimeNano == 0 {
+		return time.Now()
+	}
+	return time.Unix(0, e.TimeNano)
+}
+
+// statusWatcher keeps the registry status of every instance in step with docker events.
+type statusWatcher struct {
+	timeline bool
+	timeout  time.Duration
+	dirs     map[string]string    // canonical instance directory -> registry key
+	pending  map[string]time.Time // instance -> when its last event arrived
+}
+
+// watchCommand implements 'wpod watch [--timeline] [--timeout d]'.
+func watchCommand(args []string) {
+	watchFlags := flag.NewFlagSet("watch", flag.ExitOnError)
+	timeline := watchFlags.Bool("timeline", false, "Print every container start, stop, die and health event")
+	timeout := watchFlags.Duration("timeout", defaultStatusTimeout, "Time limit for each status check")
+	_ = watchFlags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := &statusWatcher{timeline: *timeline, timeout: *timeout, pending: make(map[string]time.Time)}
+	printSectionHeader("Watching Docker Events")
+	printInfo("Registry and local meta files follow container events.", "Press Ctrl+C to stop.")
+	w.run(ctx)
+}
+
+// run follows 'docker events' until ctx is cancelled, restarting it when docker goes away.
+// Every (re)connect starts with a full status check, since events may have been missed.
+func (w *statusWatcher) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		w.syncAll()
+		err := w.follow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		msg := "docker events stopped"
+		if err != nil {
+			msg = err.Error()
+		}
+		printWarning("Lost Docker Events", msg, fmt.Sprintf("Reconnecting in %s...", watchReconnectDelay))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchReconnectDelay):
+		}
+	}
+}
+
+// follow reads one 'docker events' stream. Checks run on this goroutine once an
+// instance has been quiet for watchSettleDelay.
+func (w *statusWatcher) follow(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "docker", "events",
+		"--filter", "type=container",
+		"--filter", "label=com.docker.compose.project",
+		"--format", "{{json .}}")
+	cmd.WaitDelay = time.Second
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("docker events: %w", err)
+	}
+
+	events := make(chan dockerEvent)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			var e dockerEvent
+			if json.Unmarshal(scanner.Bytes(), &e) == nil {
+				events <- e
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(watchSettleDelay / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return cmd.Wait()
+			}
+			w.handle(e)
+		case <-ticker.C:
+			w.checkSettled()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// handle records one container event for the instance it belongs to.
+func (w *statusWatcher) handle(e dockerEvent) {
+	action, detail, _ := strings.Cut(e.Action, ":")
+	if !watchedActions[action] {
+		return
+	}
+	name, ok := w.instanceFor(e.Actor.Attributes["com.docker.compose.project.working_dir"])
+	if !ok {
+		return
+	}
+	if w.timeline {
+		service := e.Actor.Attributes["com.docker.compose.service"]
+		switch {
+		case detail != "":
+			detail = strings.TrimSpace(detail)
+		case action == "die" && e.Actor.Attributes["exitCode"] != "":
+			detail = "exit " + e.Actor.Attributes["exitCode"]
+		}
+		line := fmt.Sprintf("%s  %-28s %-12s %s", e.time().Format("15:04:05"), name, service, action)
+		if detail != "" {
+			line += " (" + detail + ")"
+		}
+		fmt.Println(subtleStyle.Render(line))
+	}
+	w.pending[name] = time.Now()
+}
+
+// instanceFor maps a compose working directory to its registry key. Unknown directories
+// trigger one registry reload so instances created while watching are picked up.
+func (w *statusWatcher) instanceFor(dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	dir = canonicalDir(dir)
+	if name, ok := w.dirs[dir]; ok {
+		return name, true
+	}
+	w.loadRegistry()
+	name, ok := w.dirs[dir]
+	return name, ok
+}
+
+func (w *statusWatcher) loadRegistry() ManagerMeta {
+	managerMeta, err := readManagerMeta()
+	if err != nil {
+		printWarning("Failed to Read Manager Metadata", err.Error())
+		return nil
+	}
+	w.dirs = make(map[string]string, len(managerMeta))
+	for name, meta := range managerMeta {
+		w.dirs[canonicalDir(meta.Directory)] = name
+	}
+	return managerMeta
+}
+
+// syncAll checks every registered instance, as 'wpod update' does.
+func (w *statusWatcher) syncAll() {
+	managerMeta := w.loadRegistry()
+	if len(managerMeta) == 0 {
+		return
+	}
+	w.record(checkStatuses(managerMeta, defaultStatusWorkers, w.timeout))
+}
+
+// checkSettled checks the instances whose events have settled.
+func (w *statusWatcher) checkSettled() {
+	managerMeta := make(ManagerMeta)
+	var registry ManagerMeta
+	for name, at := range w.pending {
+		if time.Since(at) < watchSettleDelay {
+			continue
+		}
+		delete(w.pending, name)
+		if registry == nil {
+			if registry = w.loadRegistry(); registry == nil {
+				return
+			}
+		}
+		if meta, ok := registry[name]; ok {
+			managerMeta[name] = meta
+		}
+	}
+	if len(managerMeta) > 0 {
+		w.record(checkStatuses(managerMeta, defaultStatusWorkers, w.timeout))
+	}
+}
+
+// record writes changed statuses to the registry, in one locked update, and to the local
+// meta files, and prints each change.
+func (w *statusWatcher) record(results map[string]statusCheck) {
+	checkedAt := time.Now().Format(time.RFC3339)
+	type change struct {
+		dir, from string
+		result    statusCheck
+	}
+	changes := make(map[string]change)
+	err := updateManagerMeta(func(current ManagerMeta) bool {
+		for name, result := range results {
+			meta, ok := current[name]
+			if !ok || result.TimedOut {
+				continue
+			}
+			if meta.Status == result.Status && sameServiceStatuses(meta.Services, result.Services) {
+				continue
+			}
+			changes[name] = change{dir: meta.Directory, from: meta.Status, result: result}
+			meta.Status = result.Status
+			meta.Services = result.Services
+			meta.StatusCheckedAt = checkedAt
+			current[name] = meta
+		}
+		return len(changes) > 0
+	})
+	if err != nil {
+		printWarning("Failed to Update Manager Metadata", err.Error())
+		return
+	}
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if results[name].TimedOut {
+			printWarning(fmt.Sprintf("Status check for %s timed out", name), fmt.Sprintf("Docker did not answer within %s; the previous status was kept.", w.timeout))
+			continue
+		}
+		c, ok := changes[name]
+		if !ok {
+			continue
+		}
+		if c.from != c.result.Status {
+			fmt.Printf("%s  %s Status: %s -> %s\n", time.Now().Format("15:04:05"), boldStyle.Render(name), subtleStyle.Render(c.from), renderStatus(c.result.Status))
+		}
+		if err := writeLocalStatus(c.dir, c.result.Status, c.result.Services, checkedAt); err != nil {
+			printWarning(fmt.Sprintf("Local Meta Error for %s", name), err.Error())
+		}
+	}
+}
@@ -0,0 +1,584 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package compose edits docker-compose.yml files through their YAML node tree, so changes
+// keep comments, key order and quoting and do not depend on how the file is indented.
+package compose
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is a parsed compose file.
+type File struct {
+	doc  *yaml.Node
+	root *yaml.Node // top-level mapping
+	crlf bool
+
+	// spacing records, for each top-level key and service of the parsed file, whether a
+	// blank line preceded it. The YAML encoder drops blank lines, so Bytes puts them back.
+	spacing map[string]bool
+}
+
+// Parse reads compose content. An empty document yields an empty file.
+func Parse(data []byte) (*File, error) {
+	f := &File{crlf: bytes.Contains(data, []byte("\r\n"))}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse compose file: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("parse compose file: top level is not a mapping")
+	}
+	f.doc, f.root = &doc, doc.Content[0]
+	f.spacing = recordSpacing(data, f.root)
+	return f, nil
+}
+
+// recordSpacing notes which top-level keys and services have a blank line above them (or
+// above the comment block attached to them) in the source.
+func recordSpacing(data []byte, root *yaml.Node) map[string]bool {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	blankAbove := func(line int) bool {
+		i := line - 2 // the line above, 0-based
+		for i >= 0 && strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
+			i--
+		}
+		return i >= 0 && strings.TrimSpace(lines[i]) == ""
+	}
+	spacing := make(map[string]bool)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		spacing[spacingKey(0, key.Value)] = blankAbove(key.Line)
+		if key.Value != "services" || root.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		services := root.Content[i+1]
+		for j := 0; j+1 < len(services.Content); j += 2 {
+			spacing[spacingKey(2, services.Content[j].Value)] = blankAbove(services.Content[j].Line)
+		}
+	}
+	return spacing
+}
+
+func spacingKey(indent int, name string) string {
+	return fmt.Sprintf("%d:%s", indent, name)
+}
+
+// Load reads and parses a compose file from disk.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Bytes renders the file with two-space indentation and the line endings it was parsed
+// with. Top-level sections and services keep the blank line above them they had; ones
+// added since Parse get one.
+func (f *File) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f.doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	out := spaceSections(buf.String(), f.spacing)
+	if f.crlf {
+		out = strings.ReplaceAll(out, "\n", "\r\n")
+	}
+	return []byte(out), nil
+}
+
+// Save writes the file to path.
+func (f *File) Save(path string) error {
+	data, err := f.Bytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// spaceSections puts a blank line before top-level keys and services after the first one,
+// as recorded in spacing; keys spacing does not know get one. Comments directly above a
+// key stay attached to it.
+func spaceSections(out string, spacing map[string]bool) string {
+	lines := strings.Split(out, "\n")
+	result := make([]string, 0, len(lines)+16)
+	inServices, firstService := false, true
+	for _, line := range lines {
+		indent := indentOf(line)
+		isKey := line != "" && !strings.HasPrefix(strings.TrimSpace(line), "#") && !strings.HasPrefix(strings.TrimSpace(line), "- ")
+		separate := false
+		wanted := func() bool {
+			name, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+			blank, known := spacing[spacingKey(indent, name)]
+			return blank || !known
+		}
+		switch {
+		case isKey && indent == 0:
+			separate = len(result) > 0 && wanted()
+			inServices, firstService = strings.HasPrefix(line, "services:"), true
+		case isKey && indent == 2 && inServices:
+			separate = !firstService && wanted()
+			firstService = false
+		}
+		if separate {
+			// Insert above any comment block that belongs to this key.
+			at := len(result)
+			for at > 0 && strings.HasPrefix(strings.TrimSpace(result[at-1]), "#") && indentOf(result[at-1]) == indent {
+				at--
+			}
+			if at > 0 && result[at-1] != "" {
+				result = append(result[:at], append([]string{""}, result[at:]...)...)
+			}
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// --- Services ---
+
+// Service is one entry under "services". Reading it never changes the file; the first
+// edit gives a service declared without a body ("name:") an empty mapping.
+type Service struct {
+	Name string
+	node *yaml.Node
+}
+
+// body returns the service mapping for an edit, replacing an empty or scalar value.
+func (s *Service) body() *yaml.Node {
+	if s.node.Kind != yaml.MappingNode {
+		*s.node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	return s.node
+}
+
+// Services returns the service names in file order.
+func (f *File) Services() []string {
+	services := mappingValue(f.root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil
+	}
+	names := make([]string, 0, len(services.Content)/2)
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		names = append(names, services.Content[i].Value)
+	}
+	return names
+}
+
+// Service returns the named service, or nil if the file does not define it.
+func (f *File) Service(name string) *Service {
+	services := mappingValue(f.root, "services")
+	if services == nil {
+		return nil
+	}
+	node := mappingValue(services, name)
+	if node == nil {
+		return nil
+	}
+	return &Service{Name: name, node: node}
+}
+
+// HasService reports whether the file defines the named service.
+func (f *File) HasService(name string) bool {
+	services := mappingValue(f.root, "services")
+	return services != nil && mappingValue(services, name) != nil
+}
+
+// AddService appends an empty service and returns it; an existing service is returned as
+// it is.
+func (f *File) AddService(name string) *Service {
+	if s := f.Service(name); s != nil {
+		return s
+	}
+	services := ensureMapping(f.root, "services")
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	services.Content = append(services.Content, scalar(name), node)
+	return &Service{Name: name, node: node}
+}
+
+// AddServiceYAML appends a service whose body is given as YAML, e.g. from an add-on
+// catalogue. It fails if the service exists or the body is not a mapping.
+func (f *File) AddServiceYAML(name, body string) (*Service, error) {
+	if f.HasService(name) {
+		return nil, fmt.Errorf("service '%s' already exists", name)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		return nil, fmt.Errorf("service '%s': %w", name, err)
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("service '%s': definition is not a mapping", name)
+	}
+	node := doc.Content[0]
+	node.Style = 0
+	services := ensureMapping(f.root, "services")
+	services.Content = append(services.Content, scalar(name), node)
+	return &Service{Name: name, node: node}, nil
+}
+
+// RemoveService deletes a service and reports whether it was there.
+func (f *File) RemoveService(name string) bool {
+	services := mappingValue(f.root, "services")
+	return services != nil && deleteKey(services, name)
+}
+
+// --- Top-level volumes and networks ---
+
+// Volumes returns the names of the top-level volumes in file order.
+func (f *File) Volumes() []string {
+	volumes := mappingValue(f.root, "volumes")
+	if volumes == nil || volumes.Kind != yaml.MappingNode {
+		return nil
+	}
+	names := make([]string, 0, len(volumes.Content)/2)
+	for i := 0; i+1 < len(volumes.Content); i += 2 {
+		names = append(names, volumes.Content[i].Value)
+	}
+	return names
+}
+
+// AddVolume declares a named volume under the top-level "volumes" key.
+func (f *File) AddVolume(name string) {
+	volumes := ensureMapping(f.root, "volumes")
+	if mappingValue(volumes, name) == nil {
+		volumes.Content = append(volumes.Content, scalar(name), null())
+	}
+}
+
+// RemoveVolume deletes a top-level volume declaration, and the "volumes" key if it was
+// the last one.
+func (f *File) RemoveVolume(name string) bool {
+	return removeTopLevelEntry(f.root, "volumes", name)
+}
+
+// HasNetwork reports whether a top-level network is declared.
+func (f *File) HasNetwork(name string) bool {
+	networks := mappingValue(f.root, "networks")
+	return networks != nil && mappingValue(networks, name) != nil
+}
+
+// AddExternalNetwork declares a network created outside this project.
+func (f *File) AddExternalNetwork(name string) {
+	networks := ensureMapping(f.root, "networks")
+	if mappingValue(networks, name) != nil {
+		return
+	}
+	body := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setValue(body, "external", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+	networks.Content = append(networks.Content, scalar(name), body)
+}
+
+func removeTopLevelEntry(root *yaml.Node, key, name string) bool {
+	section := mappingValue(root, key)
+	if section == nil || !deleteKey(section, name) {
+		return false
+	}
+	if len(section.Content) == 0 {
+		deleteKey(root, key)
+	}
+	return true
+}
+
+// --- Service keys ---
+
+// Has reports whether the service sets key.
+func (s *Service) Has(key string) bool {
+	return mappingValue(s.node, key) != nil
+}
+
+// Get returns a scalar value such as "image", or "" if unset.
+func (s *Service) Get(key string) string {
+	if v := mappingValue(s.node, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// Set sets a scalar value such as "image" or "restart".
+func (s *Service) Set(key, value string) {
+	setValue(s.body(), key, scalar(value))
+}
+
+// Delete removes a key and reports whether it was set.
+func (s *Service) Delete(key string) bool {
+	return deleteKey(s.node, key)
+}
+
+// List returns the items of a sequence such as "ports" or "profiles". The mapping form of
+// "networks" and "depends_on" yields the keys.
+func (s *Service) List(key string) []string {
+	v := mappingValue(s.node, key)
+	if v == nil {
+		return nil
+	}
+	var items []string
+	switch v.Kind {
+	case yaml.SequenceNode:
+		for _, item := range v.Content {
+			if item.Kind == yaml.ScalarNode {
+				items = append(items, item.Value)
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(v.Content); i += 2 {
+			items = append(items, v.Content[i].Value)
+		}
+	}
+	return items
+}
+
+// SetList replaces a sequence; quoted items are written in double quotes, as the bundled
+// templates write ports and labels. An empty list removes the key.
+func (s *Service) SetList(key string, items []string, quoted bool) {
+	if len(items) == 0 {
+		s.Delete(key)
+		return
+	}
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, item := range items {
+		n := scalar(item)
+		if quoted {
+			n.Style = yaml.DoubleQuotedStyle
+		}
+		seq.Content = append(seq.Content, n)
+	}
+	setValue(s.body(), key, seq)
+}
+
+// AddToList appends an item to a sequence (or a key to the mapping form of "networks" and
+// "depends_on") unless it is already there. It reports whether anything changed.
+func (s *Service) AddToList(key, item string) bool {
+	v := mappingValue(s.node, key)
+	if v == nil || (v.Kind == yaml.ScalarNode && v.Value == "") {
+		s.SetList(key, []string{item}, false)
+		return true
+	}
+	for _, existing := range s.List(key) {
+		if existing == item {
+			return false
+		}
+	}
+	switch v.Kind {
+	case yaml.SequenceNode:
+		n := scalar(item)
+		if len(v.Content) > 0 {
+			n.Style = v.Content[len(v.Content)-1].Style
+		}
+		v.Content = append(v.Content, n)
+	case yaml.MappingNode:
+		v.Content = append(v.Content, scalar(item), &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle})
+	default:
+		return false
+	}
+	return true
+}
+
+// Profiles returns the compose profiles the service belongs to.
+func (s *Service) Profiles() []string { return s.List("profiles") }
+
+// AddProfile puts the service in a profile; services with a profile that is never
+// activated are not started by 'docker compose up'.
+func (s *Service) AddProfile(profile string) bool { return s.AddToList("profiles", profile) }
+
+// Ports returns the published port mappings.
+func (s *Service) Ports() []string { return s.List("ports") }
+
+// Volumes returns the service's volume mounts in short syntax.
+func (s *Service) Volumes() []string { return s.List("volumes") }
+
+// Networks returns the networks the service joins.
+func (s *Service) Networks() []string { return s.List("networks") }
+
+// AddLabels adds labels, to a list or mapping "labels" key alike. Labels are given as
+// "key=value".
+func (s *Service) AddLabels(labels ...string) {
+	v := mappingValue(s.node, "labels")
+	if v != nil && v.Kind == yaml.MappingNode {
+		for _, label := range labels {
+			key, value, _ := strings.Cut(label, "=")
+			n := scalar(value)
+			n.Style = yaml.DoubleQuotedStyle
+			setValue(v, key, n)
+		}
+		return
+	}
+	if v == nil {
+		s.SetList("labels", labels, true)
+		return
+	}
+	for _, label := range labels {
+		if s.AddToList("labels", label) {
+			v.Content[len(v.Content)-1].Style = yaml.DoubleQuotedStyle
+		}
+	}
+}
+
+// SetHealthcheckTest replaces the test command of an existing healthcheck, written in
+// flow style (["CMD", ...]). It reports false if the service has no healthcheck.
+func (s *Service) SetHealthcheckTest(test []string) bool {
+	hc := mappingValue(s.node, "healthcheck")
+	if hc == nil || hc.Kind != yaml.MappingNode {
+		return false
+	}
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, arg := range test {
+		n := scalar(arg)
+		n.Style = yaml.DoubleQuotedStyle
+		seq.Content = append(seq.Content, n)
+	}
+	setValue(hc, "test", seq)
+	return true
+}
+
+// ResourceLimits returns deploy.resources.limits.cpus and .memory; "" when unset.
+func (s *Service) ResourceLimits() (cpus, memory string) {
+	limits := mappingValue(mappingValue(mappingValue(s.node, "deploy"), "resources"), "limits")
+	if v := mappingValue(limits, "cpus"); v != nil && v.Kind == yaml.ScalarNode {
+		cpus = v.Value
+	}
+	if v := mappingValue(limits, "memory"); v != nil && v.Kind == yaml.ScalarNode {
+		memory = v.Value
+	}
+	return cpus, memory
+}
+
+// SetResourceLimits sets deploy.resources.limits; an empty value removes that limit and
+// removing both drops the block.
+func (s *Service) SetResourceLimits(cpus, memory string) {
+	deploy := ensureMapping(s.body(), "deploy")
+	resources := ensureMapping(deploy, "resources")
+	limits := ensureMapping(resources, "limits")
+	for _, kv := range [][2]string{{"cpus", cpus}, {"memory", memory}} {
+		if kv[1] == "" {
+			deleteKey(limits, kv[0])
+			continue
+		}
+		n := scalar(kv[1])
+		n.Style = yaml.DoubleQuotedStyle
+		setValue(limits, kv[0], n)
+	}
+	for _, pair := range []struct {
+		parent *yaml.Node
+		key    string
+		node   *yaml.Node
+	}{{resources, "limits", limits}, {deploy, "resources", resources}, {s.node, "deploy", deploy}} {
+		if len(pair.node.Content) == 0 {
+			deleteKey(pair.parent, pair.key)
+		}
+	}
+}
+
+// CopyFrom copies key (ports, networks, ...) from another service, replacing its own.
+// It reports false if the other service does not set key.
+func (s *Service) CopyFrom(other *Service, key string) bool {
+	v := mappingValue(other.node, key)
+	if v == nil {
+		return false
+	}
+	setValue(s.body(), key, deepCopy(v))
+	return true
+}
+
+// --- yaml.Node helpers ---
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func null() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setValue replaces the value of key in place, keeping the key's comments, or appends it.
+func setValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			value.LineComment = m.Content[i+1].LineComment
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, scalar(key), value)
+}
+
+func deleteKey(m *yaml.Node, key string) bool {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ensureMapping returns the mapping under key, creating it (or replacing an empty value)
+// when needed.
+func ensureMapping(m *yaml.Node, key string) *yaml.Node {
+	v := mappingValue(m, key)
+	if v != nil && v.Kind == yaml.MappingNode {
+		v.Style = 0
+		return v
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setValue(m, key, node)
+	return node
+}
+
+func deepCopy(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = deepCopy(child)
+	}
+	return &c
+}
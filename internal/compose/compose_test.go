@@ -0,0 +1,281 @@
+/*
+ * wpod - WordPress management tool
+ * Copyright (C) 2025 Regi E
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package compose
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// templatesDir holds the compose files wpod ships with.
+const templatesDir = "../../cmd/wp-manager/templates"
+
+// checkGolden compares got with testdata/<name>.golden, or rewrites it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run 'go test ./internal/compose -update' to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the golden file\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
+
+func loadTemplate(t *testing.T, template string) *File {
+	t.Helper()
+	f, err := Load(filepath.Join(templatesDir, template, "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func render(t *testing.T, f *File) []byte {
+	t.Helper()
+	out, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRoundTripBundledTemplates(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(templatesDir, "*", "docker-compose.yml"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no bundled compose files found: %v", err)
+	}
+	for _, path := range paths {
+		template := filepath.Base(filepath.Dir(path))
+		t.Run(template, func(t *testing.T) {
+			first := render(t, loadTemplate(t, template))
+			checkGolden(t, "roundtrip-"+template, first)
+
+			reparsed, err := Parse(first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if second := render(t, reparsed); !bytes.Equal(first, second) {
+				t.Errorf("second round trip changed the file\n--- first ---\n%s\n--- second ---\n%s", first, second)
+			}
+		})
+	}
+}
+
+func TestRoundTripHandWritten(t *testing.T) {
+	in := `# top comment
+version: "3.8"
+services:
+    web:   # the site
+        image: nginx
+        ports: ["8080:80"]
+    db:
+        image: mysql:8.0
+volumes:
+    db_data:
+`
+	f, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "handwritten", render(t, f))
+}
+
+func TestKeepsCRLF(t *testing.T) {
+	f, err := Parse([]byte("services:\r\n  web:\r\n    image: nginx\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := render(t, f)
+	if strings.Count(string(out), "\r\n") != strings.Count(string(out), "\n") {
+		t.Errorf("line endings not kept: %q", out)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	f := loadTemplate(t, "docker-default-wordpress")
+	caddy := f.Service("caddy")
+	if caddy == nil {
+		t.Fatal("template has no caddy service")
+	}
+	if caddy.AddProfile("donotstart") {
+		t.Error("adding a profile the service already has reported a change")
+	}
+	adminer := f.Service("adminer")
+	if adminer == nil {
+		t.Fatal("template has no adminer service")
+	}
+	if !adminer.AddProfile("tools") {
+		t.Fatal("AddProfile reported no change")
+	}
+	if !adminer.AddProfile("debug") {
+		t.Fatal("adding a second profile reported no change")
+	}
+	if got := adminer.Profiles(); !slices.Equal(got, []string{"tools", "debug"}) {
+		t.Errorf("Profiles() = %v", got)
+	}
+	checkGolden(t, "profiles", render(t, f))
+}
+
+// TestPorts mirrors the nginx-fpm switch: the published ports move from wordpress to a
+// new nginx service.
+func TestPorts(t *testing.T) {
+	f := loadTemplate(t, "docker-default-wordpress")
+	wordpress := f.Service("wordpress")
+	ports := wordpress.Ports()
+	if len(ports) == 0 {
+		t.Fatal("wordpress publishes no ports")
+	}
+	nginx := f.AddService("nginx")
+	nginx.Set("image", "nginx:stable-alpine")
+	if !nginx.CopyFrom(wordpress, "ports") {
+		t.Fatal("CopyFrom found no ports")
+	}
+	wordpress.Delete("ports")
+	if got := nginx.Ports(); !slices.Equal(got, ports) {
+		t.Errorf("nginx ports = %v, want %v", got, ports)
+	}
+	if got := wordpress.Ports(); got != nil {
+		t.Errorf("wordpress still publishes %v", got)
+	}
+	checkGolden(t, "ports", render(t, f))
+}
+
+func TestVolumes(t *testing.T) {
+	f := loadTemplate(t, "docker-default-wordpress")
+	original := render(t, f)
+	before := f.Volumes()
+
+	f.AddVolume("es_data")
+	f.AddVolume("es_data")
+	if got := f.Volumes(); !slices.Equal(got, append(slices.Clone(before), "es_data")) {
+		t.Errorf("Volumes() = %v", got)
+	}
+	checkGolden(t, "volumes", render(t, f))
+
+	if !f.RemoveVolume("es_data") {
+		t.Fatal("RemoveVolume found nothing")
+	}
+	if got := render(t, f); !bytes.Equal(got, original) {
+		t.Errorf("removing the volume did not restore the file\n%s", got)
+	}
+}
+
+const redisAddon = `
+image: redis:7-alpine
+restart: unless-stopped
+command: ["redis-server", "--save", ""]
+ports:
+  - "${REDIS_PORT}:6379"
+volumes:
+  - redis_data:/data
+`
+
+func TestAddonInsertAndRemove(t *testing.T) {
+	f := loadTemplate(t, "docker-default-wordpress")
+	original := render(t, f)
+
+	svc, err := f.AddServiceYAML("redis", redisAddon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if networks := f.Service("wordpress").Networks(); len(networks) > 0 {
+		svc.SetList("networks", networks[:1], false)
+	}
+	f.AddVolume("redis_data")
+	if _, err := f.AddServiceYAML("redis", redisAddon); err == nil {
+		t.Error("adding an existing service did not fail")
+	}
+	if _, err := f.AddServiceYAML("broken", "- not\n- a mapping\n"); err == nil {
+		t.Error("a sequence body was accepted")
+	}
+	checkGolden(t, "addon-insert", render(t, f))
+
+	if !f.RemoveService("redis") || !f.RemoveVolume("redis_data") {
+		t.Fatal("removal found nothing")
+	}
+	if f.HasService("redis") {
+		t.Error("redis is still there")
+	}
+	if got := render(t, f); !bytes.Equal(got, original) {
+		t.Errorf("removing the add-on did not restore the file\n%s", got)
+	}
+}
+
+func TestResourceLimits(t *testing.T) {
+	f := loadTemplate(t, "docker-default-wordpress")
+	original := render(t, f)
+	wordpress := f.Service("wordpress")
+
+	wordpress.SetResourceLimits("1.5", "512M")
+	if cpus, memory := wordpress.ResourceLimits(); cpus != "1.5" || memory != "512M" {
+		t.Errorf("ResourceLimits() = %q, %q", cpus, memory)
+	}
+	checkGolden(t, "resource-limits", render(t, f))
+
+	wordpress.SetResourceLimits("", "1G")
+	if cpus, memory := wordpress.ResourceLimits(); cpus != "" || memory != "1G" {
+		t.Errorf("after clearing cpus: ResourceLimits() = %q, %q", cpus, memory)
+	}
+	wordpress.SetResourceLimits("", "")
+	if got := render(t, f); !bytes.Equal(got, original) {
+		t.Errorf("clearing both limits did not drop the deploy block\n%s", got)
+	}
+}
+
+func TestServiceLookupDoesNotChangeFile(t *testing.T) {
+	in := "services:\n  app:\n  db:\n    image: mysql\n"
+	f, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := render(t, f)
+	app := f.Service("app")
+	if app == nil {
+		t.Fatal("Service(app) = nil")
+	}
+	if app.Get("image") != "" || app.Has("image") || app.Profiles() != nil {
+		t.Error("an empty service reports keys")
+	}
+	if f.Service("missing") != nil {
+		t.Error("Service(missing) != nil")
+	}
+	if got := render(t, f); !bytes.Equal(got, before) {
+		t.Errorf("lookup changed the file\n--- before ---\n%s\n--- after ---\n%s", before, got)
+	}
+
+	app.Set("image", "nginx")
+	if got, want := string(render(t, f)), "services:\n  app:\n    image: nginx\n  db:\n    image: mysql\n"; got != want {
+		t.Errorf("after Set:\n%s\nwant:\n%s", got, want)
+	}
+}